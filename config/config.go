@@ -3,6 +3,8 @@ package config
 import (
 	stderrors "errors"
 	"fmt"
+	"log/slog"
+	"time"
 
 	"github.com/KARTIKrocks/apikit/errors"
 	"github.com/KARTIKrocks/apikit/request"
@@ -12,10 +14,13 @@ import (
 type Option func(*options)
 
 type options struct {
-	prefix   string
-	envFile  string
-	jsonFile string
-	required bool
+	prefix       string
+	envFile      string
+	file         fileSource
+	required     bool
+	pollInterval time.Duration // Watcher only
+	logger       *slog.Logger  // Watcher only
+	sources      []prioritized
 }
 
 // WithPrefix sets a prefix for all environment variable lookups.
@@ -38,58 +43,102 @@ func WithEnvFile(path string) Option {
 // Environment variables and .env values take precedence over JSON values.
 func WithJSONFile(path string) Option {
 	return func(o *options) {
-		o.jsonFile = path
+		o.file = fileSource{path: path, format: "JSON", decode: DecodeJSON}
+	}
+}
+
+// WithYAMLFile loads configuration from a YAML file as the base layer, like
+// WithJSONFile. See DecodeYAML for the supported YAML subset.
+func WithYAMLFile(path string) Option {
+	return func(o *options) {
+		o.file = fileSource{path: path, format: "YAML", decode: DecodeYAML}
+	}
+}
+
+// WithTOMLFile loads configuration from a TOML file as the base layer, like
+// WithJSONFile.
+func WithTOMLFile(path string) Option {
+	return func(o *options) {
+		o.file = fileSource{path: path, format: "TOML", decode: DecodeTOML}
+	}
+}
+
+// WithFile loads configuration from a file decoded by decode as the base
+// layer, like WithJSONFile. Use it to plug in another format or a
+// third-party parser. If decode is nil, the format is chosen by extension:
+// .json, .yaml, .yml, or .toml.
+//
+//	config.Load(&cfg, config.WithFile("config.hcl", decodeHCL))
+func WithFile(path string, decode Decoder) Option {
+	return func(o *options) {
+		o.file = fileSource{path: path, format: "config", decode: decode}
 	}
 }
 
 // WithRequired causes Load to return an error if any specified files
-// (env file or JSON file) do not exist.
+// (env file or config file) do not exist.
 func WithRequired() Option {
 	return func(o *options) {
 		o.required = true
 	}
 }
 
-// Load populates dst from environment variables, optional .env files, and
-// optional JSON config files, then validates the result using struct tags.
+// WithPollInterval sets how often a Watcher checks the config and .env files
+// for changes. Default: 5 seconds. Load ignores it.
+func WithPollInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.pollInterval = d
+	}
+}
+
+// WithLogger sets the logger a Watcher reports reloads and rejected changes
+// to. Default: slog.Default(). Load ignores it.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// Load populates dst from environment variables, optional .env files,
+// optional config files, and any sources added with WithSource, then
+// validates the result using struct tags.
 //
 // dst must be a non-nil pointer to a struct.
 //
 // Sources are applied in priority order (high to low):
-//  1. Environment variables
-//  2. .env file values (do not override existing env vars)
-//  3. JSON file values
-//  4. default:"..." struct tags
+//  1. Command-line flags (WithFlags)
+//  2. Environment variables
+//  3. Secrets (WithSecretsDir, WithFileEnvVars, WithSecretProvider)
+//  4. .env file values (do not override existing env vars)
+//  5. Config file values (JSON, YAML, TOML, or WithFile)
+//  6. default:"..." struct tags
+//
+// See WithSource and the Priority constants to add other sources.
 func Load(dst any, opts ...Option) error {
-	o := &options{}
-	for _, opt := range opts {
-		if opt != nil {
-			opt(o)
-		}
-	}
+	o := newOptions(opts)
+
+	builtin := []prioritized{{envSource{}, PriorityEnv}}
 
-	// Load JSON file (base layer).
-	var jsonValues map[string]string
-	if o.jsonFile != "" {
-		var err error
-		jsonValues, err = loadJSONFile(o.jsonFile, o.required)
+	// Load .env file into a map (does not modify process environment).
+	if o.envFile != "" {
+		values, err := loadEnvFile(o.envFile, o.required)
 		if err != nil {
 			return err
 		}
+		builtin = append(builtin, prioritized{envFileSource(values), PriorityEnvFile})
 	}
 
-	// Load .env file into a map (does not modify process environment).
-	var envFileValues map[string]string
-	if o.envFile != "" {
-		var err error
-		envFileValues, err = loadEnvFile(o.envFile, o.required)
+	// Load the config file (base layer).
+	if o.file.path != "" {
+		values, err := loadFile(o.file, o.required)
 		if err != nil {
 			return err
 		}
+		builtin = append(builtin, prioritized{fileValuesSource(values), PriorityFile})
 	}
 
 	// Resolve all values into the struct.
-	if err := resolve(dst, o.prefix, envFileValues, jsonValues); err != nil {
+	if err := resolve(dst, o.prefix, sortSources(builtin, o.sources)); err != nil {
 		return err
 	}
 
@@ -106,6 +155,17 @@ func Load(dst any, opts ...Option) error {
 	return nil
 }
 
+// newOptions applies opts to the default options.
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	return o
+}
+
 // MustLoad calls Load and panics if an error occurs.
 // It is intended for use in main() or init() functions.
 func MustLoad(dst any, opts ...Option) {
@@ -1,5 +1,5 @@
 // Package config loads application configuration from environment variables,
-// .env files, and JSON config files into typed Go structs.
+// .env files, and JSON, YAML, or TOML config files into typed Go structs.
 //
 // It uses struct tags to map fields to environment variables and supports
 // default values, type coercion, and validation via the request package's
@@ -25,13 +25,18 @@
 //   - env:"VAR_NAME"       — maps the field to the named environment variable
 //   - default:"value"      — fallback if env var and config file both miss
 //   - validate:"..."       — reuses request package validators (required, min, max, url, etc.)
+//   - reload:"false"       — Watcher keeps the field's value on reload (restart required)
+//   - secret:"true"        — Redact hides the value; WithSecretProvider resolves it
+//     (implied for fields of type Secret)
 //
 // # Source Priority (high to low)
 //
-//  1. Environment variables (always win)
-//  2. .env file (does not override existing env vars)
-//  3. JSON config file (base config layer)
-//  4. default:"..." tags (fallback)
+//  1. Command-line flags (WithFlags)
+//  2. Environment variables
+//  3. Secrets (WithSecretsDir, WithFileEnvVars, WithSecretProvider)
+//  4. .env file (does not override existing env vars)
+//  5. Config file: JSON, YAML, or TOML (base config layer)
+//  6. default:"..." tags (fallback)
 //
 // # Options
 //
@@ -42,6 +47,17 @@
 //	    config.WithRequired(),              // Error if files don't exist
 //	)
 //
+// # Config Files
+//
+// One config file forms the base layer. Nested tables flatten into keys like
+// DB_HOST and arrays into comma-separated values, whatever the format:
+//
+//	config.WithJSONFile("config.json")
+//	config.WithYAMLFile("config.yaml") // see DecodeYAML for the supported subset
+//	config.WithTOMLFile("config.toml")
+//	config.WithFile(path, nil)         // format chosen by extension
+//	config.WithFile("app.hcl", decode) // any Decoder
+//
 // # Supported Types
 //
 //   - string, bool, int/int8/16/32/64, uint variants, float32/64
@@ -85,6 +101,46 @@
 // Use envprefix:"-" to skip the nesting prefix entirely, so inner env tags
 // are used as-is.
 //
+// # Sources and Secrets
+//
+// Every source implements Source and has a priority; WithSource adds custom
+// ones, e.g. a remote config service, relative to the Priority constants.
+// Sources are asked for a Key holding both the environment variable name
+// (APP_DB_PASSWORD) and the prefix-free name (DB_PASSWORD):
+//
+//	config.Load(&cfg,
+//	    config.WithFlags(nil),                     // -db-host, only if set
+//	    config.WithSecretsDir("/run/secrets"),     // /run/secrets/db_password
+//	    config.WithFileEnvVars(),                  // DB_PASSWORD_FILE=/path
+//	    config.WithSecretProvider(lookupInVault),  // secret:"true" fields only
+//	    config.WithSource(remote, config.PriorityEnv+1),
+//	)
+//
+// Declare secrets as Secret to keep them out of every log line, %+v dump,
+// and JSON encoding of the config; Value returns the plain text:
+//
+//	type AppConfig struct {
+//	    DBPassword config.Secret `env:"DB_PASSWORD"`
+//	}
+//
+//	slog.Info("config loaded", "config", cfg) // DBPassword: REDACTED
+//
+// Plain string fields tagged secret:"true" are only hidden in the copy
+// Redact returns; logging the config itself prints them:
+//
+//	slog.Info("config loaded", "config", config.Redact(cfg))
+//
+// # Hot Reload
+//
+// A Watcher keeps a typed snapshot in sync with the config and .env files,
+// rejecting changes that fail validation and ignoring changes to fields
+// tagged reload:"false":
+//
+//	w, err := config.NewWatcher[AppConfig](config.WithJSONFile("config.json"))
+//	w.Subscribe(func(old, new AppConfig) { /* apply */ })
+//	go w.Run(ctx)
+//	cfg := w.Current()
+//
 // # MustLoad
 //
 // For use in main() or init(), MustLoad panics on error:
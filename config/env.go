@@ -88,31 +88,3 @@ func stripQuotes(s string) string {
 	}
 	return s
 }
-
-// lookupEnv looks up an environment variable with optional prefix.
-// Returns the value and whether it was found.
-func lookupEnv(key, prefix string) (string, bool) {
-	if prefix != "" {
-		key = prefix + "_" + key
-	}
-	return os.LookupEnv(key)
-}
-
-// lookupEnvFile looks up a key in the .env file map with optional prefix.
-// Real environment variables take precedence: if the key exists in os env,
-// the .env value is skipped (returns not-found so the caller falls through
-// to the env-var lookup that already ran).
-func lookupEnvFile(key, prefix string, envFileValues map[string]string) (string, bool) {
-	if envFileValues == nil {
-		return "", false
-	}
-	if prefix != "" {
-		key = prefix + "_" + key
-	}
-	// Only return .env value if the real env doesn't have this key.
-	if _, inEnv := os.LookupEnv(key); inEnv {
-		return "", false
-	}
-	val, ok := envFileValues[key]
-	return val, ok
-}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/KARTIKrocks/apikit/errors"
+)
+
+// Decoder parses a configuration file into nested maps, the way
+// encoding/json decodes into map[string]any. Values may be maps, slices,
+// strings, numbers, bools, or nil. Nested maps are flattened into keys like
+// DB_HOST and slices into comma-separated values, so every format resolves
+// the same way.
+type Decoder func(data []byte) (map[string]any, error)
+
+// DecodeJSON is the Decoder used by WithJSONFile.
+func DecodeJSON(data []byte) (map[string]any, error) {
+	var raw map[string]any
+	if err := json.Unmarshal(trimBOM(data), &raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// trimBOM strips a leading UTF-8 byte order mark, which some editors write
+// and which would otherwise end up in the first key.
+func trimBOM(data []byte) []byte {
+	return bytes.TrimPrefix(data, []byte("\ufeff"))
+}
+
+// fileSource is the config file set by WithJSONFile, WithYAMLFile,
+// WithTOMLFile, or WithFile.
+type fileSource struct {
+	path   string
+	format string // for error messages
+	decode Decoder
+}
+
+// decoderFor picks a Decoder by file extension.
+func decoderFor(path string) (format string, decode Decoder, ok bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return "JSON", DecodeJSON, true
+	case ".yaml", ".yml":
+		return "YAML", DecodeYAML, true
+	case ".toml":
+		return "TOML", DecodeTOML, true
+	}
+	return "", nil, false
+}
+
+// loadFile reads and decodes a config file and flattens it into a map of
+// uppercase underscore-separated keys to string values. Nested objects are
+// flattened: {"db": {"host": "localhost"}} becomes {"DB_HOST": "localhost"}.
+func loadFile(src fileSource, required bool) (map[string]string, error) {
+	if src.decode == nil {
+		var ok bool
+		if src.format, src.decode, ok = decoderFor(src.path); !ok {
+			return nil, errors.Internal("config: unknown file format: " + src.path)
+		}
+	}
+
+	data, err := os.ReadFile(src.path)
+	if err != nil {
+		if os.IsNotExist(err) && !required {
+			return nil, nil
+		}
+		return nil, errors.Internal("config: cannot open file: " + src.path)
+	}
+
+	raw, err := src.decode(data)
+	if err != nil {
+		return nil, errors.Internal(fmt.Sprintf("config: invalid %s in %s: %v", src.format, src.path, err))
+	}
+
+	result := make(map[string]string)
+	flatten("", raw, result)
+	return result, nil
+}
+
+// flatten recursively flattens a decoded object into uppercase
+// underscore-separated keys.
+func flatten(prefix string, m map[string]any, result map[string]string) {
+	for k, v := range m {
+		key := strings.ToUpper(k)
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+
+		switch val := v.(type) {
+		case map[string]any:
+			flatten(key, val, result)
+		case []any:
+			// Convert array to comma-separated string.
+			parts := make([]string, 0, len(val))
+			for _, item := range val {
+				parts = append(parts, fmt.Sprintf("%v", item))
+			}
+			result[key] = strings.Join(parts, ",")
+		case float64:
+			// JSON numbers are float64; format as int if no fractional part.
+			if val == float64(int64(val)) {
+				result[key] = strconv.FormatInt(int64(val), 10)
+			} else {
+				result[key] = strconv.FormatFloat(val, 'f', -1, 64)
+			}
+		case bool:
+			result[key] = strconv.FormatBool(val)
+		case string:
+			result[key] = val
+		case nil:
+			// skip nil values
+		default:
+			result[key] = fmt.Sprintf("%v", val)
+		}
+	}
+}
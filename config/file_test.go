@@ -0,0 +1,292 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDecodeYAML(t *testing.T) {
+	data := `
+# service config
+---
+name: api
+port: 8080
+ratio: 0.5
+debug: true
+zip: "02134"
+mode: 0755
+empty:
+nothing: ~
+quoted: 'it''s'
+escaped: "tab\there"
+url: http://example.com:8080/path # comment
+db:
+  host: localhost
+  pool:
+    max: 10
+hosts:
+  - a.example.com
+  - b.example.com
+ports: [80, 443]
+labels: {team: core, tier: "1"}
+servers:
+- name: one
+  weight: 2
+- name: two
+  weight: 3
+script: |
+  echo one
+  echo two
+summary: >-
+  folded
+  text
+
+  next
+`
+	got, err := DecodeYAML([]byte(data))
+	if err != nil {
+		t.Fatalf("DecodeYAML: %v", err)
+	}
+	want := map[string]any{
+		"name":    "api",
+		"port":    int64(8080),
+		"ratio":   0.5,
+		"debug":   true,
+		"zip":     "02134",
+		"mode":    "0755",
+		"empty":   nil,
+		"nothing": nil,
+		"quoted":  "it's",
+		"escaped": "tab\there",
+		"url":     "http://example.com:8080/path",
+		"db": map[string]any{
+			"host": "localhost",
+			"pool": map[string]any{"max": int64(10)},
+		},
+		"hosts":  []any{"a.example.com", "b.example.com"},
+		"ports":  []any{int64(80), int64(443)},
+		"labels": map[string]any{"team": "core", "tier": "1"},
+		"servers": []any{
+			map[string]any{"name": "one", "weight": int64(2)},
+			map[string]any{"name": "two", "weight": int64(3)},
+		},
+		"script":  "echo one\necho two\n",
+		"summary": "folded text\nnext",
+	}
+	for k, w := range want {
+		if !reflect.DeepEqual(got[k], w) {
+			t.Errorf("%s = %#v, want %#v", k, got[k], w)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("got %d keys, want %d: %v", len(got), len(want), got)
+	}
+}
+
+func TestDecodeYAML_Errors(t *testing.T) {
+	tests := map[string]string{
+		"duplicate key":    "a: 1\na: 2\n",
+		"bad indentation":  "a: 1\n  b: 2\n",
+		"alias":            "a: &x 1\nb: *x\n",
+		"not a mapping":    "- a\n- b\n",
+		"unterminated":     "a: [1, 2\n",
+		"multiple docs":    "a: 1\n---\nb: 2\n",
+		"tab indentation":  "a:\n\tb: 1\n",
+		"missing colon":    "a: 1\nb\n",
+		"bad double quote": "a: \"\\q\"\n",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := DecodeYAML([]byte(data)); err == nil {
+				t.Errorf("expected error for %q", data)
+			}
+		})
+	}
+}
+
+func TestDecodeTOML(t *testing.T) {
+	data := `
+# service config
+name = "api"
+port = 8_080
+ratio = 0.5
+debug = true
+mask = 0o755
+literal = 'C:\path'
+started = 1979-05-27T07:32:00Z
+multi = """
+line one
+line two"""
+site."google.com" = true
+
+[db]
+host = "localhost"
+pool.max = 10
+
+[db.replica]
+host = "replica"
+
+[[servers]]
+name = "one"
+
+[[servers]]
+name = "two"
+
+[limits]
+ports = [
+  80,
+  443, # https
+]
+labels = { team = "core", tier = 1 }
+`
+	got, err := DecodeTOML([]byte(data))
+	if err != nil {
+		t.Fatalf("DecodeTOML: %v", err)
+	}
+	want := map[string]any{
+		"name":    "api",
+		"port":    int64(8080),
+		"ratio":   0.5,
+		"debug":   true,
+		"mask":    int64(0o755),
+		"literal": `C:\path`,
+		"started": "1979-05-27T07:32:00Z",
+		"multi":   "line one\nline two",
+		"site":    map[string]any{"google.com": true},
+		"db": map[string]any{
+			"host":    "localhost",
+			"pool":    map[string]any{"max": int64(10)},
+			"replica": map[string]any{"host": "replica"},
+		},
+		"servers": []any{
+			map[string]any{"name": "one"},
+			map[string]any{"name": "two"},
+		},
+		"limits": map[string]any{
+			"ports":  []any{int64(80), int64(443)},
+			"labels": map[string]any{"team": "core", "tier": int64(1)},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DecodeTOML =\n%#v\nwant\n%#v", got, want)
+	}
+}
+
+func TestDecodeTOML_Errors(t *testing.T) {
+	tests := map[string]string{
+		"duplicate key":     "a = 1\na = 2\n",
+		"table redefines":   "a = 1\n[a]\nb = 2\n",
+		"missing value":     "a =\n",
+		"trailing content":  "a = 1 2\n",
+		"unterminated":      "a = \"abc\n",
+		"leading zero":      "a = 012\n",
+		"bad escape":        "a = \"\\q\"\n",
+		"unterminated list": "a = [1, 2\n",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := DecodeTOML([]byte(data)); err == nil {
+				t.Errorf("expected error for %q", data)
+			}
+		})
+	}
+}
+
+type fileConfig struct {
+	Name    string        `env:"NAME"`
+	Timeout time.Duration `env:"TIMEOUT"`
+	Hosts   []string      `env:"HOSTS"`
+	DB      struct {
+		Host string `env:"HOST"`
+		Port int    `env:"PORT"`
+	}
+}
+
+func checkFileConfig(t *testing.T, c fileConfig) {
+	t.Helper()
+	if c.Name != "api" || c.Timeout != 5*time.Second || c.DB.Host != "db.local" || c.DB.Port != 5432 {
+		t.Errorf("unexpected config: %+v", c)
+	}
+	if !reflect.DeepEqual(c.Hosts, []string{"a", "b"}) {
+		t.Errorf("Hosts = %v, want [a b]", c.Hosts)
+	}
+}
+
+func TestLoad_YAMLFile(t *testing.T) {
+	path := tmpFile(t, "config.yaml", "name: api\ntimeout: 5s\nhosts: [a, b]\ndb:\n  host: db.local\n  port: 5432\n")
+
+	var c fileConfig
+	if err := Load(&c, WithYAMLFile(path)); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	checkFileConfig(t, c)
+}
+
+func TestLoad_JSONFileWithBOM(t *testing.T) {
+	path := tmpFile(t, "config.json", "\ufeff"+`{"name": "api", "timeout": "5s", "hosts": ["a", "b"], "db": {"host": "db.local", "port": 5432}}`)
+
+	var c fileConfig
+	if err := Load(&c, WithJSONFile(path)); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	checkFileConfig(t, c)
+}
+
+func TestLoad_TOMLFile(t *testing.T) {
+	path := tmpFile(t, "config.toml", "name = \"api\"\ntimeout = \"5s\"\nhosts = [\"a\", \"b\"]\n\n[db]\nhost = \"db.local\"\nport = 5432\n")
+
+	var c fileConfig
+	if err := Load(&c, WithTOMLFile(path)); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	checkFileConfig(t, c)
+}
+
+func TestLoad_FileEnvOverrides(t *testing.T) {
+	path := tmpFile(t, "config.yml", "name: api\ntimeout: 5s\nhosts: [a, b]\ndb:\n  host: yaml.local\n  port: 5432\n")
+	setEnv(t, "DB_HOST", "db.local")
+
+	var c fileConfig
+	if err := Load(&c, WithFile(path, nil)); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	checkFileConfig(t, c)
+}
+
+func TestLoad_WithFileCustomDecoder(t *testing.T) {
+	path := tmpFile(t, "config.custom", "ignored")
+	decode := func([]byte) (map[string]any, error) {
+		return map[string]any{
+			"name": "api", "timeout": "5s", "hosts": []any{"a", "b"},
+			"db": map[string]any{"host": "db.local", "port": 5432},
+		}, nil
+	}
+
+	var c fileConfig
+	if err := Load(&c, WithFile(path, decode)); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	checkFileConfig(t, c)
+}
+
+func TestLoad_WithFileUnknownExtension(t *testing.T) {
+	path := tmpFile(t, "config.ini", "name=api")
+
+	var c fileConfig
+	err := Load(&c, WithFile(path, nil))
+	if err == nil || !strings.Contains(err.Error(), "unknown file format") {
+		t.Fatalf("expected unknown format error, got %v", err)
+	}
+}
+
+func TestLoad_InvalidYAML(t *testing.T) {
+	path := tmpFile(t, "config.yaml", "a: 1\na: 2\n")
+
+	var c fileConfig
+	err := Load(&c, WithYAMLFile(path))
+	if err == nil || !strings.Contains(err.Error(), "invalid YAML") {
+		t.Fatalf("expected invalid YAML error, got %v", err)
+	}
+}
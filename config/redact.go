@@ -0,0 +1,86 @@
+package config
+
+import (
+	"reflect"
+	"time"
+)
+
+// Redacted replaces the values of secret fields in Redact's result.
+const Redacted = "REDACTED"
+
+// Redact returns a copy of cfg, a config struct or a pointer to one, that is
+// safe to log or dump: non-empty fields tagged secret:"true" are replaced
+// with Redacted (strings and string slices) or zeroed (other types), in
+// nested structs too. cfg itself is not modified.
+//
+// Redact only protects the copies it returns; a config passed to a logger
+// or encoder directly still prints tagged string fields. Declare such fields
+// as Secret to redact them everywhere.
+//
+//	type AppConfig struct {
+//	    DBPassword string `env:"DB_PASSWORD" secret:"true"`
+//	}
+//
+//	slog.Info("config loaded", "config", config.Redact(cfg))
+func Redact[T any](cfg T) T {
+	rv := reflect.ValueOf(&cfg).Elem()
+	switch {
+	case rv.Kind() == reflect.Struct:
+		redactStruct(rv)
+	case rv.Kind() == reflect.Pointer && !rv.IsNil() && rv.Elem().Kind() == reflect.Struct:
+		cp := reflect.New(rv.Elem().Type())
+		cp.Elem().Set(rv.Elem())
+		redactStruct(cp.Elem())
+		rv.Set(cp)
+	}
+	return cfg
+}
+
+// redactStruct redacts the secret fields of an addressable struct in place.
+func redactStruct(rv reflect.Value) {
+	rt := rv.Type()
+	for i := range rt.NumField() {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fv := rv.Field(i)
+
+		if isSecret(field) {
+			if !fv.IsZero() {
+				redactValue(fv)
+			}
+			continue
+		}
+		if fv.Kind() == reflect.Struct && field.Type != reflect.TypeFor[time.Duration]() {
+			redactStruct(fv)
+		}
+	}
+}
+
+// redactValue replaces a secret field's value.
+func redactValue(fv reflect.Value) {
+	switch {
+	case fv.Kind() == reflect.String:
+		fv.SetString(Redacted)
+	case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.String:
+		// Build a new slice; the original's backing array is shared with cfg.
+		s := reflect.MakeSlice(fv.Type(), fv.Len(), fv.Len())
+		for i := range fv.Len() {
+			s.Index(i).SetString(Redacted)
+		}
+		fv.Set(s)
+	default:
+		fv.SetZero()
+	}
+}
+
+// isSecret reports whether the field is tagged secret:"true" or holds
+// Secret values.
+func isSecret(field reflect.StructField) bool {
+	t := field.Type
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return field.Tag.Get("secret") == "true" || t == reflect.TypeFor[Secret]()
+}
@@ -10,10 +10,10 @@ import (
 	"github.com/KARTIKrocks/apikit/errors"
 )
 
-// resolve populates dst by merging sources in priority order:
-// env vars > .env file values > json values > default tags.
+// resolve populates dst from srcs, ordered from highest priority to lowest,
+// falling back to default tags.
 // dst must be a non-nil pointer to a struct.
-func resolve(dst any, prefix string, envFileValues, jsonValues map[string]string) error {
+func resolve(dst any, prefix string, srcs []Source) error {
 	rv := reflect.ValueOf(dst)
 	if rv.Kind() != reflect.Pointer || rv.IsNil() {
 		return errors.BadRequest("config: dst must be a non-nil pointer to a struct")
@@ -23,7 +23,7 @@ func resolve(dst any, prefix string, envFileValues, jsonValues map[string]string
 		return errors.BadRequest("config: dst must be a pointer to a struct")
 	}
 
-	return resolveStruct(rv, prefix, "", "", envFileValues, jsonValues)
+	return resolveStruct(rv, prefix, "", "", srcs)
 }
 
 // nestedEnvPrefix determines the env key prefix for a nested struct field.
@@ -61,7 +61,7 @@ func nestedDisplayName(namePrefix string, field reflect.StructField) string {
 // userPrefix is the caller-supplied prefix (e.g., "APP").
 // structPrefix is the nesting prefix built from struct field names (e.g., "DB_").
 // namePrefix is the struct nesting prefix for error messages.
-func resolveStruct(rv reflect.Value, userPrefix, structPrefix, namePrefix string, envFileValues, jsonValues map[string]string) error {
+func resolveStruct(rv reflect.Value, userPrefix, structPrefix, namePrefix string, srcs []Source) error {
 	rt := rv.Type()
 
 	for i := range rt.NumField() {
@@ -75,7 +75,7 @@ func resolveStruct(rv reflect.Value, userPrefix, structPrefix, namePrefix string
 		// Handle nested structs (no env tag).
 		envTag := field.Tag.Get("env")
 		if fieldVal.Kind() == reflect.Struct && envTag == "" && field.Type != reflect.TypeFor[time.Duration]() {
-			if err := resolveStruct(fieldVal, userPrefix, nestedEnvPrefix(structPrefix, field), nestedDisplayName(namePrefix, field), envFileValues, jsonValues); err != nil {
+			if err := resolveStruct(fieldVal, userPrefix, nestedEnvPrefix(structPrefix, field), nestedDisplayName(namePrefix, field), srcs); err != nil {
 				return err
 			}
 			continue
@@ -92,12 +92,20 @@ func resolveStruct(rv reflect.Value, userPrefix, structPrefix, namePrefix string
 		}
 
 		// Look up value from sources in priority order.
-		value, found := lookupValue(envTag, userPrefix, structPrefix, envFileValues, jsonValues, field)
+		secret := isSecret(field)
+		value, found, err := lookupValue(envTag, userPrefix, structPrefix, displayName, secret, srcs, field)
+		if err != nil {
+			return err
+		}
 		if !found {
 			continue
 		}
 
 		if err := setField(fieldVal, field.Type, value, displayName); err != nil {
+			if secret {
+				// Parse errors quote the value; don't leak it.
+				return errors.BadRequest(fmt.Sprintf("config: field %s: cannot parse secret value as %s", displayName, field.Type))
+			}
 			return err
 		}
 	}
@@ -116,43 +124,36 @@ func buildKey(parts ...string) string {
 	return strings.Join(nonEmpty, "_")
 }
 
-// lookupValue retrieves a configuration value from sources in priority order:
-// 1. Environment variable  (key = userPrefix + structPrefix + envTag)
-// 2. .env file value       (key = userPrefix + structPrefix + envTag, skipped if real env has it)
-// 3. JSON values           (key = structPrefix + envTag — no user prefix)
-// 4. Default tag
-func lookupValue(envTag, userPrefix, structPrefix string, envFileValues, jsonValues map[string]string, field reflect.StructField) (string, bool) {
+// lookupValue retrieves a configuration value from srcs in priority order,
+// falling back to the default tag. Sources are asked for:
+//   - Env:  userPrefix + structPrefix + envTag, e.g. "APP_DB_HOST"
+//   - Name: structPrefix + envTag — no user prefix, e.g. "DB_HOST"
+func lookupValue(envTag, userPrefix, structPrefix, displayName string, secret bool, srcs []Source, field reflect.StructField) (string, bool, error) {
 	// structPrefix ends with "_" (e.g., "DB_"); trim for key building.
 	sp := strings.TrimSuffix(structPrefix, "_")
 
-	// Env prefix combines userPrefix and struct nesting: "APP" + "DB" → "APP_DB".
-	envPrefix := buildKey(userPrefix, sp)
-	// JSON key uses only struct nesting: "DB" + "HOST" → "DB_HOST".
-	jsonKey := buildKey(sp, envTag)
-
-	// 1. Environment variable (highest priority).
-	if val, ok := lookupEnv(envTag, envPrefix); ok {
-		return val, true
+	key := Key{
+		Env:    buildKey(userPrefix, sp, envTag),
+		Name:   buildKey(sp, envTag),
+		Field:  displayName,
+		Secret: secret,
 	}
-
-	// 2. .env file values.
-	if val, ok := lookupEnvFile(envTag, envPrefix, envFileValues); ok {
-		return val, true
-	}
-
-	// 3. JSON values.
-	if jsonValues != nil {
-		if val, ok := jsonValues[jsonKey]; ok {
-			return val, true
+	for _, src := range srcs {
+		val, ok, err := src.Lookup(key)
+		if err != nil {
+			return "", false, err
+		}
+		if ok {
+			return val, true, nil
 		}
 	}
 
-	// 4. Default tag (lowest priority).
+	// Default tag (lowest priority).
 	if def, ok := field.Tag.Lookup("default"); ok {
-		return def, true
+		return def, true, nil
 	}
 
-	return "", false
+	return "", false, nil
 }
 
 // setField parses a string value and sets it on a reflect.Value.
@@ -227,7 +228,7 @@ func setSliceField(fieldVal reflect.Value, fieldType reflect.Type, value, displa
 
 		switch elemType.Kind() {
 		case reflect.String:
-			slice = reflect.Append(slice, reflect.ValueOf(part))
+			slice = reflect.Append(slice, reflect.ValueOf(part).Convert(elemType))
 
 		case reflect.Int:
 			n, err := strconv.Atoi(part)
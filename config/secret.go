@@ -0,0 +1,64 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+)
+
+// Secret is a string configuration value that stays out of logs and dumps:
+// fmt (every verb, including %+v and %#v on an enclosing struct), slog, and
+// encoding/json print Redacted in its place, or nothing if it is empty. Load
+// fills Secret fields like string fields, and treats them as tagged
+// secret:"true". Read the value with Value.
+//
+//	type AppConfig struct {
+//	    DBPassword config.Secret `env:"DB_PASSWORD"`
+//	}
+//
+//	slog.Info("config loaded", "config", cfg) // DBPassword:REDACTED
+//	db.Connect(cfg.DBPassword.Value())
+type Secret string
+
+// Value returns the secret in plain text.
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// String returns Redacted, or "" for an empty secret.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return Redacted
+}
+
+// GoString implements fmt.GoStringer without revealing the value.
+func (s Secret) GoString() string {
+	return "config.Secret(" + strconv.Quote(s.String()) + ")"
+}
+
+// Format implements fmt.Formatter, so no verb prints the value.
+func (s Secret) Format(f fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && f.Flag('#'):
+		_, _ = io.WriteString(f, s.GoString())
+	case verb == 'q':
+		_, _ = io.WriteString(f, strconv.Quote(s.String()))
+	default:
+		_, _ = io.WriteString(f, s.String())
+	}
+}
+
+// LogValue implements slog.LogValuer.
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
+
+// MarshalJSON encodes the redacted form, so dumping a config as JSON does
+// not leak the value.
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
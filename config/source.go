@@ -0,0 +1,232 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/KARTIKrocks/apikit/errors"
+)
+
+// Key identifies the field a Source is asked for.
+type Key struct {
+	// Env is the environment variable name, including the WithPrefix
+	// prefix and struct nesting, e.g. "APP_DB_PASSWORD".
+	Env string
+
+	// Name is the prefix-free name used by config files, e.g. "DB_PASSWORD".
+	Name string
+
+	// Field is the Go field path, e.g. "DB.Password".
+	Field string
+
+	// Secret reports whether the field is tagged secret:"true" or has type
+	// Secret.
+	Secret bool
+}
+
+// Source supplies configuration values. Lookup returns ok == false when the
+// source has no value for the key, so lower-priority sources are consulted;
+// an error aborts Load.
+type Source interface {
+	Lookup(key Key) (value string, ok bool, err error)
+}
+
+// SourceFunc adapts a function to the Source interface.
+type SourceFunc func(key Key) (value string, ok bool, err error)
+
+// Lookup calls f(key).
+func (f SourceFunc) Lookup(key Key) (string, bool, error) {
+	return f(key)
+}
+
+// Priorities of the built-in sources, for use with WithSource. For each
+// field, sources are consulted from the highest priority down and the first
+// value wins; default:"..." tags apply when no source has one.
+const (
+	PriorityFile    = 100 // config file (WithJSONFile, WithYAMLFile, ...)
+	PriorityEnvFile = 200 // .env file
+	PrioritySecrets = 250 // WithSecretsDir, WithFileEnvVars, WithSecretProvider
+	PriorityEnv     = 300 // environment variables
+	PriorityFlags   = 400 // WithFlags
+)
+
+// DefaultSecretsDir is where Docker and Kubernetes conventionally mount
+// secret files.
+const DefaultSecretsDir = "/run/secrets"
+
+// prioritized is a Source registered with WithSource.
+type prioritized struct {
+	src      Source
+	priority int
+}
+
+// WithSource adds a Source at the given priority. Sources with equal
+// priority are consulted in the order they were added, after the built-in
+// source of that priority.
+//
+//	config.Load(&cfg, config.WithSource(vaultSource, config.PriorityEnv+1))
+func WithSource(src Source, priority int) Option {
+	return func(o *options) {
+		if src != nil {
+			o.sources = append(o.sources, prioritized{src, priority})
+		}
+	}
+}
+
+// WithFlags reads values from flags that were set on the command line,
+// above environment variables. See FlagSource for flag naming.
+func WithFlags(fs *flag.FlagSet) Option {
+	return WithSource(FlagSource(fs), PriorityFlags)
+}
+
+// WithSecretsDir reads values from files in dir, as mounted by Docker
+// secrets or Kubernetes secret volumes, below environment variables. See
+// SecretsDirSource for file naming.
+func WithSecretsDir(dir string) Option {
+	return WithSource(SecretsDirSource(dir), PrioritySecrets)
+}
+
+// WithFileEnvVars enables the _FILE convention: when DB_PASSWORD is wanted
+// and DB_PASSWORD_FILE is set, the value is read from that file. See
+// FileEnvVarsSource.
+func WithFileEnvVars() Option {
+	return WithSource(FileEnvVarsSource(), PrioritySecrets)
+}
+
+// WithSecretProvider resolves fields tagged secret:"true" through fn, e.g. a
+// call to a secret manager, below environment variables. fn is not called
+// for other fields.
+//
+//	config.WithSecretProvider(func(key config.Key) (string, bool, error) {
+//	    v, err := vault.Get(ctx, "app/"+strings.ToLower(key.Name))
+//	    if errors.Is(err, vault.ErrNotFound) {
+//	        return "", false, nil
+//	    }
+//	    return v, err == nil, err
+//	})
+func WithSecretProvider(fn SourceFunc) Option {
+	if fn == nil {
+		return nil
+	}
+	return WithSource(SourceFunc(func(key Key) (string, bool, error) {
+		if !key.Secret {
+			return "", false, nil
+		}
+		return fn(key)
+	}), PrioritySecrets)
+}
+
+// FlagSource returns a Source backed by fs, which must already be parsed;
+// nil means flag.CommandLine. A field's flag name is its Name in lower case
+// with underscores replaced by dashes: DB_HOST is read from -db-host. Only
+// flags set on the command line are used, so flag defaults don't override
+// other sources.
+func FlagSource(fs *flag.FlagSet) Source {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+	return SourceFunc(func(key Key) (string, bool, error) {
+		name := strings.ReplaceAll(strings.ToLower(key.Name), "_", "-")
+		var value string
+		var found bool
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name == name {
+				value, found = f.Value.String(), true
+			}
+		})
+		return value, found, nil
+	})
+}
+
+// SecretsDirSource returns a Source that reads a field from the file in dir
+// named after its Name, as is or in lower case: DB_PASSWORD is read from
+// dir/DB_PASSWORD or dir/db_password. A trailing newline is trimmed. Missing
+// files are skipped; other read errors fail Load.
+func SecretsDirSource(dir string) Source {
+	return SourceFunc(func(key Key) (string, bool, error) {
+		names := []string{key.Name}
+		if lower := strings.ToLower(key.Name); lower != key.Name {
+			names = append(names, lower)
+		}
+		for _, name := range names {
+			if value, ok, err := readSecretFile(filepath.Join(dir, name)); ok || err != nil {
+				return value, ok, err
+			}
+		}
+		return "", false, nil
+	})
+}
+
+// FileEnvVarsSource returns a Source implementing the _FILE convention used
+// by Docker images: if the environment variable named Env+"_FILE" is set,
+// the field is read from the file it names, with a trailing newline
+// trimmed. A missing file fails Load, since the variable asked for it.
+func FileEnvVarsSource() Source {
+	return SourceFunc(func(key Key) (string, bool, error) {
+		path, ok := os.LookupEnv(key.Env + "_FILE")
+		if !ok {
+			return "", false, nil
+		}
+		value, ok, err := readSecretFile(path)
+		if err == nil && !ok {
+			err = errors.Internal("config: cannot open file: " + path)
+		}
+		return value, ok, err
+	})
+}
+
+// readSecretFile reads a secret file, reporting ok == false if it doesn't
+// exist.
+func readSecretFile(path string) (string, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", false, nil
+		}
+		return "", false, errors.Internal("config: cannot open file: " + path)
+	}
+	value := strings.TrimSuffix(string(data), "\n")
+	return strings.TrimSuffix(value, "\r"), true, nil
+}
+
+// envSource reads environment variables.
+type envSource struct{}
+
+func (envSource) Lookup(key Key) (string, bool, error) {
+	value, ok := os.LookupEnv(key.Env)
+	return value, ok, nil
+}
+
+// envFileSource reads values loaded from a .env file by environment
+// variable name.
+type envFileSource map[string]string
+
+func (s envFileSource) Lookup(key Key) (string, bool, error) {
+	value, ok := s[key.Env]
+	return value, ok, nil
+}
+
+// fileValuesSource reads flattened config file values by prefix-free name.
+type fileValuesSource map[string]string
+
+func (s fileValuesSource) Lookup(key Key) (string, bool, error) {
+	value, ok := s[key.Name]
+	return value, ok, nil
+}
+
+// sortSources orders the built-in and added sources from highest priority
+// to lowest, keeping registration order within a priority.
+func sortSources(builtin, added []prioritized) []Source {
+	all := append(slices.Clip(builtin), added...)
+	slices.SortStableFunc(all, func(a, b prioritized) int {
+		return b.priority - a.priority
+	})
+	srcs := make([]Source, len(all))
+	for i, p := range all {
+		srcs[i] = p.src
+	}
+	return srcs
+}
@@ -0,0 +1,329 @@
+package config
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoad_SourcePriority(t *testing.T) {
+	type cfg struct {
+		A string `env:"SRC_A"`
+		B string `env:"SRC_B"`
+		C string `env:"SRC_C"`
+		D string `env:"SRC_D" default:"default"`
+	}
+	jsonPath := tmpFile(t, "config.json", `{"src_a": "file", "src_b": "file", "src_c": "file"}`)
+	setEnv(t, "SRC_A", "env")
+
+	static := func(value string) Source {
+		return SourceFunc(func(key Key) (string, bool, error) {
+			if key.Name == "SRC_D" {
+				return "", false, nil
+			}
+			return value, true, nil
+		})
+	}
+
+	var c cfg
+	err := Load(&c,
+		WithJSONFile(jsonPath),
+		WithSource(static("above-file"), PriorityFile+1),
+		WithSource(static("below-file"), PriorityFile-1),
+		WithSource(static("above-env"), PriorityEnv+1),
+	)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want := cfg{A: "above-env", B: "above-env", C: "above-env", D: "default"}
+	if c != want {
+		t.Errorf("got %+v, want %+v", c, want)
+	}
+
+	c = cfg{}
+	err = Load(&c,
+		WithJSONFile(jsonPath),
+		WithSource(static("first"), PriorityFile),
+		WithSource(static("second"), PriorityFile),
+	)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	// Env wins for A; the built-in file source wins ties.
+	if c.A != "env" || c.B != "file" {
+		t.Errorf("got %+v", c)
+	}
+}
+
+func TestLoad_SourceKey(t *testing.T) {
+	type cfg struct {
+		DB struct {
+			Password string `env:"PASSWORD" secret:"true"`
+		}
+	}
+	var got Key
+	src := SourceFunc(func(key Key) (string, bool, error) {
+		got = key
+		return "", false, nil
+	})
+
+	var c cfg
+	if err := Load(&c, WithPrefix("APP"), WithSource(src, PriorityEnv)); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want := Key{Env: "APP_DB_PASSWORD", Name: "DB_PASSWORD", Field: "DB.Password", Secret: true}
+	if got != want {
+		t.Errorf("Key = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoad_SourceError(t *testing.T) {
+	type cfg struct {
+		Host string `env:"SRC_ERR_HOST" default:"localhost"`
+	}
+	boom := stderrors.New("boom")
+	src := SourceFunc(func(Key) (string, bool, error) { return "", false, boom })
+
+	var c cfg
+	if err := Load(&c, WithSource(src, PriorityEnv)); !stderrors.Is(err, boom) {
+		t.Fatalf("err = %v, want %v", err, boom)
+	}
+}
+
+func TestLoad_WithFlags(t *testing.T) {
+	type cfg struct {
+		Port int `env:"PORT"`
+		DB   struct {
+			Host string `env:"HOST" default:"localhost"`
+		}
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Int("port", 8080, "")
+	fs.String("db-host", "flag-default", "")
+	if err := fs.Parse([]string{"-port", "9090"}); err != nil {
+		t.Fatal(err)
+	}
+	setEnv(t, "APP_PORT", "7070")
+
+	var c cfg
+	if err := Load(&c, WithPrefix("APP"), WithFlags(fs)); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if c.Port != 9090 {
+		t.Errorf("Port = %d, want 9090 (flag beats env)", c.Port)
+	}
+	if c.DB.Host != "localhost" {
+		t.Errorf("DB.Host = %q, want default (unset flag is ignored)", c.DB.Host)
+	}
+}
+
+func TestLoad_WithSecretsDir(t *testing.T) {
+	type cfg struct {
+		Password string `env:"DB_PASSWORD" secret:"true"`
+		APIKey   string `env:"API_KEY"`
+		Token    string `env:"TOKEN"`
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "db_password"), []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "API_KEY"), []byte("key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "token"), []byte("file"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	setEnv(t, "TOKEN", "env")
+
+	var c cfg
+	if err := Load(&c, WithSecretsDir(dir)); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	want := cfg{Password: "s3cret", APIKey: "key", Token: "env"}
+	if c != want {
+		t.Errorf("got %+v, want %+v", c, want)
+	}
+}
+
+func TestLoad_WithFileEnvVars(t *testing.T) {
+	type cfg struct {
+		Password string `env:"PASSWORD"`
+	}
+	path := tmpFile(t, "password", "s3cret\n")
+	setEnv(t, "APP_PASSWORD_FILE", path)
+
+	var c cfg
+	if err := Load(&c, WithPrefix("APP"), WithFileEnvVars()); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if c.Password != "s3cret" {
+		t.Errorf("Password = %q, want %q", c.Password, "s3cret")
+	}
+
+	// Without the option, _FILE variables are ignored.
+	c = cfg{}
+	if err := Load(&c, WithPrefix("APP")); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if c.Password != "" {
+		t.Errorf("Password = %q, want empty", c.Password)
+	}
+
+	setEnv(t, "APP_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+	if err := Load(&c, WithPrefix("APP"), WithFileEnvVars()); err == nil {
+		t.Error("expected error for missing _FILE target")
+	}
+}
+
+func TestLoad_WithSecretProvider(t *testing.T) {
+	type cfg struct {
+		Host     string `env:"HOST" default:"localhost"`
+		Password string `env:"PASSWORD" secret:"true"`
+	}
+	var asked []string
+	provider := func(key Key) (string, bool, error) {
+		asked = append(asked, key.Name)
+		return "from-vault", true, nil
+	}
+
+	var c cfg
+	if err := Load(&c, WithSecretProvider(provider)); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if c.Host != "localhost" || c.Password != "from-vault" {
+		t.Errorf("got %+v", c)
+	}
+	if !reflect.DeepEqual(asked, []string{"PASSWORD"}) {
+		t.Errorf("provider asked for %v, want only [PASSWORD]", asked)
+	}
+}
+
+func TestLoad_SecretParseErrorRedacted(t *testing.T) {
+	type cfg struct {
+		PIN int `env:"SECRET_PIN" secret:"true"`
+	}
+	setEnv(t, "SECRET_PIN", "hunter2")
+
+	var c cfg
+	err := Load(&c)
+	if err == nil {
+		t.Fatal("expected parse error")
+	}
+	if strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("error leaks secret: %v", err)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	type db struct {
+		Host     string `env:"HOST"`
+		Password string `env:"PASSWORD" secret:"true"`
+	}
+	type cfg struct {
+		Name   string   `json:"name" env:"NAME"`
+		Token  string   `json:"token" env:"TOKEN" secret:"true"`
+		Keys   []string `json:"keys" env:"KEYS" secret:"true"`
+		PIN    int      `json:"pin" env:"PIN" secret:"true"`
+		Unset  string   `json:"unset" env:"UNSET" secret:"true"`
+		DB     db       `json:"db"`
+		hidden string
+	}
+	orig := cfg{
+		Name:  "api",
+		Token: "t0ken",
+		Keys:  []string{"k1", "k2"},
+		PIN:   1234,
+		DB:    db{Host: "localhost", Password: "pw"},
+	}
+
+	got := Redact(orig)
+	want := cfg{
+		Name:  "api",
+		Token: Redacted,
+		Keys:  []string{Redacted, Redacted},
+		DB:    db{Host: "localhost", Password: Redacted},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Redact = %+v, want %+v", got, want)
+	}
+	if orig.Token != "t0ken" || orig.Keys[0] != "k1" || orig.DB.Password != "pw" {
+		t.Errorf("Redact modified the original: %+v", orig)
+	}
+
+	p := Redact(&orig)
+	if p == &orig || p.Token != Redacted || orig.Token != "t0ken" {
+		t.Errorf("Redact(pointer) = %+v, original %+v", p, orig)
+	}
+
+	data, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"t0ken", "k1", "1234", `"pw"`} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("JSON leaks %s: %s", secret, data)
+		}
+	}
+}
+
+func TestSecret(t *testing.T) {
+	type cfg struct {
+		Host     string   `json:"host" env:"SECRET_TEST_HOST"`
+		Password Secret   `json:"password" env:"SECRET_TEST_PASSWORD"`
+		Keys     []Secret `json:"keys" env:"SECRET_TEST_KEYS"`
+		Empty    Secret   `json:"empty" env:"SECRET_TEST_EMPTY"`
+	}
+	setEnv(t, "SECRET_TEST_HOST", "db.local")
+	setEnv(t, "SECRET_TEST_PASSWORD", "hunter2")
+	setEnv(t, "SECRET_TEST_KEYS", "k3y1,k3y2")
+
+	var asked []Key
+	provider := func(key Key) (string, bool, error) {
+		asked = append(asked, key)
+		return "", false, nil
+	}
+	var c cfg
+	if err := Load(&c, WithSecretProvider(provider)); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if c.Password.Value() != "hunter2" || len(c.Keys) != 2 || c.Keys[1].Value() != "k3y2" {
+		t.Fatalf("got %#v", c)
+	}
+	if len(asked) != 1 || asked[0].Name != "SECRET_TEST_EMPTY" || !asked[0].Secret {
+		t.Errorf("provider asked for %+v, want the unset Secret field marked secret", asked)
+	}
+
+	var buf strings.Builder
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	logger.Info("config", "config", c, "password", c.Password)
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("config", "config", c, "password", c.Password)
+	for _, format := range []string{"%v", "%+v", "%#v", "%s", "%q", "%x"} {
+		fmt.Fprintf(&buf, format+"\n", c)
+		fmt.Fprintf(&buf, format+"\n", c.Password)
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Write(data)
+
+	out := buf.String()
+	for _, secret := range []string{"hunter2", "k3y1", "k3y2"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("output leaks %s:\n%s", secret, out)
+		}
+	}
+	if !strings.Contains(out, "db.local") || !strings.Contains(out, Redacted) {
+		t.Errorf("expected the host and %s in the output:\n%s", Redacted, out)
+	}
+	if got := fmt.Sprint(c.Empty); got != "" {
+		t.Errorf("empty secret printed as %q", got)
+	}
+}
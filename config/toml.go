@@ -0,0 +1,625 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DecodeTOML is the Decoder used by WithTOMLFile. It supports TOML 1.0
+// tables, arrays of tables, dotted and quoted keys, all string forms,
+// integers, floats, booleans, arrays, and inline tables. Dates and times are
+// kept as strings, so they can be parsed by the field that receives them.
+func DecodeTOML(data []byte) (map[string]any, error) {
+	p := &tomlParser{
+		s:      strings.ReplaceAll(string(trimBOM(data)), "\r\n", "\n"),
+		line:   1,
+		tables: make(map[uintptr]tomlTable),
+		arrays: make(map[tomlArray]bool),
+	}
+	root := make(map[string]any)
+	p.tables[tableID(root)] = tableHeader
+	current := root
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return root, nil
+		}
+		var err error
+		if p.peek() == '[' {
+			current, err = p.table(root)
+		} else {
+			err = p.keyValue(current)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := p.endLine(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// tomlParser is a cursor over a TOML document.
+type tomlParser struct {
+	s    string
+	i    int
+	line int
+
+	// tables records how each table was created, which decides whether a
+	// header or dotted key may define or extend it. arrays records the
+	// arrays of tables created by [[headers]], which are the only arrays
+	// headers may append to or descend into.
+	tables map[uintptr]tomlTable
+	arrays map[tomlArray]bool
+}
+
+// tomlTable is how a table came to exist.
+type tomlTable int
+
+const (
+	tableImplicit tomlTable = iota // parent of a [header]; may be defined once later
+	tableHeader                    // defined by a [header] or [[header]]
+	tableDotted                    // created by a dotted key
+	tableInline                    // inline table; closed once parsed
+)
+
+// tomlArray identifies an array of tables by its parent table and key.
+type tomlArray struct {
+	parent uintptr
+	key    string
+}
+
+// tableID identifies a table; maps are reference types, so the pointer is
+// stable for the table's lifetime.
+func tableID(m map[string]any) uintptr {
+	return reflect.ValueOf(m).Pointer()
+}
+
+// newTable creates a table of the given kind under parent[key].
+func (p *tomlParser) newTable(parent map[string]any, key string, kind tomlTable) map[string]any {
+	t := make(map[string]any)
+	parent[key] = t
+	p.tables[tableID(t)] = kind
+	return t
+}
+
+func (p *tomlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("toml: line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+func (p *tomlParser) eof() bool  { return p.i >= len(p.s) }
+func (p *tomlParser) peek() byte { return p.s[p.i] }
+
+func (p *tomlParser) advance(n int) {
+	p.line += strings.Count(p.s[p.i:p.i+n], "\n")
+	p.i += n
+}
+
+// skipSpace skips spaces and tabs.
+func (p *tomlParser) skipSpace() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.i++
+	}
+}
+
+// skipComment skips a comment up to, not including, the newline.
+func (p *tomlParser) skipComment() {
+	if !p.eof() && p.peek() == '#' {
+		for !p.eof() && p.peek() != '\n' {
+			p.i++
+		}
+	}
+}
+
+// skipBlank skips whitespace, newlines, and comments.
+func (p *tomlParser) skipBlank() {
+	for {
+		p.skipSpace()
+		p.skipComment()
+		if p.eof() || p.peek() != '\n' {
+			return
+		}
+		p.advance(1)
+	}
+}
+
+// endLine requires the rest of the line to be blank or a comment.
+func (p *tomlParser) endLine() error {
+	p.skipSpace()
+	p.skipComment()
+	if p.eof() {
+		return nil
+	}
+	if p.peek() != '\n' {
+		return p.errorf("unexpected %q after value", p.peek())
+	}
+	p.advance(1)
+	return nil
+}
+
+// table parses a [table] or [[array]] header and returns the table that
+// following key/value pairs belong to.
+func (p *tomlParser) table(root map[string]any) (map[string]any, error) {
+	array := strings.HasPrefix(p.s[p.i:], "[[")
+	if array {
+		p.i += 2
+	} else {
+		p.i++
+	}
+	keys, err := p.key()
+	if err != nil {
+		return nil, err
+	}
+	closing := "]"
+	if array {
+		closing = "]]"
+	}
+	p.skipSpace()
+	if !strings.HasPrefix(p.s[p.i:], closing) {
+		return nil, p.errorf("expected %q after table name", closing)
+	}
+	p.i += len(closing)
+
+	parent, err := p.descendHeader(root, keys[:len(keys)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := keys[len(keys)-1]
+	if array {
+		arr := tomlArray{tableID(parent), last}
+		existing, ok := parent[last]
+		if ok && !p.arrays[arr] {
+			return nil, p.errorf("key %q is already defined", last)
+		}
+		tables, _ := existing.([]any)
+		t := make(map[string]any)
+		p.tables[tableID(t)] = tableHeader
+		parent[last] = append(tables, t)
+		p.arrays[arr] = true
+		return t, nil
+	}
+	switch v := parent[last].(type) {
+	case nil:
+		return p.newTable(parent, last, tableHeader), nil
+	case map[string]any:
+		// A table created as the parent of an earlier header may be defined
+		// once; tables defined by headers, dotted keys, or inline may not.
+		if p.tables[tableID(v)] == tableImplicit {
+			p.tables[tableID(v)] = tableHeader
+			return v, nil
+		}
+		return nil, p.errorf("table %q is already defined", last)
+	}
+	return nil, p.errorf("key %q is already defined", last)
+}
+
+// descendHeader walks a header's parent keys from root, creating implicit
+// tables as needed. A key holding an array of tables resolves to its last
+// element. Inline tables and other arrays can't be extended.
+func (p *tomlParser) descendHeader(m map[string]any, keys []string) (map[string]any, error) {
+	for _, k := range keys {
+		switch v := m[k].(type) {
+		case nil:
+			m = p.newTable(m, k, tableImplicit)
+		case map[string]any:
+			if p.tables[tableID(v)] == tableInline {
+				return nil, p.errorf("inline table %q can't be extended", k)
+			}
+			m = v
+		case []any:
+			if !p.arrays[tomlArray{tableID(m), k}] {
+				return nil, p.errorf("key %q is already defined", k)
+			}
+			m = v[len(v)-1].(map[string]any)
+		default:
+			return nil, p.errorf("key %q is already defined", k)
+		}
+	}
+	return m, nil
+}
+
+// descendDotted walks a dotted key's parent keys from m. Dotted keys create
+// and define tables, and may only extend tables created by dotted keys in
+// the same table.
+func (p *tomlParser) descendDotted(m map[string]any, keys []string) (map[string]any, error) {
+	for _, k := range keys {
+		switch v := m[k].(type) {
+		case nil:
+			m = p.newTable(m, k, tableDotted)
+		case map[string]any:
+			if p.tables[tableID(v)] != tableDotted {
+				return nil, p.errorf("table %q is already defined", k)
+			}
+			m = v
+		default:
+			return nil, p.errorf("key %q is already defined", k)
+		}
+	}
+	return m, nil
+}
+
+// keyValue parses "key = value" into m.
+func (p *tomlParser) keyValue(m map[string]any) error {
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	p.skipSpace()
+	if p.eof() || p.peek() != '=' {
+		return p.errorf("expected '=' after key")
+	}
+	p.i++
+	p.skipSpace()
+	v, err := p.value()
+	if err != nil {
+		return err
+	}
+	parent, err := p.descendDotted(m, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, dup := parent[last]; dup {
+		return p.errorf("duplicate key %q", last)
+	}
+	parent[last] = v
+	return nil
+}
+
+// key parses a possibly dotted key.
+func (p *tomlParser) key() ([]string, error) {
+	var keys []string
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return nil, p.errorf("expected key")
+		}
+		var k string
+		switch c := p.peek(); {
+		case c == '"' || c == '\'':
+			s, err := p.str()
+			if err != nil {
+				return nil, err
+			}
+			k = s
+		default:
+			start := p.i
+			for !p.eof() && isBareKeyChar(p.peek()) {
+				p.i++
+			}
+			if p.i == start {
+				return nil, p.errorf("invalid key character %q", c)
+			}
+			k = p.s[start:p.i]
+		}
+		keys = append(keys, k)
+		p.skipSpace()
+		if p.eof() || p.peek() != '.' {
+			return keys, nil
+		}
+		p.i++
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+// value parses any TOML value.
+func (p *tomlParser) value() (any, error) {
+	if p.eof() {
+		return nil, p.errorf("expected value")
+	}
+	switch c := p.peek(); c {
+	case '"', '\'':
+		return p.str()
+	case '[':
+		return p.array()
+	case '{':
+		t, err := p.inlineTable()
+		if err == nil {
+			p.tables[tableID(t)] = tableInline
+		}
+		return t, err
+	}
+
+	start := p.i
+	for !p.eof() && !strings.ContainsRune(" \t\n,]}#", rune(p.peek())) {
+		p.i++
+	}
+	// Datetimes may contain a single space between date and time.
+	if p.i-start == 10 && !p.eof() && p.peek() == ' ' && p.i+1 < len(p.s) && isDigit(p.s[p.i+1]) {
+		p.i++
+		for !p.eof() && !strings.ContainsRune(" \t\n,]}#", rune(p.peek())) {
+			p.i++
+		}
+	}
+	tok := p.s[start:p.i]
+	if tok == "" {
+		return nil, p.errorf("expected value")
+	}
+	return p.scalar(tok)
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+// scalar resolves a bare value: bool, integer, float, or datetime.
+func (p *tomlParser) scalar(tok string) (any, error) {
+	switch tok {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan", "+nan", "-nan":
+		return math.NaN(), nil
+	}
+	if isTOMLDatetime(tok) {
+		return tok, nil
+	}
+
+	digits := strings.ReplaceAll(tok, "_", "")
+	if len(tok) > 2 && tok[0] == '0' && strings.IndexByte("xob", tok[1]) >= 0 {
+		valid := isHexDigit
+		switch tok[1] {
+		case 'o':
+			valid = isOctDigit
+		case 'b':
+			valid = isBinDigit
+		}
+		n, err := strconv.ParseInt(digits, 0, 64)
+		if err != nil || !isDigitRun(tok[2:], valid) {
+			return nil, p.errorf("invalid integer %q", tok)
+		}
+		return n, nil
+	}
+	float, ok := isTOMLDecimal(tok)
+	if !ok {
+		if unsigned := strings.TrimLeft(tok, "+-"); len(unsigned) > 1 && unsigned[0] == '0' && isDigit(unsigned[1]) {
+			return nil, p.errorf("leading zeros are not allowed in %q", tok)
+		}
+		return nil, p.errorf("invalid value %q", tok)
+	}
+	if !float {
+		n, err := strconv.ParseInt(digits, 10, 64)
+		if err != nil {
+			return nil, p.errorf("integer %q out of range", tok)
+		}
+		return n, nil
+	}
+	f, err := strconv.ParseFloat(digits, 64)
+	if err != nil {
+		return nil, p.errorf("invalid float %q", tok)
+	}
+	return f, nil
+}
+
+// isTOMLDecimal reports whether tok is a decimal integer or float, and
+// whether it is a float: an optional sign, an integer part without leading
+// zeros, then an optional fraction and exponent. Underscores must sit
+// between digits.
+func isTOMLDecimal(tok string) (float, ok bool) {
+	s := tok
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		s = s[1:]
+	}
+	intPart, rest := s, ""
+	if i := strings.IndexAny(s, ".eE"); i >= 0 {
+		intPart, rest = s[:i], s[i:]
+	}
+	if !isDigitRun(intPart, isDigit) || len(intPart) > 1 && intPart[0] == '0' {
+		return false, false
+	}
+	if strings.HasPrefix(rest, ".") {
+		frac := rest[1:]
+		j := strings.IndexAny(frac, "eE")
+		if j < 0 {
+			j = len(frac)
+		}
+		if !isDigitRun(frac[:j], isDigit) {
+			return false, false
+		}
+		rest, float = frac[j:], true
+	}
+	if rest != "" {
+		exp := rest[1:]
+		if exp != "" && (exp[0] == '+' || exp[0] == '-') {
+			exp = exp[1:]
+		}
+		if !isDigitRun(exp, isDigit) {
+			return false, false
+		}
+		float = true
+	}
+	return float, true
+}
+
+// isDigitRun reports whether s is one or more digits accepted by valid,
+// with single underscores allowed between digits.
+func isDigitRun(s string, valid func(byte) bool) bool {
+	if s == "" || s[0] == '_' || s[len(s)-1] == '_' || strings.Contains(s, "__") {
+		return false
+	}
+	for i := range len(s) {
+		if s[i] != '_' && !valid(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isHexDigit(c byte) bool { return isDigit(c) || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F' }
+func isOctDigit(c byte) bool { return c >= '0' && c <= '7' }
+func isBinDigit(c byte) bool { return c == '0' || c == '1' }
+
+// isTOMLDatetime reports whether tok looks like a date, time, or datetime.
+func isTOMLDatetime(tok string) bool {
+	if len(tok) >= 10 && tok[4] == '-' && tok[7] == '-' && isDigit(tok[0]) {
+		return true
+	}
+	return len(tok) >= 8 && tok[2] == ':' && tok[5] == ':' && isDigit(tok[0])
+}
+
+// str parses a basic, literal, or multi-line string.
+func (p *tomlParser) str() (string, error) {
+	q := p.peek()
+	delim := string(q)
+	multi := strings.HasPrefix(p.s[p.i:], strings.Repeat(delim, 3))
+	if multi {
+		delim = strings.Repeat(delim, 3)
+	}
+	p.i += len(delim)
+	if multi && !p.eof() && p.peek() == '\n' {
+		p.advance(1) // a newline right after the opening delimiter is trimmed
+	}
+
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return "", p.errorf("unterminated string")
+		}
+		if strings.HasPrefix(p.s[p.i:], delim) {
+			// Up to two extra quotes before a multi-line closing delimiter
+			// belong to the content.
+			for extra := 0; multi && extra < 2 && p.i+3 < len(p.s) && p.s[p.i+3] == q; extra++ {
+				b.WriteByte(q)
+				p.i++
+			}
+			p.i += len(delim)
+			return b.String(), nil
+		}
+		c := p.peek()
+		switch {
+		case c == '\n' && !multi:
+			return "", p.errorf("newline in single-line string")
+		case c == '\\' && q == '"':
+			if err := p.escape(&b, multi); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+			p.advance(1)
+		}
+	}
+}
+
+// escape decodes a backslash escape in a basic string.
+func (p *tomlParser) escape(b *strings.Builder, multi bool) error {
+	p.i++ // backslash
+	if p.eof() {
+		return p.errorf("unterminated string")
+	}
+	c := p.peek()
+	p.i++
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case 'e':
+		b.WriteByte(0x1b)
+	case '"':
+		b.WriteByte('"')
+	case '\\':
+		b.WriteByte('\\')
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if p.i+n > len(p.s) {
+			return p.errorf("invalid unicode escape")
+		}
+		r, err := strconv.ParseUint(p.s[p.i:p.i+n], 16, 32)
+		if err != nil {
+			return p.errorf("invalid unicode escape")
+		}
+		b.WriteRune(rune(r))
+		p.i += n
+	case ' ', '\t', '\n':
+		if !multi {
+			return p.errorf("invalid escape \\%c", c)
+		}
+		// A line-ending backslash trims the newline and leading whitespace.
+		p.i--
+		for !p.eof() && strings.ContainsRune(" \t\n", rune(p.peek())) {
+			p.advance(1)
+		}
+	default:
+		return p.errorf("invalid escape \\%c", c)
+	}
+	return nil
+}
+
+// array parses [v, ...], which may span lines and contain comments.
+func (p *tomlParser) array() ([]any, error) {
+	p.i++ // [
+	items := []any{}
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.peek() == ']' {
+			p.i++
+			return items, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+		p.skipBlank()
+		if p.eof() {
+			return nil, p.errorf("unterminated array")
+		}
+		switch p.peek() {
+		case ',':
+			p.i++
+		case ']':
+		default:
+			return nil, p.errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+// inlineTable parses {k = v, ...} on a single line.
+func (p *tomlParser) inlineTable() (map[string]any, error) {
+	p.i++ // {
+	m := make(map[string]any)
+	p.skipSpace()
+	if !p.eof() && p.peek() == '}' {
+		p.i++
+		return m, nil
+	}
+	for {
+		if err := p.keyValue(m); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.eof() {
+			return nil, p.errorf("unterminated inline table")
+		}
+		switch p.peek() {
+		case ',':
+			p.i++
+		case '}':
+			p.i++
+			return m, nil
+		default:
+			return nil, p.errorf("expected ',' or '}' in inline table")
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package config
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// TOML conformance cases, taken mostly from the examples in the TOML 1.0
+// specification (https://toml.io/en/v1.0.0).
+
+func TestDecodeTOML_Spec(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want map[string]any
+	}{
+		{
+			name: "comments",
+			data: "# This is a full-line comment\nkey = \"value\"  # This is a comment at the end of a line\nanother = \"# This is not a comment\"\n",
+			want: map[string]any{"key": "value", "another": "# This is not a comment"},
+		},
+		{
+			name: "bare keys",
+			data: "key = 1\nbare_key = 2\nbare-key = 3\n1234 = 4\n",
+			want: map[string]any{"key": int64(1), "bare_key": int64(2), "bare-key": int64(3), "1234": int64(4)},
+		},
+		{
+			name: "quoted keys",
+			data: "\"127.0.0.1\" = \"value\"\n\"character encoding\" = \"value\"\n\"ʎǝʞ\" = \"value\"\n'key2' = \"value\"\n'quoted \"value\"' = \"value\"\n\"\" = \"blank\"\n",
+			want: map[string]any{
+				"127.0.0.1": "value", "character encoding": "value", "ʎǝʞ": "value",
+				"key2": "value", `quoted "value"`: "value", "": "blank",
+			},
+		},
+		{
+			name: "dotted keys",
+			data: "name = \"Orange\"\nphysical.color = \"orange\"\nphysical.shape = \"round\"\nsite.\"google.com\" = true\n",
+			want: map[string]any{
+				"name":     "Orange",
+				"physical": map[string]any{"color": "orange", "shape": "round"},
+				"site":     map[string]any{"google.com": true},
+			},
+		},
+		{
+			name: "whitespace around dots",
+			data: "fruit.name = \"banana\"\nfruit. color = \"yellow\"\nfruit . flavor = \"banana\"\n",
+			want: map[string]any{"fruit": map[string]any{"name": "banana", "color": "yellow", "flavor": "banana"}},
+		},
+		{
+			name: "dotted keys out of order",
+			data: "apple.type = \"fruit\"\norange.type = \"fruit\"\napple.skin = \"thin\"\norange.skin = \"thick\"\n",
+			want: map[string]any{
+				"apple":  map[string]any{"type": "fruit", "skin": "thin"},
+				"orange": map[string]any{"type": "fruit", "skin": "thick"},
+			},
+		},
+		{
+			name: "float-like key",
+			data: "3.14159 = \"pi\"\n",
+			want: map[string]any{"3": map[string]any{"14159": "pi"}},
+		},
+		{
+			name: "basic string escapes",
+			data: `str = "I'm a string. \"You can quote me\". Name\tJos\u00E9\nLocation\tSF."` + "\n",
+			want: map[string]any{"str": "I'm a string. \"You can quote me\". Name\tJos\u00e9\nLocation\tSF."},
+		},
+		{
+			name: "multi-line basic strings",
+			data: "str1 = \"\"\"\nRoses are red\nViolets are blue\"\"\"\n" +
+				"str2 = \"\"\"\nThe quick brown \\\n\n\n  fox jumps over \\\n    the lazy dog.\"\"\"\n" +
+				"str3 = \"\"\"Here are two quotation marks: \"\". Simple enough.\"\"\"\n" +
+				"str4 = \"\"\"Here are three quotation marks: \"\"\\\".\"\"\"\n" +
+				"str5 = \"\"\"\"This,\" she said, \"is just a pointless statement.\"\"\"\"\n",
+			want: map[string]any{
+				"str1": "Roses are red\nViolets are blue",
+				"str2": "The quick brown fox jumps over the lazy dog.",
+				"str3": `Here are two quotation marks: "". Simple enough.`,
+				"str4": `Here are three quotation marks: """.`,
+				"str5": `"This," she said, "is just a pointless statement."`,
+			},
+		},
+		{
+			name: "literal strings",
+			data: "winpath = 'C:\\Users\\nodejs\\templates'\nregex = '<\\i\\c*\\s*>'\n" +
+				"regex2 = '''I [dw]on't need \\d{2} apples'''\n" +
+				"lines = '''\nThe first newline is\ntrimmed in raw strings.'''\n" +
+				"quot15 = '''Here are fifteen quotation marks: \"\"\"\"\"\"\"\"\"\"\"\"\"\"\"'''\n" +
+				"str = ''''That,' she said, 'is still pointless.''''\n",
+			want: map[string]any{
+				"winpath": `C:\Users\nodejs\templates`,
+				"regex":   `<\i\c*\s*>`,
+				"regex2":  `I [dw]on't need \d{2} apples`,
+				"lines":   "The first newline is\ntrimmed in raw strings.",
+				"quot15":  `Here are fifteen quotation marks: """""""""""""""`,
+				"str":     `'That,' she said, 'is still pointless.'`,
+			},
+		},
+		{
+			name: "integers",
+			data: "int1 = +99\nint2 = 42\nint3 = 0\nint4 = -17\nint5 = 1_000\nint6 = 5_349_221\nint7 = 53_49_221\nint8 = 1_2_3_4_5\n" +
+				"hex1 = 0xDEADBEEF\nhex2 = 0xdeadbeef\nhex3 = 0xdead_beef\noct1 = 0o01234567\noct2 = 0o755\nbin1 = 0b11010110\n" +
+				"max = 9_223_372_036_854_775_807\nmin = -9223372036854775808\n",
+			want: map[string]any{
+				"int1": int64(99), "int2": int64(42), "int3": int64(0), "int4": int64(-17),
+				"int5": int64(1000), "int6": int64(5349221), "int7": int64(5349221), "int8": int64(12345),
+				"hex1": int64(0xdeadbeef), "hex2": int64(0xdeadbeef), "hex3": int64(0xdeadbeef),
+				"oct1": int64(0o1234567), "oct2": int64(0o755), "bin1": int64(0b11010110),
+				"max": int64(math.MaxInt64), "min": int64(math.MinInt64),
+			},
+		},
+		{
+			name: "floats",
+			data: "flt1 = +1.0\nflt2 = 3.1415\nflt3 = -0.01\nflt4 = 5e+22\nflt5 = 1e06\nflt6 = -2E-2\nflt7 = 6.626e-34\nflt8 = 224_617.445_991_228\n" +
+				"sf1 = inf\nsf2 = +inf\nsf3 = -inf\nzero = -0.0\n",
+			want: map[string]any{
+				"flt1": 1.0, "flt2": 3.1415, "flt3": -0.01, "flt4": 5e+22, "flt5": 1e06, "flt6": -2e-2,
+				"flt7": 6.626e-34, "flt8": 224617.445991228,
+				"sf1": math.Inf(1), "sf2": math.Inf(1), "sf3": math.Inf(-1), "zero": math.Copysign(0, -1),
+			},
+		},
+		{
+			name: "booleans and datetimes",
+			data: "bool1 = true\nbool2 = false\nodt1 = 1979-05-27T07:32:00Z\nodt2 = 1979-05-27T00:32:00.999999-07:00\n" +
+				"odt3 = 1979-05-27 07:32:00Z\nldt = 1979-05-27T07:32:00\nld = 1979-05-27\nlt1 = 07:32:00\nlt2 = 00:32:00.999999\n",
+			want: map[string]any{
+				"bool1": true, "bool2": false,
+				"odt1": "1979-05-27T07:32:00Z", "odt2": "1979-05-27T00:32:00.999999-07:00",
+				"odt3": "1979-05-27 07:32:00Z", "ldt": "1979-05-27T07:32:00", "ld": "1979-05-27",
+				"lt1": "07:32:00", "lt2": "00:32:00.999999",
+			},
+		},
+		{
+			name: "arrays",
+			data: "integers = [ 1, 2, 3 ]\ncolors = [ \"red\", \"yellow\", \"green\" ]\n" +
+				"nested = [ [ 1, 2 ], [\"a\", \"b\", \"c\"] ]\n" +
+				"strings = [ \"all\", 'strings', \"\"\"are the same\"\"\", '''type''' ]\n" +
+				"mixed = [ 0.1, 2, { name = \"Baz\" } ]\n" +
+				"multi = [\n  1,\n  2, # comment\n]\nempty = []\n",
+			want: map[string]any{
+				"integers": []any{int64(1), int64(2), int64(3)},
+				"colors":   []any{"red", "yellow", "green"},
+				"nested":   []any{[]any{int64(1), int64(2)}, []any{"a", "b", "c"}},
+				"strings":  []any{"all", "strings", "are the same", "type"},
+				"mixed":    []any{0.1, int64(2), map[string]any{"name": "Baz"}},
+				"multi":    []any{int64(1), int64(2)},
+				"empty":    []any{},
+			},
+		},
+		{
+			name: "table names",
+			data: "[dog.\"tater.man\"]\ntype.name = \"pug\"\n[a.b.c]\n[ d.e.f ]\n[ g .  h  . i ]\n[ j . \"ʞ\" . 'l' ]\n",
+			want: map[string]any{
+				"dog": map[string]any{"tater.man": map[string]any{"type": map[string]any{"name": "pug"}}},
+				"a":   map[string]any{"b": map[string]any{"c": map[string]any{}}},
+				"d":   map[string]any{"e": map[string]any{"f": map[string]any{}}},
+				"g":   map[string]any{"h": map[string]any{"i": map[string]any{}}},
+				"j":   map[string]any{"ʞ": map[string]any{"l": map[string]any{}}},
+			},
+		},
+		{
+			name: "super-table defined after sub-table",
+			data: "[x.y.z.w]\na = 1\n[x]\nb = 2\n",
+			want: map[string]any{"x": map[string]any{
+				"b": int64(2),
+				"y": map[string]any{"z": map[string]any{"w": map[string]any{"a": int64(1)}}},
+			}},
+		},
+		{
+			name: "tables out of order",
+			data: "[fruit.apple]\n[animal]\n[fruit.orange]\n",
+			want: map[string]any{
+				"fruit":  map[string]any{"apple": map[string]any{}, "orange": map[string]any{}},
+				"animal": map[string]any{},
+			},
+		},
+		{
+			name: "sub-table of dotted keys",
+			data: "[fruit]\napple.color = \"red\"\napple.taste.sweet = true\n[fruit.apple.texture]\nsmooth = true\n",
+			want: map[string]any{"fruit": map[string]any{"apple": map[string]any{
+				"color":   "red",
+				"taste":   map[string]any{"sweet": true},
+				"texture": map[string]any{"smooth": true},
+			}}},
+		},
+		{
+			name: "inline tables",
+			data: "name = { first = \"Tom\", last = \"Preston-Werner\" }\npoint = { x = 1, y = 2 }\nanimal = { type.name = \"pug\" }\nnone = {}\n",
+			want: map[string]any{
+				"name":   map[string]any{"first": "Tom", "last": "Preston-Werner"},
+				"point":  map[string]any{"x": int64(1), "y": int64(2)},
+				"animal": map[string]any{"type": map[string]any{"name": "pug"}},
+				"none":   map[string]any{},
+			},
+		},
+		{
+			name: "arrays of tables",
+			data: "[[products]]\nname = \"Hammer\"\nsku = 738594937\n\n[[products]]  # empty table within the array\n\n" +
+				"[[products]]\nname = \"Nail\"\nsku = 284758393\n",
+			want: map[string]any{"products": []any{
+				map[string]any{"name": "Hammer", "sku": int64(738594937)},
+				map[string]any{},
+				map[string]any{"name": "Nail", "sku": int64(284758393)},
+			}},
+		},
+		{
+			name: "nested arrays of tables",
+			data: "[[fruits]]\nname = \"apple\"\n\n[fruits.physical]\ncolor = \"red\"\n\n" +
+				"[[fruits.varieties]]\nname = \"red delicious\"\n\n[[fruits.varieties]]\nname = \"granny smith\"\n\n" +
+				"[[fruits]]\nname = \"banana\"\n\n[[fruits.varieties]]\nname = \"plantain\"\n",
+			want: map[string]any{"fruits": []any{
+				map[string]any{
+					"name":      "apple",
+					"physical":  map[string]any{"color": "red"},
+					"varieties": []any{map[string]any{"name": "red delicious"}, map[string]any{"name": "granny smith"}},
+				},
+				map[string]any{
+					"name":      "banana",
+					"varieties": []any{map[string]any{"name": "plantain"}},
+				},
+			}},
+		},
+		{
+			name: "CRLF line endings",
+			data: "a = 1\r\n[t]\r\nb = \"x\"\r\n",
+			want: map[string]any{"a": int64(1), "t": map[string]any{"b": "x"}},
+		},
+		{
+			name: "byte order mark",
+			data: "\ufeffa = 1\n",
+			want: map[string]any{"a": int64(1)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeTOML([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("DecodeTOML: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecodeTOML =\n%#v\nwant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeTOML_SpecNaN(t *testing.T) {
+	got, err := DecodeTOML([]byte("sf4 = nan\nsf5 = +nan\nsf6 = -nan\n"))
+	if err != nil {
+		t.Fatalf("DecodeTOML: %v", err)
+	}
+	for _, k := range []string{"sf4", "sf5", "sf6"} {
+		if f, ok := got[k].(float64); !ok || !math.IsNaN(f) {
+			t.Errorf("%s = %#v, want NaN", k, got[k])
+		}
+	}
+}
+
+func TestDecodeTOML_SpecInvalid(t *testing.T) {
+	tests := map[string]string{
+		"missing value":                    "key = # INVALID\n",
+		"two pairs on one line":            "first = \"Tom\" last = \"Preston-Werner\"\n",
+		"missing key":                      "= \"no key name\"\n",
+		"duplicate key":                    "name = \"Tom\"\nname = \"Pradyun\"\n",
+		"duplicate quoted key":             "spelling = \"favorite\"\n\"spelling\" = \"favourite\"\n",
+		"value extended by dotted key":     "fruit.apple = 1\nfruit.apple.smooth = true\n",
+		"bare key with unicode":            "ʎǝʞ = 1\n",
+		"newline in basic string":          "str = \"line\nbreak\"\n",
+		"newline in literal string":        "str = 'line\nbreak'\n",
+		"four quotes closing basic":        "str = \"\"\"Here are three quotation marks: \"\"\".\"\"\"\n",
+		"too many apostrophes":             "apos15 = '''Here are fifteen apostrophes: ''''''''''''''''''\n",
+		"unknown escape":                   "str = \"\\x41\"\n",
+		"leading zero":                     "int = 012\n",
+		"double underscore":                "int = 1__2\n",
+		"leading underscore":               "int = _1\n",
+		"trailing underscore":              "int = 1_\n",
+		"signed hex":                       "int = +0xFF\n",
+		"bad hex digit":                    "int = 0xG\n",
+		"bad octal digit":                  "int = 0o8\n",
+		"bad binary digit":                 "int = 0b2\n",
+		"hex underscore after prefix":      "int = 0x_1\n",
+		"integer overflow":                 "int = 9223372036854775808\n",
+		"float without integer part":       "flt = .7\n",
+		"float without fraction":           "flt = 7.\n",
+		"float with dot before exponent":   "flt = 3.e+20\n",
+		"float underscore before exponent": "flt = 1_e2\n",
+		"hex float":                        "flt = 0x1p-2\n",
+		"infinity spelled out":             "flt = infinity\n",
+		"capitalised boolean":              "b = True\n",
+		"empty array element":              "a = [1,,2]\n",
+		"multi-line inline table":          "t = { a = 1,\n b = 2 }\n",
+		"inline table trailing comma":      "t = { a = 1, }\n",
+		"duplicate key in inline table":    "t = { a = 1, a = 2 }\n",
+		"table defined twice":              "[fruit]\napple = \"red\"\n[fruit]\norange = \"orange\"\n",
+		"table redefines a key":            "[fruit]\napple = \"red\"\n[fruit.apple]\ntexture = \"smooth\"\n",
+		"table redefines dotted keys":      "a.b = 1\n[a]\nc = 2\n",
+		"sub-table redefines dotted keys":  "[fruit]\napple.color = \"red\"\napple.taste.sweet = true\n[fruit.apple]\n",
+		"deeper dotted table redefined":    "[fruit]\napple.color = \"red\"\napple.taste.sweet = true\n[fruit.apple.taste]\n",
+		"implicit table defined twice":     "[x.y]\n[x]\n[x]\n",
+		"dotted key extends header table":  "[a.b.c]\nz = 9\n[a]\nb.c.t = 1\n",
+		"dotted key extends implicit":      "[a.b.c.d]\nz = 9\n[a]\nb.c.d.k.t = 1\n",
+		"inline table extended by key":     "[product]\ntype = { name = \"Nail\" }\ntype.edible = false\n",
+		"inline table extended by header":  "[product]\ntype.name = \"Nail\"\nt = { a = 1 }\n[product.t]\nb = 2\n",
+		"inline table under header":        "a = { b = 1 }\n[a.c]\n",
+		"static array appended":            "fruits = []\n[[fruits]]\n",
+		"array of tables as table":         "[[fruits]]\nname = \"apple\"\n[[fruits.varieties]]\nname = \"red\"\n[fruits.varieties]\nname = \"granny\"\n",
+		"table as array of tables":         "[[fruits]]\n[fruits.physical]\ncolor = \"red\"\n[[fruits.physical]]\ncolor = \"green\"\n",
+		"static array of inline tables":    "a = [{ b = 1 }]\n[a.c]\n",
+		"unterminated table header":        "[a\n",
+		"empty table name":                 "[]\n",
+		"header after value on line":       "a = 1 [b]\n",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got, err := DecodeTOML([]byte(data)); err == nil {
+				t.Errorf("expected error for %q, got %#v", data, got)
+			}
+		})
+	}
+}
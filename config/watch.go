@@ -0,0 +1,230 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Watcher keeps a typed configuration snapshot up to date with its config
+// file, whatever its format, and .env file. It polls the files for changes,
+// reloads and re-validates them, and atomically swaps in the new snapshot.
+// A reload that fails to load or validate is logged and the last good
+// configuration stays in use.
+//
+// Fields tagged reload:"false" cannot change at runtime, e.g. listen
+// addresses: when a reload changes one, a warning is logged and the field
+// keeps its current value until restart.
+//
+//	type AppConfig struct {
+//	    Addr      string        `env:"ADDR" default:":8080" reload:"false"`
+//	    RateLimit int           `env:"RATE_LIMIT" default:"100" validate:"min=1"`
+//	    Timeout   time.Duration `env:"TIMEOUT" default:"5s"`
+//	}
+//
+//	w, err := config.NewWatcher[AppConfig](config.WithJSONFile("config.json"))
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	w.Subscribe(func(old, new AppConfig) {
+//	    limiter.SetLimit(new.RateLimit)
+//	})
+//	go w.Run(ctx)
+//
+//	cfg := w.Current()
+//
+// Environment variables are read from the process environment on every
+// reload, but only file changes trigger one; call Reload to force it. After a
+// rejected reload, Run retries on every poll until one succeeds, so a fix
+// made outside the files, such as to a secret, is picked up too.
+type Watcher[T any] struct {
+	opts     []Option
+	files    []string
+	interval time.Duration
+	logger   *slog.Logger
+
+	current atomic.Pointer[T]
+
+	mu     sync.Mutex           // serializes reloads and guards the fields below
+	stamps map[string]fileStamp // files as of the last successful load
+	failed map[string]fileStamp // files as of the last rejected reload
+	subs   map[int]func(old, new T)
+	nextID int
+}
+
+// fileStamp identifies a version of a file for change detection.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+// NewWatcher loads the initial configuration with the same options as Load
+// and returns a Watcher for it. It returns an error if the initial load
+// fails. Call Run to start watching.
+func NewWatcher[T any](opts ...Option) (*Watcher[T], error) {
+	o := newOptions(opts)
+	w := &Watcher[T]{
+		opts:     opts,
+		interval: o.pollInterval,
+		logger:   o.logger,
+		subs:     make(map[int]func(old, new T)),
+	}
+	if w.interval <= 0 {
+		w.interval = 5 * time.Second
+	}
+	if w.logger == nil {
+		w.logger = slog.Default()
+	}
+	for _, path := range []string{o.file.path, o.envFile} {
+		if path != "" {
+			w.files = append(w.files, path)
+		}
+	}
+
+	stamps := w.stat()
+	var cfg T
+	if err := Load(&cfg, opts...); err != nil {
+		return nil, err
+	}
+	w.stamps = stamps
+	w.current.Store(&cfg)
+	return w, nil
+}
+
+// Current returns the current configuration snapshot. Slices and maps in it
+// are shared with other callers and must not be modified.
+func (w *Watcher[T]) Current() T {
+	return *w.current.Load()
+}
+
+// Subscribe registers fn to be called with the previous and new
+// configuration after each reload that changes it. Callbacks run one at a
+// time on the reloading goroutine, in no particular order, and must not
+// call Subscribe or Reload. The returned function removes the subscription.
+func (w *Watcher[T]) Subscribe(fn func(old, new T)) (unsubscribe func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	id := w.nextID
+	w.nextID++
+	w.subs[id] = fn
+	return func() {
+		w.mu.Lock()
+		delete(w.subs, id)
+		w.mu.Unlock()
+	}
+}
+
+// Reload re-reads the configuration now. If it fails to load or validate,
+// the error is returned and the current configuration is kept.
+func (w *Watcher[T]) Reload() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.reloadLocked(w.stat())
+}
+
+// Run polls the files every poll interval and reloads when one changes,
+// until ctx is cancelled. It always returns nil, so a Watcher can run as a
+// server.Group component.
+func (w *Watcher[T]) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			w.mu.Lock()
+			if stamps := w.stat(); !reflect.DeepEqual(stamps, w.stamps) {
+				_ = w.reloadLocked(stamps)
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+// reloadLocked loads, swaps, and notifies. stamps describe the files as they
+// were before loading, so a write during the load triggers another reload.
+// They are recorded only when the load succeeds, so Run retries a rejected
+// reload on the next poll; repeated failures for the same files are logged
+// at debug level.
+func (w *Watcher[T]) reloadLocked(stamps map[string]fileStamp) error {
+	var next T
+	if err := Load(&next, w.opts...); err != nil {
+		level := slog.LevelWarn
+		if reflect.DeepEqual(stamps, w.failed) {
+			level = slog.LevelDebug
+		}
+		w.logger.Log(context.Background(), level, "config reload rejected, keeping current config", "error", err)
+		w.failed = stamps
+		return err
+	}
+	w.stamps, w.failed = stamps, nil
+
+	old := *w.current.Load()
+	for _, name := range keepStatic(reflect.ValueOf(&next).Elem(), reflect.ValueOf(&old).Elem(), "") {
+		w.logger.Warn("config field requires restart, change ignored", "field", name)
+	}
+	if reflect.DeepEqual(old, next) {
+		return nil
+	}
+
+	w.current.Store(&next)
+	w.logger.Info("config reloaded")
+	for _, fn := range w.subs {
+		fn(old, next)
+	}
+	return nil
+}
+
+// stat returns the current stamps of the watched files.
+func (w *Watcher[T]) stat() map[string]fileStamp {
+	stamps := make(map[string]fileStamp, len(w.files))
+	for _, path := range w.files {
+		fi, err := os.Stat(path)
+		if err != nil {
+			stamps[path] = fileStamp{}
+			continue
+		}
+		stamps[path] = fileStamp{modTime: fi.ModTime(), size: fi.Size(), exists: true}
+	}
+	return stamps
+}
+
+// keepStatic copies fields tagged reload:"false" from old into next where
+// they differ, returning the names of those fields.
+func keepStatic(next, old reflect.Value, namePrefix string) []string {
+	var changed []string
+	rt := next.Type()
+	for i := range rt.NumField() {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		nf, of := next.Field(i), old.Field(i)
+
+		if field.Tag.Get("reload") == "false" {
+			if !reflect.DeepEqual(nf.Interface(), of.Interface()) {
+				nf.Set(of)
+				changed = append(changed, fieldDisplayName(namePrefix, field))
+			}
+			continue
+		}
+		if nf.Kind() == reflect.Struct && field.Tag.Get("env") == "" && field.Type != reflect.TypeFor[time.Duration]() {
+			changed = append(changed, keepStatic(nf, of, nestedDisplayName(namePrefix, field))...)
+		}
+	}
+	return changed
+}
+
+// fieldDisplayName returns the field's name as used in error messages.
+func fieldDisplayName(namePrefix string, field reflect.StructField) string {
+	if namePrefix != "" {
+		return namePrefix + "." + field.Name
+	}
+	return field.Name
+}
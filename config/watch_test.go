@@ -0,0 +1,171 @@
+package config
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+type watchConfig struct {
+	Addr  string `env:"WATCH_ADDR" default:":8080" reload:"false"`
+	Limit int    `env:"WATCH_LIMIT" default:"10" validate:"min=1"`
+	DB    struct {
+		Pool int `env:"POOL" default:"5"`
+	}
+}
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Make the change visible even on filesystems with coarse mtimes.
+	future := time.Now().Add(time.Duration(len(content)) * time.Second)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestWatcher(t *testing.T, path string) *Watcher[watchConfig] {
+	t.Helper()
+	w, err := NewWatcher[watchConfig](
+		WithJSONFile(path),
+		WithPollInterval(5*time.Millisecond),
+		WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	)
+	if err != nil {
+		t.Fatalf("NewWatcher: %v", err)
+	}
+	return w
+}
+
+func TestWatcher_InitialLoad(t *testing.T) {
+	path := tmpFile(t, "config.json", `{"watch_limit": 20, "db": {"pool": 8}}`)
+	w := newTestWatcher(t, path)
+	cfg := w.Current()
+	if cfg.Limit != 20 || cfg.DB.Pool != 8 || cfg.Addr != ":8080" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestWatcher_InitialLoadInvalid(t *testing.T) {
+	path := tmpFile(t, "config.json", `{"watch_limit": 0}`)
+	if _, err := NewWatcher[watchConfig](WithJSONFile(path)); err == nil {
+		t.Fatal("expected validation error")
+	}
+}
+
+func TestWatcher_ReloadOnChange(t *testing.T) {
+	path := tmpFile(t, "config.json", `{"watch_limit": 20}`)
+	w := newTestWatcher(t, path)
+
+	changes := make(chan [2]watchConfig, 1)
+	w.Subscribe(func(old, new watchConfig) { changes <- [2]watchConfig{old, new} })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() { defer wg.Done(); _ = w.Run(ctx) }()
+	defer func() { cancel(); wg.Wait() }()
+
+	writeConfig(t, path, `{"watch_limit": 30}`)
+	select {
+	case c := <-changes:
+		if c[0].Limit != 20 || c[1].Limit != 30 {
+			t.Errorf("change = %+v -> %+v", c[0], c[1])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change notification")
+	}
+	if got := w.Current().Limit; got != 30 {
+		t.Errorf("Current().Limit = %d, want 30", got)
+	}
+}
+
+func TestWatcher_RejectsInvalidReload(t *testing.T) {
+	path := tmpFile(t, "config.json", `{"watch_limit": 20}`)
+	w := newTestWatcher(t, path)
+	notified := false
+	w.Subscribe(func(old, new watchConfig) { notified = true })
+
+	writeConfig(t, path, `{"watch_limit": 0}`)
+	if err := w.Reload(); err == nil {
+		t.Fatal("expected validation error")
+	}
+	writeConfig(t, path, `{not json`)
+	if err := w.Reload(); err == nil {
+		t.Fatal("expected parse error")
+	}
+	if got := w.Current().Limit; got != 20 || notified {
+		t.Errorf("last good config not kept: limit=%d notified=%v", got, notified)
+	}
+}
+
+func TestWatcher_StaticFieldKept(t *testing.T) {
+	path := tmpFile(t, "config.json", `{"watch_addr": ":8080", "watch_limit": 20}`)
+	w := newTestWatcher(t, path)
+
+	writeConfig(t, path, `{"watch_addr": ":9090", "watch_limit": 25}`)
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	cfg := w.Current()
+	if cfg.Addr != ":8080" {
+		t.Errorf("Addr = %q, want unchanged :8080", cfg.Addr)
+	}
+	if cfg.Limit != 25 {
+		t.Errorf("Limit = %d, want 25", cfg.Limit)
+	}
+}
+
+func TestWatcher_Unsubscribe(t *testing.T) {
+	path := tmpFile(t, "config.json", `{"watch_limit": 20}`)
+	w := newTestWatcher(t, path)
+	calls := 0
+	unsubscribe := w.Subscribe(func(old, new watchConfig) { calls++ })
+
+	writeConfig(t, path, `{"watch_limit": 21}`)
+	_ = w.Reload()
+	unsubscribe()
+	writeConfig(t, path, `{"watch_limit": 22}`)
+	_ = w.Reload()
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestWatcher_RetriesRejectedReload(t *testing.T) {
+	path := tmpFile(t, "config.json", `{"watch_limit": 20}`)
+	w := newTestWatcher(t, path)
+	changes := make(chan watchConfig, 1)
+	w.Subscribe(func(old, new watchConfig) { changes <- new })
+
+	// The file change is rejected because of the environment, which a
+	// later fix corrects without touching the file again.
+	setEnv(t, "WATCH_LIMIT", "0")
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() { defer wg.Done(); _ = w.Run(ctx) }()
+	defer func() { cancel(); wg.Wait() }()
+
+	writeConfig(t, path, `{"watch_limit": 30}`)
+	time.Sleep(50 * time.Millisecond)
+	if got := w.Current().Limit; got != 20 {
+		t.Fatalf("Current().Limit = %d, want 20 while rejected", got)
+	}
+	os.Unsetenv("WATCH_LIMIT") // setEnv restores it after the test
+
+	select {
+	case c := <-changes:
+		if c.Limit != 30 {
+			t.Errorf("Limit = %d, want 30", c.Limit)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("rejected reload was not retried")
+	}
+}
@@ -0,0 +1,582 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DecodeYAML is the Decoder used by WithYAMLFile. It supports the subset of
+// YAML used for configuration files:
+//
+//   - block mappings and sequences, nested by indentation
+//   - flow sequences and mappings: [a, b] and {a: 1}
+//   - plain, single-quoted, and double-quoted scalars
+//   - literal (|) and folded (>) block scalars with chomping indicators
+//   - null, booleans, integers, and floats as in the YAML 1.2 core schema
+//   - comments and a leading "---" document marker
+//
+// Anchors, aliases, tags, multi-line plain scalars, and multiple documents
+// are rejected with an error; use WithFile with a full YAML library if you
+// need them.
+func DecodeYAML(data []byte) (map[string]any, error) {
+	p := &yamlParser{lines: strings.Split(strings.ReplaceAll(string(trimBOM(data)), "\r\n", "\n"), "\n")}
+	if err := p.start(); err != nil {
+		return nil, err
+	}
+	if !p.next() {
+		return map[string]any{}, nil
+	}
+	v, err := p.block(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.next() {
+		return nil, p.errorf("unexpected content")
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("yaml: top level must be a mapping")
+	}
+	return m, nil
+}
+
+// yamlParser parses block structure line by line.
+type yamlParser struct {
+	lines []string
+	pos   int
+}
+
+func (p *yamlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("yaml: line %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+// start rejects tab indentation and directives and skips a leading
+// document marker.
+func (p *yamlParser) start() error {
+	for i, line := range p.lines {
+		if strings.HasPrefix(strings.TrimLeft(line, " "), "\t") && strings.TrimSpace(stripYAMLComment(line)) != "" {
+			p.pos = i
+			return p.errorf("tabs are not allowed for indentation")
+		}
+	}
+	for p.next() {
+		text := p.text()
+		switch {
+		case text == "---":
+			p.pos++
+			return nil
+		case strings.HasPrefix(text, "%"):
+			return p.errorf("directives are not supported")
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// next skips blank and comment-only lines and reports whether a content
+// line remains.
+func (p *yamlParser) next() bool {
+	for ; p.pos < len(p.lines); p.pos++ {
+		text := p.text()
+		if text == "" {
+			continue
+		}
+		if text == "..." {
+			p.pos = len(p.lines)
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// indent returns the indentation of the current line.
+func (p *yamlParser) indent() int {
+	line := p.lines[p.pos]
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// text returns the current line without indentation and comment.
+func (p *yamlParser) text() string {
+	return strings.TrimSpace(stripYAMLComment(p.lines[p.pos]))
+}
+
+// block parses the node starting at the current line, whose indentation
+// must be at least minIndent.
+func (p *yamlParser) block(minIndent int) (any, error) {
+	n := p.indent()
+	if n < minIndent {
+		return nil, nil
+	}
+	text := p.text()
+	if text == "---" {
+		return nil, p.errorf("multiple documents are not supported")
+	}
+	if isSeqItem(text) {
+		return p.sequence(n)
+	}
+	if _, _, ok := splitYAMLKey(text); ok {
+		return p.mapping(n)
+	}
+	if text[0] == '|' || text[0] == '>' {
+		return p.blockScalar(text, n-1)
+	}
+	// A lone scalar, e.g. a sequence item's value.
+	p.pos++
+	return parseYAMLInline(text)
+}
+
+// mapping parses "key: value" lines at indentation n.
+func (p *yamlParser) mapping(n int) (map[string]any, error) {
+	m := make(map[string]any)
+	for p.next() {
+		ind := p.indent()
+		if ind < n {
+			break
+		}
+		if ind > n {
+			return nil, p.errorf("unexpected indentation")
+		}
+		text := p.text()
+		key, rest, ok := splitYAMLKey(text)
+		if !ok {
+			if isSeqItem(text) {
+				break // a sequence at the parent's indentation ends the mapping
+			}
+			return nil, p.errorf("expected \"key: value\"")
+		}
+		if _, dup := m[key]; dup {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+		v, err := p.value(rest, n)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+	return m, nil
+}
+
+// sequence parses "- item" lines at indentation n.
+func (p *yamlParser) sequence(n int) ([]any, error) {
+	var items []any
+	for p.next() {
+		if p.indent() != n || !isSeqItem(p.text()) {
+			if p.indent() > n {
+				return nil, p.errorf("unexpected indentation")
+			}
+			break
+		}
+		line := p.lines[p.pos]
+		rest := strings.TrimLeft(line[n+1:], " ")
+		if stripYAMLComment(rest) == "" || strings.TrimSpace(stripYAMLComment(rest)) == "" {
+			// The item is a nested block on the following lines.
+			p.pos++
+			if !p.next() || p.indent() <= n {
+				items = append(items, nil)
+				continue
+			}
+			v, err := p.block(n + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+			continue
+		}
+		// Replace "- " with spaces so the item's content parses as a block
+		// indented past the dash, e.g. "- name: a" followed by "  port: 1".
+		p.lines[p.pos] = strings.Repeat(" ", len(line)-len(rest)) + rest
+		v, err := p.block(n + 1)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+	}
+	return items, nil
+}
+
+// value parses the value after "key:" on a line at indentation n.
+func (p *yamlParser) value(rest string, n int) (any, error) {
+	if rest == "" {
+		p.pos++
+		if !p.next() {
+			return nil, nil
+		}
+		ind := p.indent()
+		switch {
+		case ind > n:
+			return p.block(n + 1)
+		case ind == n && isSeqItem(p.text()):
+			return p.sequence(n)
+		}
+		return nil, nil
+	}
+	if rest[0] == '|' || rest[0] == '>' {
+		return p.blockScalar(rest, n)
+	}
+	p.pos++
+	return parseYAMLInline(rest)
+}
+
+// blockScalar parses a literal or folded scalar whose header is on the
+// current line; content lines are indented more than parent.
+func (p *yamlParser) blockScalar(header string, parent int) (string, error) {
+	folded := header[0] == '>'
+	chomp := byte(0)
+	for _, c := range header[1:] {
+		switch {
+		case c == '-' || c == '+':
+			chomp = byte(c)
+		case c >= '1' && c <= '9':
+			return "", p.errorf("explicit indentation indicators are not supported")
+		default:
+			return "", p.errorf("invalid block scalar header %q", header)
+		}
+	}
+	p.pos++
+
+	var lines []string
+	contentIndent := -1
+	for ; p.pos < len(p.lines); p.pos++ {
+		line := p.lines[p.pos]
+		if strings.TrimSpace(line) == "" {
+			lines = append(lines, "")
+			continue
+		}
+		ind := len(line) - len(strings.TrimLeft(line, " "))
+		if ind <= parent {
+			break
+		}
+		if contentIndent < 0 {
+			contentIndent = ind
+		}
+		if ind < contentIndent {
+			return "", p.errorf("block scalar line is less indented than the first")
+		}
+		lines = append(lines, line[contentIndent:])
+	}
+
+	// Trailing blank lines belong to the chomping, not the content.
+	trailing := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+
+	// Each content line is joined to the previous one by its line break and
+	// the blank lines between them. Folding turns a lone break between two
+	// lines that aren't more indented into a space, and drops the break
+	// before blank lines, which each become a newline. Breaks next to
+	// more-indented lines are kept.
+	var b strings.Builder
+	blanks, prev, first := 0, "", true
+	for _, line := range lines {
+		if line == "" {
+			blanks++
+			continue
+		}
+		switch {
+		case first:
+			b.WriteString(strings.Repeat("\n", blanks)) // leading blank lines
+		case folded && !strings.HasPrefix(prev, " ") && !strings.HasPrefix(line, " "):
+			if blanks == 0 {
+				b.WriteByte(' ')
+			} else {
+				b.WriteString(strings.Repeat("\n", blanks))
+			}
+		default:
+			b.WriteString(strings.Repeat("\n", blanks+1))
+		}
+		b.WriteString(line)
+		blanks, prev, first = 0, line, false
+	}
+	s := b.String()
+
+	switch chomp {
+	case '-':
+	case '+':
+		if len(lines) > 0 {
+			s += "\n"
+		}
+		s += strings.Repeat("\n", trailing)
+	default:
+		if len(lines) > 0 {
+			s += "\n"
+		}
+	}
+	return s, nil
+}
+
+// isSeqItem reports whether text starts a block sequence item.
+func isSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitYAMLKey splits "key: value" at the first colon followed by a space or
+// the end of the line, outside quotes and flow collections.
+func splitYAMLKey(text string) (key, rest string, ok bool) {
+	if text == "" || text[0] == '[' || text[0] == '{' || text[0] == '|' || text[0] == '>' {
+		return "", "", false
+	}
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == '\'' && quote == '\'' && i+1 < len(text) && text[i+1] == '\'' {
+				i++ // escaped quote
+			} else if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && i == 0:
+			quote = c
+		case c == ':' && (i+1 == len(text) || text[i+1] == ' '):
+			k := strings.TrimSpace(text[:i])
+			if k == "" {
+				return "", "", false
+			}
+			if k[0] == '"' || k[0] == '\'' {
+				unq, err := unquoteYAML(k)
+				if err != nil {
+					return "", "", false
+				}
+				k = unq
+			}
+			return k, strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// stripYAMLComment removes a comment: "#" at the start or after whitespace,
+// outside quotes.
+func stripYAMLComment(line string) string {
+	var quote byte
+	depth := 0
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == '\'' && quote == '\'' && i+1 < len(line) && line[i+1] == '\'' {
+				i++ // escaped quote
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			// Quotes only open a scalar at its start, not inside plain text.
+			if i == 0 || strings.ContainsRune(" \t[{,:-", rune(line[i-1])) || depth > 0 {
+				quote = c
+			}
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return strings.TrimRight(line[:i], " \t")
+		}
+	}
+	return strings.TrimRight(line, " \t")
+}
+
+// parseYAMLInline parses a scalar or flow collection on a single line.
+func parseYAMLInline(s string) (any, error) {
+	f := &yamlFlow{s: s}
+	v, err := f.value()
+	if err != nil {
+		return nil, err
+	}
+	f.skipSpace()
+	if f.i != len(f.s) {
+		return nil, fmt.Errorf("yaml: unexpected %q after value", f.s[f.i:])
+	}
+	return v, nil
+}
+
+// yamlFlow parses flow collections and scalars within one line.
+type yamlFlow struct {
+	s string
+	i int
+}
+
+func (f *yamlFlow) skipSpace() {
+	for f.i < len(f.s) && f.s[f.i] == ' ' {
+		f.i++
+	}
+}
+
+func (f *yamlFlow) value() (any, error) {
+	f.skipSpace()
+	if f.i == len(f.s) {
+		return nil, nil
+	}
+	switch c := f.s[f.i]; c {
+	case '[':
+		return f.seq()
+	case '{':
+		return f.mapping()
+	case '"', '\'':
+		return f.quoted()
+	case '&', '*', '!':
+		return nil, fmt.Errorf("yaml: anchors, aliases, and tags are not supported")
+	}
+	return f.plain(), nil
+}
+
+// plain reads a plain scalar up to a flow indicator (inside a collection)
+// or the end of the line.
+func (f *yamlFlow) plain() any {
+	start := f.i
+	for f.i < len(f.s) {
+		c := f.s[f.i]
+		if c == ',' || c == ']' || c == '}' {
+			break
+		}
+		if c == ':' && (f.i+1 == len(f.s) || f.s[f.i+1] == ' ') {
+			break
+		}
+		f.i++
+	}
+	return resolveYAMLScalar(strings.TrimSpace(f.s[start:f.i]))
+}
+
+func (f *yamlFlow) quoted() (string, error) {
+	q := f.s[f.i]
+	for j := f.i + 1; j < len(f.s); j++ {
+		switch {
+		case q == '"' && f.s[j] == '\\':
+			j++
+		case f.s[j] == q:
+			if q == '\'' && j+1 < len(f.s) && f.s[j+1] == '\'' {
+				j++ // '' escapes a single quote
+				continue
+			}
+			s, err := unquoteYAML(f.s[f.i : j+1])
+			f.i = j + 1
+			return s, err
+		}
+	}
+	return "", fmt.Errorf("yaml: unterminated quoted string")
+}
+
+func (f *yamlFlow) seq() ([]any, error) {
+	f.i++ // [
+	items := []any{}
+	for {
+		f.skipSpace()
+		if f.i < len(f.s) && f.s[f.i] == ']' {
+			f.i++
+			return items, nil
+		}
+		v, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+		f.skipSpace()
+		if f.i == len(f.s) {
+			return nil, fmt.Errorf("yaml: unterminated flow sequence")
+		}
+		switch f.s[f.i] {
+		case ',':
+			f.i++
+		case ']':
+		default:
+			return nil, fmt.Errorf("yaml: expected ',' or ']' in flow sequence")
+		}
+	}
+}
+
+func (f *yamlFlow) mapping() (map[string]any, error) {
+	f.i++ // {
+	m := make(map[string]any)
+	for {
+		f.skipSpace()
+		if f.i < len(f.s) && f.s[f.i] == '}' {
+			f.i++
+			return m, nil
+		}
+		k, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		f.skipSpace()
+		if f.i == len(f.s) || f.s[f.i] != ':' {
+			return nil, fmt.Errorf("yaml: expected ':' in flow mapping")
+		}
+		f.i++
+		v, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		m[fmt.Sprint(k)] = v
+		f.skipSpace()
+		if f.i == len(f.s) {
+			return nil, fmt.Errorf("yaml: unterminated flow mapping")
+		}
+		switch f.s[f.i] {
+		case ',':
+			f.i++
+		case '}':
+		default:
+			return nil, fmt.Errorf("yaml: expected ',' or '}' in flow mapping")
+		}
+	}
+}
+
+// unquoteYAML unquotes a single- or double-quoted scalar.
+func unquoteYAML(s string) (string, error) {
+	if s[0] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	}
+	u, err := strconv.Unquote(s)
+	if err != nil {
+		return "", fmt.Errorf("yaml: invalid double-quoted string %s", s)
+	}
+	return u, nil
+}
+
+// resolveYAMLScalar types a plain scalar per the YAML 1.2 core schema.
+// Integers with leading zeros stay strings, so values like zip codes and
+// file modes keep their digits.
+func resolveYAMLScalar(s string) any {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	case ".inf", ".Inf", ".INF", "+.inf", "+.Inf", "+.INF":
+		return math.Inf(1)
+	case "-.inf", "-.Inf", "-.INF":
+		return math.Inf(-1)
+	case ".nan", ".NaN", ".NAN":
+		return math.NaN()
+	}
+	digits := strings.TrimLeft(s, "+-")
+	if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0o") {
+		if n, err := strconv.ParseInt(s, 0, 64); err == nil {
+			return n
+		}
+		return s
+	}
+	if len(digits) > 1 && digits[0] == '0' && digits[1] != '.' {
+		return s
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	if strings.ContainsAny(s, "0123456789") && !strings.ContainsAny(s, "_xX") {
+		if v, err := strconv.ParseFloat(s, 64); err == nil {
+			return v
+		}
+	}
+	return s
+}
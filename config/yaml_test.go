@@ -0,0 +1,239 @@
+package config
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// YAML conformance cases, taken mostly from the examples in the YAML 1.2.2
+// specification (https://yaml.org/spec/1.2.2/), limited to the subset
+// DecodeYAML supports. Examples whose top level is not a mapping are nested
+// under a key.
+
+func TestDecodeYAML_Spec(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want map[string]any
+	}{
+		{
+			name: "2.2 mapping scalars to scalars",
+			data: "hr:  65    # Home runs\navg: 0.278 # Batting average\nrbi: 147   # Runs Batted In\n",
+			want: map[string]any{"hr": int64(65), "avg": 0.278, "rbi": int64(147)},
+		},
+		{
+			name: "2.3 mapping scalars to sequences",
+			data: "american:\n  - Boston Red Sox\n  - Detroit Tigers\nnational:\n  - New York Mets\n  - Chicago Cubs\n",
+			want: map[string]any{
+				"american": []any{"Boston Red Sox", "Detroit Tigers"},
+				"national": []any{"New York Mets", "Chicago Cubs"},
+			},
+		},
+		{
+			name: "2.4 sequence of mappings",
+			data: "players:\n  -\n    name: Mark McGwire\n    hr:   65\n    avg:  0.278\n  -\n    name: Sammy Sosa\n    hr:   63\n    avg:  0.288\n",
+			want: map[string]any{"players": []any{
+				map[string]any{"name": "Mark McGwire", "hr": int64(65), "avg": 0.278},
+				map[string]any{"name": "Sammy Sosa", "hr": int64(63), "avg": 0.288},
+			}},
+		},
+		{
+			name: "2.5 sequence of sequences",
+			data: "stats:\n- [name        , hr, avg  ]\n- [Mark McGwire, 65, 0.278]\n",
+			want: map[string]any{"stats": []any{
+				[]any{"name", "hr", "avg"},
+				[]any{"Mark McGwire", int64(65), 0.278},
+			}},
+		},
+		{
+			name: "2.12 compact nested mapping",
+			data: "items:\n- item    : Super Hoop\n  quantity: 1\n- item    : Basketball\n  quantity: 4\n",
+			want: map[string]any{"items": []any{
+				map[string]any{"item": "Super Hoop", "quantity": int64(1)},
+				map[string]any{"item": "Basketball", "quantity": int64(4)},
+			}},
+		},
+		{
+			name: "2.13 literal newlines are preserved",
+			data: "art: |\n  \\//||\\/||\n  // ||  ||__\n",
+			want: map[string]any{"art": "\\//||\\/||\n// ||  ||__\n"},
+		},
+		{
+			name: "2.14 folded newlines become spaces",
+			data: "quote: >\n  Mark McGwire's\n  year was crippled\n  by a knee injury.\n",
+			want: map[string]any{"quote": "Mark McGwire's year was crippled by a knee injury.\n"},
+		},
+		{
+			name: "2.15 folded lines keep more-indented lines and blank lines",
+			data: "text: >\n Sammy Sosa completed another\n fine season with great stats.\n\n   63 Home Runs\n   0.288 Batting Average\n\n What a year!\n",
+			want: map[string]any{"text": "Sammy Sosa completed another fine season with great stats.\n\n  63 Home Runs\n  0.288 Batting Average\n\nWhat a year!\n"},
+		},
+		{
+			name: "2.16 indentation determines scope",
+			data: "name: Mark McGwire\naccomplishment: >\n  Mark set a major league\n  home run record in 1998.\nstats: |\n  65 Home Runs\n  0.278 Batting Average\n",
+			want: map[string]any{
+				"name":           "Mark McGwire",
+				"accomplishment": "Mark set a major league home run record in 1998.\n",
+				"stats":          "65 Home Runs\n0.278 Batting Average\n",
+			},
+		},
+		{
+			name: "2.17 quoted scalars",
+			data: "unicode: \"Sosa did fine.\\u263A\"\ncontrol: \"\\b1998\\t1999\\t2000\\n\"\nhex esc: \"\\x0d\\x0a is \\r\\n\"\n\n" +
+				"single: '\"Howdy!\" he cried.'\nquoted: ' # Not a ''comment''.'\ntie-fighter: '|\\-*-/|'\n",
+			want: map[string]any{
+				"unicode":     "Sosa did fine.\u263a",
+				"control":     "\b1998\t1999\t2000\n",
+				"hex esc":     "\r\n is \r\n",
+				"single":      `"Howdy!" he cried.`,
+				"quoted":      " # Not a 'comment'.",
+				"tie-fighter": `|\-*-/|`,
+			},
+		},
+		{
+			name: "block scalar leading blank lines",
+			data: "literal: |\n\n  text\nfolded: >\n\n  a\n  b\n",
+			want: map[string]any{"literal": "\ntext\n", "folded": "\na b\n"},
+		},
+		{
+			name: "block chomping",
+			data: "strip: |-\n  text\nclip: |\n  text\nkeep: |+\n  text\n\nfolded-strip: >-\n  a\n  b\nlast: end\n",
+			want: map[string]any{
+				"strip": "text", "clip": "text\n", "keep": "text\n\n",
+				"folded-strip": "a b", "last": "end",
+			},
+		},
+		{
+			name: "core schema null and booleans",
+			data: "a: null\nb: Null\nc: NULL\nd: ~\ne:\nf: true\ng: True\nh: TRUE\ni: false\nj: FALSE\nk: yes\nl: off\n",
+			want: map[string]any{
+				"a": nil, "b": nil, "c": nil, "d": nil, "e": nil,
+				"f": true, "g": true, "h": true, "i": false, "j": false,
+				"k": "yes", "l": "off", // YAML 1.1 booleans are strings in 1.2
+			},
+		},
+		{
+			name: "core schema numbers",
+			data: "a: 0\nb: -19\nc: +12345\nd: 0o14\ne: 0x3A\nf: 1.5\ng: -0.5\nh: .5\ni: +12e03\nj: -2E+05\nk: .inf\nl: -.Inf\nm: 1_000\nn: 12:30\n",
+			want: map[string]any{
+				"a": int64(0), "b": int64(-19), "c": int64(12345), "d": int64(12), "e": int64(58),
+				"f": 1.5, "g": -0.5, "h": 0.5, "i": 12e03, "j": -2e05,
+				"k": math.Inf(1), "l": math.Inf(-1),
+				"m": "1_000", "n": "12:30", // YAML 1.1 forms are strings in 1.2
+			},
+		},
+		{
+			name: "plain scalars with indicators inside",
+			data: "url: http://example.com:8080/a#frag\nhash: value#not-a-comment\nratio: 1:2\ndash: -x\nquestion: ?x\n",
+			want: map[string]any{
+				"url": "http://example.com:8080/a#frag", "hash": "value#not-a-comment",
+				"ratio": "1:2", "dash": "-x", "question": "?x",
+			},
+		},
+		{
+			name: "quoted keys",
+			data: "\"a b\": 1\n'c: d': 2\n\"\": 3\n",
+			want: map[string]any{"a b": int64(1), "c: d": int64(2), "": int64(3)},
+		},
+		{
+			name: "flow collections",
+			data: "empty-seq: []\nempty-map: {}\nnested: [[1, 2], {a: b}, 'x, y', \"z]\"]\nmap: {a: 1, b: [c, d], 'e': ~}\n",
+			want: map[string]any{
+				"empty-seq": []any{},
+				"empty-map": map[string]any{},
+				"nested":    []any{[]any{int64(1), int64(2)}, map[string]any{"a": "b"}, "x, y", "z]"},
+				"map":       map[string]any{"a": int64(1), "b": []any{"c", "d"}, "e": nil},
+			},
+		},
+		{
+			name: "sequence at the same indentation as its key",
+			data: "a:\n- 1\n- 2\nb: 3\n",
+			want: map[string]any{"a": []any{int64(1), int64(2)}, "b": int64(3)},
+		},
+		{
+			name: "nested sequences",
+			data: "matrix:\n  -\n    - 1\n    - 2\n  -\n    - 3\nempty:\n  -\n  - x\n",
+			want: map[string]any{
+				"matrix": []any{[]any{int64(1), int64(2)}, []any{int64(3)}},
+				"empty":  []any{nil, "x"},
+			},
+		},
+		{
+			name: "document markers and comments",
+			data: "# leading comment\n---\na: 1 # trailing\n\n  # indented comment\nb: 2\n...\nignored after end marker\n",
+			want: map[string]any{"a": int64(1), "b": int64(2)},
+		},
+		{
+			name: "escaped single quote before a hash",
+			data: "a: 'it''s # x'\npw: 'don''t #1' # comment\nflow: ['a''b #c']\n'k'': #': 1\n",
+			want: map[string]any{"a": "it's # x", "pw": "don't #1", "flow": []any{"a'b #c"}, "k': #": int64(1)},
+		},
+		{
+			name: "CRLF line endings",
+			data: "a: 1\r\nb:\r\n  c: x\r\n",
+			want: map[string]any{"a": int64(1), "b": map[string]any{"c": "x"}},
+		},
+		{
+			name: "byte order mark",
+			data: "\ufeffa: 1\n",
+			want: map[string]any{"a": int64(1)},
+		},
+		{
+			name: "empty document",
+			data: "# nothing here\n",
+			want: map[string]any{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeYAML([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("DecodeYAML: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecodeYAML =\n%#v\nwant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeYAML_SpecInvalid(t *testing.T) {
+	tests := map[string]string{
+		// Invalid YAML.
+		"duplicate key":                "a: 1\na: 2\n",
+		"mapping value in plain value": "a: b: c\n",
+		"less indented sibling":        "a:\n    b: 1\n  c: 2\n",
+		"more indented sibling":        "a: 1\n  b: 2\n",
+		"sequence item misaligned":     "a:\n  - 1\n   - 2\n",
+		"tab indentation":              "a:\n\tb: 1\n",
+		"unterminated double quote":    "a: \"abc\n",
+		"unterminated single quote":    "a: 'abc\n",
+		"text after quoted scalar":     "a: 'it's'\n",
+		"unterminated flow sequence":   "a: [1, 2\n",
+		"unterminated flow mapping":    "a: {b: 1\n",
+		"flow mapping missing colon":   "a: {b}\n",
+		"invalid double-quote escape":  "a: \"\\q\"\n",
+		"key without value line":       "a: 1\nb\n",
+		"top-level sequence":           "- a\n- b\n",
+		"top-level scalar":             "just text\n",
+		"block scalar under-indented":  "a: |\n    one\n  two\n",
+		// Valid YAML outside the supported subset, rejected rather than
+		// misread.
+		"anchor and alias":            "a: &x 1\nb: *x\n",
+		"tag":                         "a: !!str 1\n",
+		"multiple documents":          "a: 1\n---\nb: 2\n",
+		"directive":                   "%YAML 1.2\n---\na: 1\n",
+		"multi-line plain scalar":     "a: one\n  two\n",
+		"indentation indicator":       "a: |2\n   text\n",
+		"multi-line flow sequence":    "a: [1,\n  2]\n",
+		"2.6 multi-line flow mapping": "Mark McGwire: {hr: 65, avg: 0.278}\nSammy Sosa: {\n    hr: 63,\n    avg: 0.288,\n }\n",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got, err := DecodeYAML([]byte(data)); err == nil {
+				t.Errorf("expected error for %q, got %#v", data, got)
+			}
+		})
+	}
+}
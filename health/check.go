@@ -0,0 +1,213 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CheckOption configures a single check added with AddCheck or
+// AddNonCriticalCheck.
+type CheckOption func(*namedCheck)
+
+// WithCheckTimeout sets the timeout for this check, overriding the
+// Checker's WithTimeout.
+func WithCheckTimeout(d time.Duration) CheckOption {
+	return func(nc *namedCheck) {
+		nc.timeout = d
+	}
+}
+
+// WithInterval caches the check's result for d, so probes read the cached
+// result instead of hitting the dependency on every request. While
+// Checker.Run is active the check is refreshed in the background every d and
+// probes never wait for it; otherwise it runs on the first probe after the
+// cached result expires.
+func WithInterval(d time.Duration) CheckOption {
+	return func(nc *namedCheck) {
+		nc.interval = d
+	}
+}
+
+// WithFailureThreshold keeps a passing check healthy until it fails n times
+// in a row, damping flaps caused by single slow or dropped requests.
+// Default: 1.
+func WithFailureThreshold(n int) CheckOption {
+	return func(nc *namedCheck) {
+		nc.failureThreshold = max(n, 1)
+	}
+}
+
+// WithSuccessThreshold keeps a failing check unhealthy until it passes n
+// times in a row. Default: 1.
+func WithSuccessThreshold(n int) CheckOption {
+	return func(nc *namedCheck) {
+		nc.successThreshold = max(n, 1)
+	}
+}
+
+// namedCheck is a registered check with its settings and state.
+type namedCheck struct {
+	name             string
+	fn               CheckFunc
+	critical         bool
+	timeout          time.Duration // 0 = the Checker's timeout
+	interval         time.Duration // 0 = run on every Check
+	failureThreshold int
+	successThreshold int
+	tags             []string
+
+	runMu sync.Mutex // serializes runs of cached checks
+
+	mu          sync.Mutex
+	checked     bool // at least one run has completed
+	healthy     bool
+	err         string
+	duration    time.Duration
+	checkedAt   time.Time
+	lastSuccess time.Time
+	failures    int // consecutive
+	successes   int // consecutive
+}
+
+func newNamedCheck(name string, fn CheckFunc, critical bool, opts []CheckOption) *namedCheck {
+	nc := &namedCheck{name: name, fn: fn, critical: critical, failureThreshold: 1, successThreshold: 1}
+	for _, opt := range opts {
+		opt(nc)
+	}
+	return nc
+}
+
+// current returns the check's result, running it first unless a cached
+// result is still valid. polling reports whether Checker.Run keeps cached
+// checks fresh.
+func (nc *namedCheck) current(ctx context.Context, timeout time.Duration, polling bool) CheckResult {
+	if nc.interval <= 0 {
+		nc.run(ctx, timeout)
+		return nc.result()
+	}
+	if !nc.fresh(polling) {
+		nc.runMu.Lock()
+		// Another probe may have refreshed it while we waited.
+		if !nc.fresh(polling) {
+			// A probe that disconnects must not record a failure in the cache.
+			nc.run(context.WithoutCancel(ctx), timeout)
+		}
+		nc.runMu.Unlock()
+	}
+	return nc.result()
+}
+
+// fresh reports whether the cached result can be used.
+func (nc *namedCheck) fresh(polling bool) bool {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+	return nc.checked && (polling || time.Since(nc.checkedAt) < nc.interval)
+}
+
+// run executes the check and records its outcome.
+func (nc *namedCheck) run(ctx context.Context, timeout time.Duration) {
+	if nc.timeout > 0 {
+		timeout = nc.timeout
+	}
+	checkCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := nc.fn(checkCtx)
+	if nc.interval > 0 && ctx.Err() != nil {
+		return // a cancelled poll says nothing about the dependency
+	}
+	nc.record(err, start, time.Since(start))
+}
+
+// record applies one outcome, flipping the status once the relevant
+// threshold is reached. The first outcome sets the status directly.
+func (nc *namedCheck) record(err error, at time.Time, d time.Duration) {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	nc.duration, nc.checkedAt = d, at
+	if err == nil {
+		nc.err = ""
+		nc.lastSuccess = at.Add(d)
+		nc.failures = 0
+		nc.successes++
+		if !nc.checked || nc.successes >= nc.successThreshold {
+			nc.healthy = true
+		}
+	} else {
+		nc.err = err.Error()
+		nc.successes = 0
+		nc.failures++
+		if !nc.checked || nc.failures >= nc.failureThreshold {
+			nc.healthy = false
+		}
+	}
+	nc.checked = true
+}
+
+// result returns the recorded state as a CheckResult.
+func (nc *namedCheck) result() CheckResult {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	r := CheckResult{
+		Status:              StatusHealthy,
+		Error:               nc.err,
+		Duration:            nc.duration.Milliseconds(),
+		ConsecutiveFailures: nc.failures,
+	}
+	if !nc.healthy {
+		r.Status = StatusUnhealthy
+	}
+	if !nc.lastSuccess.IsZero() {
+		last := nc.lastSuccess
+		r.LastSuccess = &last
+	}
+	return r
+}
+
+// Run refreshes checks added with WithInterval in the background until ctx
+// is cancelled, running each once immediately and then every interval. It
+// always returns nil, so a Checker can run as a server.Group component.
+//
+//	go h.Run(ctx)
+func (c *Checker) Run(ctx context.Context) error {
+	// Until a poller records its first result, probes still run the check
+	// themselves, one at a time with the poller.
+	c.polling.Store(true)
+	defer c.polling.Store(false)
+
+	var wg sync.WaitGroup
+	for _, nc := range c.checks {
+		if nc.interval <= 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(nc *namedCheck) {
+			defer wg.Done()
+			c.poll(ctx, nc)
+		}(nc)
+	}
+	<-ctx.Done()
+	wg.Wait()
+	return nil
+}
+
+// poll refreshes nc every interval until ctx is done.
+func (c *Checker) poll(ctx context.Context, nc *namedCheck) {
+	ticker := time.NewTicker(nc.interval)
+	defer ticker.Stop()
+	for {
+		nc.runMu.Lock()
+		nc.run(ctx, c.timeout)
+		nc.runMu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"runtime"
+	"runtime/metrics"
+	"slices"
+	"strings"
+
+	"github.com/KARTIKrocks/apikit/dbx"
+	"github.com/KARTIKrocks/apikit/httpclient"
+)
+
+// Pinger is implemented by *sql.DB and *sql.Conn.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// PingCheck returns a check that pings a database connection pool.
+//
+//	h.AddCheck("postgres", health.PingCheck(db))
+func PingCheck(db Pinger) CheckFunc {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// QueryCheck returns a check that runs query on db and reads all rows,
+// verifying the database can serve queries, not just accept connections.
+// db is any dbx.DB: *sql.DB, *sql.Conn, or a wrapper.
+//
+//	h.AddCheck("postgres", health.QueryCheck(db, "SELECT 1"))
+func QueryCheck(db dbx.DB, query string, args ...any) CheckFunc {
+	return func(ctx context.Context) error {
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+		}
+		return rows.Err()
+	}
+}
+
+// HTTPGetter is the part of an HTTP client HTTPCheck uses. It is implemented
+// by *httpclient.Client and httpclient.HTTPClient.
+type HTTPGetter interface {
+	Get(ctx context.Context, path string) (*httpclient.Response, error)
+}
+
+// HTTPCheck returns a check that GETs path with client and fails on
+// transport errors and non-2xx responses. The client's base URL, timeouts,
+// retries, and circuit breaker apply; a client without retries keeps the
+// check within its timeout.
+//
+//	billing := httpclient.New("http://billing:8080", httpclient.WithMaxRetries(0))
+//	h.AddNonCriticalCheck("billing", health.HTTPCheck(billing, "/health"))
+func HTTPCheck(client HTTPGetter, path string) CheckFunc {
+	return func(ctx context.Context) error {
+		resp, err := client.Get(ctx, path)
+		if err != nil {
+			return err
+		}
+		if !resp.IsSuccess() {
+			return fmt.Errorf("GET %s: unexpected status %d", path, resp.StatusCode)
+		}
+		return nil
+	}
+}
+
+// TCPCheck returns a check that opens and closes a TCP connection to addr
+// (host:port), e.g. for brokers without an HTTP health endpoint.
+func TCPCheck(addr string) CheckFunc {
+	return func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// DNSCheck returns a check that resolves host and fails if it has no
+// addresses.
+func DNSCheck(host string) CheckFunc {
+	return func(ctx context.Context) error {
+		addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+		if err != nil {
+			return err
+		}
+		if len(addrs) == 0 {
+			return fmt.Errorf("dns: no addresses for %s", host)
+		}
+		return nil
+	}
+}
+
+// DiskConfig configures DiskCheck. Zero thresholds are not checked.
+type DiskConfig struct {
+	// Path is any path on the filesystem to check. Default: "/".
+	Path string
+
+	// MinFreeBytes fails the check when less space is available.
+	MinFreeBytes uint64
+
+	// MinFreePercent fails the check when less than this percentage (0-100)
+	// of the filesystem is available.
+	MinFreePercent float64
+}
+
+// DiskCheck returns a check that fails when the filesystem containing
+// cfg.Path runs low on space available to the process. It is supported on
+// Linux, macOS, and FreeBSD; elsewhere the check always fails.
+//
+//	h.AddNonCriticalCheck("disk", health.DiskCheck(health.DiskConfig{
+//	    Path:           "/var/lib/app",
+//	    MinFreePercent: 10,
+//	}))
+func DiskCheck(cfg DiskConfig) CheckFunc {
+	if cfg.Path == "" {
+		cfg.Path = "/"
+	}
+	return func(context.Context) error {
+		free, total, err := diskSpace(cfg.Path)
+		if err != nil {
+			return fmt.Errorf("disk: %w", err)
+		}
+		if cfg.MinFreeBytes > 0 && free < cfg.MinFreeBytes {
+			return fmt.Errorf("disk: %d bytes free on %s, below %d", free, cfg.Path, cfg.MinFreeBytes)
+		}
+		if cfg.MinFreePercent > 0 && total > 0 {
+			if pct := float64(free) / float64(total) * 100; pct < cfg.MinFreePercent {
+				return fmt.Errorf("disk: %.1f%% free on %s, below %.1f%%", pct, cfg.Path, cfg.MinFreePercent)
+			}
+		}
+		return nil
+	}
+}
+
+// GoroutineCheck returns a check that fails when more than max goroutines
+// are running, which usually indicates a leak.
+func GoroutineCheck(max int) CheckFunc {
+	return func(context.Context) error {
+		if n := runtime.NumGoroutine(); n > max {
+			return fmt.Errorf("goroutines: %d running, above %d", n, max)
+		}
+		return nil
+	}
+}
+
+// heapMetric is the runtime/metrics name for bytes in live and not yet
+// swept heap objects. Reading it does not stop the world, unlike
+// runtime.ReadMemStats.
+const heapMetric = "/memory/classes/heap/objects:bytes"
+
+// HeapCheck returns a check that fails when the heap holds more than
+// maxBytes of objects.
+func HeapCheck(maxBytes uint64) CheckFunc {
+	return func(context.Context) error {
+		sample := []metrics.Sample{{Name: heapMetric}}
+		metrics.Read(sample)
+		if sample[0].Value.Kind() != metrics.KindUint64 {
+			return errors.New("heap: metric not supported by this Go runtime")
+		}
+		if n := sample[0].Value.Uint64(); n > maxBytes {
+			return fmt.Errorf("heap: %d bytes in use, above %d", n, maxBytes)
+		}
+		return nil
+	}
+}
+
+// BreakerState reports a circuit breaker's state. It is implemented by
+// *httpclient.CircuitBreaker.
+type BreakerState interface {
+	State() httpclient.CircuitState
+}
+
+// BreakerStates reports the states of a set of circuit breakers by key. It
+// is implemented by *httpclient.CircuitBreakerGroup.
+type BreakerStates interface {
+	States() map[string]httpclient.CircuitState
+}
+
+// CircuitBreakerCheck returns a check that fails while cb is open, reporting
+// a dependency that is failing fast. A half-open breaker passes, since it is
+// probing recovery. A nil cb, such as Client.CircuitBreaker() on a client
+// without one, always passes. Register it with AddNonCriticalCheck when the
+// service can degrade without the dependency.
+func CircuitBreakerCheck(cb BreakerState) CheckFunc {
+	return func(context.Context) error {
+		if isNil(cb) {
+			return nil
+		}
+		if cb.State() == httpclient.StateOpen {
+			return httpclient.ErrCircuitOpen
+		}
+		return nil
+	}
+}
+
+// CircuitBreakerGroupCheck returns a check that fails while any breaker in
+// g is open, naming the open keys. A nil g always passes.
+//
+//	h.AddNonCriticalCheck("upstreams", health.CircuitBreakerGroupCheck(breakers))
+func CircuitBreakerGroupCheck(g BreakerStates) CheckFunc {
+	return func(context.Context) error {
+		if isNil(g) {
+			return nil
+		}
+		var open []string
+		for key, state := range g.States() {
+			if state == httpclient.StateOpen {
+				open = append(open, key)
+			}
+		}
+		if len(open) == 0 {
+			return nil
+		}
+		slices.Sort(open)
+		return fmt.Errorf("%w: %s", httpclient.ErrCircuitOpen, strings.Join(open, ", "))
+	}
+}
+
+// isNil reports whether v is nil or a nil pointer in an interface.
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Pointer && rv.IsNil()
+}
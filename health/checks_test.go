@@ -0,0 +1,170 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KARTIKrocks/apikit/httpclient"
+)
+
+type fakePinger struct{ err error }
+
+func (p fakePinger) PingContext(context.Context) error { return p.err }
+
+type fakeDB struct{ err error }
+
+func (d fakeDB) QueryContext(context.Context, string, ...any) (*sql.Rows, error) { return nil, d.err }
+func (d fakeDB) ExecContext(context.Context, string, ...any) (sql.Result, error) { return nil, d.err }
+
+func TestPingCheck(t *testing.T) {
+	if err := PingCheck(fakePinger{})(context.Background()); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+	down := errors.New("connection refused")
+	if err := PingCheck(fakePinger{err: down})(context.Background()); !errors.Is(err, down) {
+		t.Errorf("expected %v, got %v", down, err)
+	}
+}
+
+func TestQueryCheck_Error(t *testing.T) {
+	down := errors.New("too many connections")
+	if err := QueryCheck(fakeDB{err: down}, "SELECT 1")(context.Background()); !errors.Is(err, down) {
+		t.Errorf("expected %v, got %v", down, err)
+	}
+}
+
+func TestHTTPCheck(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	client := httpclient.New(srv.URL, httpclient.WithMaxRetries(0))
+
+	if err := HTTPCheck(client, "/health")(context.Background()); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+	if err := HTTPCheck(client, "/down")(context.Background()); err == nil {
+		t.Error("expected error for 503")
+	}
+}
+
+func TestTCPCheck(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+
+	if err := TCPCheck(addr)(context.Background()); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+	ln.Close()
+	if err := TCPCheck(addr)(context.Background()); err == nil {
+		t.Error("expected error after listener closed")
+	}
+}
+
+func TestDNSCheck(t *testing.T) {
+	if err := DNSCheck("localhost")(context.Background()); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+	if err := DNSCheck("nonexistent.invalid")(context.Background()); err == nil {
+		t.Error("expected error for .invalid host")
+	}
+}
+
+func TestDiskCheck(t *testing.T) {
+	if _, _, err := diskSpace(t.TempDir()); err != nil {
+		t.Skipf("disk space unavailable: %v", err)
+	}
+	ok := DiskCheck(DiskConfig{Path: t.TempDir(), MinFreeBytes: 1})
+	if err := ok(context.Background()); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+	full := DiskCheck(DiskConfig{Path: t.TempDir(), MinFreePercent: 100.1})
+	if err := full(context.Background()); err == nil {
+		t.Error("expected error when threshold exceeds capacity")
+	}
+}
+
+func TestGoroutineCheck(t *testing.T) {
+	if err := GoroutineCheck(1_000_000)(context.Background()); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+	if err := GoroutineCheck(0)(context.Background()); err == nil {
+		t.Error("expected error above limit")
+	}
+}
+
+func TestHeapCheck(t *testing.T) {
+	if err := HeapCheck(1 << 50)(context.Background()); err != nil {
+		t.Errorf("expected nil, got %v", err)
+	}
+	if err := HeapCheck(1)(context.Background()); err == nil {
+		t.Error("expected error above limit")
+	}
+}
+
+func TestCircuitBreakerCheck(t *testing.T) {
+	cb := httpclient.NewCircuitBreaker(1, time.Hour)
+	check := CircuitBreakerCheck(cb)
+	if err := check(context.Background()); err != nil {
+		t.Errorf("expected nil while closed, got %v", err)
+	}
+	_ = cb.Call(func() error { return errors.New("upstream down") })
+	if err := check(context.Background()); !errors.Is(err, httpclient.ErrCircuitOpen) {
+		t.Errorf("expected ErrCircuitOpen, got %v", err)
+	}
+}
+
+func TestCircuitBreakerCheck_Nil(t *testing.T) {
+	var cb *httpclient.CircuitBreaker
+	if err := CircuitBreakerCheck(cb)(context.Background()); err != nil {
+		t.Errorf("expected nil for a nil breaker, got %v", err)
+	}
+	if err := CircuitBreakerCheck(nil)(context.Background()); err != nil {
+		t.Errorf("expected nil for a nil interface, got %v", err)
+	}
+	var g *httpclient.CircuitBreakerGroup
+	if err := CircuitBreakerGroupCheck(g)(context.Background()); err != nil {
+		t.Errorf("expected nil for a nil group, got %v", err)
+	}
+}
+
+func TestCircuitBreakerGroupCheck(t *testing.T) {
+	g := httpclient.NewCircuitBreakerGroup(httpclient.CircuitBreakerConfig{
+		ConsecutiveFailures: 1,
+		OpenTimeout:         time.Hour,
+	}, nil)
+	check := CircuitBreakerGroupCheck(g)
+	_ = g.Get("billing").Call(func() error { return nil })
+	if err := check(context.Background()); err != nil {
+		t.Errorf("expected nil while closed, got %v", err)
+	}
+	_ = g.Get("search").Call(func() error { return errors.New("upstream down") })
+	_ = g.Get("auth").Call(func() error { return errors.New("upstream down") })
+	err := check(context.Background())
+	if !errors.Is(err, httpclient.ErrCircuitOpen) || !strings.Contains(err.Error(), "auth, search") {
+		t.Errorf("expected ErrCircuitOpen naming auth and search, got %v", err)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !(linux || darwin || freebsd)
+
+package health
+
+import "errors"
+
+func diskSpace(string) (free, total uint64, err error) {
+	return 0, 0, errors.New("not supported on this platform")
+}
@@ -10,16 +10,21 @@
 //	})
 //	h.AddNonCriticalCheck("redis", func(ctx context.Context) error {
 //	    return rdb.Ping(ctx).Err()
-//	})
+//	}, health.WithInterval(10*time.Second), health.WithFailureThreshold(3))
+//	go h.Run(ctx) // refresh checks with an interval in the background
 //
 //	r.Get("/health", h.Handler())
 //	r.Get("/health/live", h.LiveHandler())
+//
+// Tag checks with WithTags to serve separate startup, readiness, and
+// liveness probes from StartupHandler, ReadyHandler, and LiveHandler.
 package health
 
 import (
 	"context"
 	"net/http"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/KARTIKrocks/apikit/response"
@@ -40,6 +45,14 @@ type CheckResult struct {
 	Status   string `json:"status"`
 	Error    string `json:"error,omitempty"`
 	Duration int64  `json:"duration_ms"`
+
+	// LastSuccess is when the check last passed; nil if it never has.
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+
+	// ConsecutiveFailures counts failed runs since the last success. With
+	// WithFailureThreshold the status stays healthy until it reaches the
+	// threshold, while Error already reports the latest failure.
+	ConsecutiveFailures int `json:"consecutive_failures"`
 }
 
 // Response is the full health check response.
@@ -49,17 +62,17 @@ type Response struct {
 	Timestamp int64                  `json:"timestamp"`
 }
 
-// namedCheck is an internal type pairing a name with its check function.
-type namedCheck struct {
-	name     string
-	fn       CheckFunc
-	critical bool
-}
+// DrainingCheck is the check reported while the Checker is draining.
+const DrainingCheck = "draining"
 
 // Checker runs health checks and exposes HTTP handlers.
 type Checker struct {
-	timeout time.Duration
-	checks  []namedCheck
+	timeout  time.Duration
+	checks   []*namedCheck
+	draining atomic.Bool
+	polling  atomic.Bool // Run is refreshing checks with an interval
+	started  atomic.Bool // the startup probe has passed
+	format   Format
 }
 
 // Option configures a Checker.
@@ -85,59 +98,73 @@ func NewChecker(opts ...Option) *Checker {
 
 // AddCheck registers a critical health check. If it fails the overall status
 // is "unhealthy" and the HTTP handler returns 503.
-func (c *Checker) AddCheck(name string, fn CheckFunc) {
-	c.checks = append(c.checks, namedCheck{name: name, fn: fn, critical: true})
+func (c *Checker) AddCheck(name string, fn CheckFunc, opts ...CheckOption) {
+	c.checks = append(c.checks, newNamedCheck(name, fn, true, opts))
 }
 
 // AddNonCriticalCheck registers a non-critical health check. If it fails the
 // overall status is "degraded" but the HTTP handler still returns 200.
-func (c *Checker) AddNonCriticalCheck(name string, fn CheckFunc) {
-	c.checks = append(c.checks, namedCheck{name: name, fn: fn, critical: false})
+func (c *Checker) AddNonCriticalCheck(name string, fn CheckFunc, opts ...CheckOption) {
+	c.checks = append(c.checks, newNamedCheck(name, fn, false, opts))
+}
+
+// SetDraining marks the process as shutting down. While draining, Check
+// reports "unhealthy" without running any checks, so readiness probes fail
+// and load balancers stop sending traffic. Liveness is unaffected.
+// server.WithHealthChecker calls it when graceful shutdown begins.
+func (c *Checker) SetDraining(draining bool) {
+	c.draining.Store(draining)
 }
 
-// Check runs all registered checks concurrently and returns the aggregated result.
+// Draining reports whether SetDraining(true) was called.
+func (c *Checker) Draining() bool {
+	return c.draining.Load()
+}
+
+// Check runs all registered checks concurrently and returns the aggregated
+// result. Checks added with WithInterval report their cached result instead
+// of running on every call.
 func (c *Checker) Check(ctx context.Context) Response {
-	if len(c.checks) == 0 {
+	return c.check(ctx, c.checks, true)
+}
+
+// check runs the given checks concurrently and aggregates their results.
+// While draining, honorDraining reports unhealthy without running them.
+func (c *Checker) check(ctx context.Context, selected []*namedCheck, honorDraining bool) Response {
+	if honorDraining && c.draining.Load() {
+		return Response{
+			Status: StatusUnhealthy,
+			Checks: map[string]CheckResult{
+				DrainingCheck: {Status: StatusUnhealthy, Error: "shutting down"},
+			},
+			Timestamp: time.Now().Unix(),
+		}
+	}
+	if len(selected) == 0 {
 		return Response{
 			Status:    StatusHealthy,
 			Timestamp: time.Now().Unix(),
 		}
 	}
 
-	results := make([]CheckResult, len(c.checks))
+	results := make([]CheckResult, len(selected))
 	var wg sync.WaitGroup
-	wg.Add(len(c.checks))
+	wg.Add(len(selected))
 
-	for i, nc := range c.checks {
-		go func(idx int, nc namedCheck) {
+	polling := c.polling.Load()
+	for i, nc := range selected {
+		go func(idx int, nc *namedCheck) {
 			defer wg.Done()
-
-			checkCtx, cancel := context.WithTimeout(ctx, c.timeout)
-			defer cancel()
-
-			start := time.Now()
-			err := nc.fn(checkCtx)
-			duration := time.Since(start).Milliseconds()
-
-			r := CheckResult{
-				Status:   StatusHealthy,
-				Duration: duration,
-			}
-			if err != nil {
-				r.Status = StatusUnhealthy
-				r.Error = err.Error()
-			}
-
-			results[idx] = r
+			results[idx] = nc.current(ctx, c.timeout, polling)
 		}(i, nc)
 	}
 
 	wg.Wait()
 
-	checks := make(map[string]CheckResult, len(c.checks))
+	checks := make(map[string]CheckResult, len(selected))
 	overall := StatusHealthy
 
-	for i, nc := range c.checks {
+	for i, nc := range selected {
 		checks[nc.name] = results[i]
 
 		if results[i].Status != StatusHealthy {
@@ -158,30 +185,23 @@ func (c *Checker) Check(ctx context.Context) Response {
 
 // Handler returns an HTTP handler that runs all health checks and responds
 // with 200 (healthy/degraded) or 503 (unhealthy). Compatible with
-// router.HandlerFunc (returns error).
+// router.HandlerFunc (returns error). The ?check= query parameter restricts
+// it to the named checks, e.g. ?check=db,cache.
 func (c *Checker) Handler() func(http.ResponseWriter, *http.Request) error {
-	return func(w http.ResponseWriter, r *http.Request) error {
-		resp := c.Check(r.Context())
-
-		if resp.Status == StatusUnhealthy {
-			response.New().
-				Status(http.StatusServiceUnavailable).
-				Message("Health check").
-				Data(resp).
-				Send(w)
-			return nil
-		}
-
-		response.OK(w, "Health check", resp)
-		return nil
-	}
+	return c.handler("", true, nil)
 }
 
-// LiveHandler returns an HTTP handler that always responds with 200.
-// Use this for Kubernetes liveness probes to confirm the process is running.
+// LiveHandler returns an HTTP handler for Kubernetes liveness probes. It
+// runs only checks tagged TagLiveness. When there are none it confirms the
+// process is running with a plain 200 "OK" response and no data, as before
+// probe groups existed. Draining does not affect it.
 func (c *Checker) LiveHandler() func(http.ResponseWriter, *http.Request) error {
-	return func(w http.ResponseWriter, _ *http.Request) error {
-		response.OK(w, "OK", nil)
-		return nil
+	checked := c.handler(TagLiveness, false, nil)
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if !r.URL.Query().Has("check") && !c.hasTag(TagLiveness) && !c.wantsIETF(r) {
+			response.OK(w, "OK", nil)
+			return nil
+		}
+		return checked(w, r)
 	}
 }
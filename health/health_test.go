@@ -6,6 +6,7 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -188,6 +189,14 @@ func TestLiveHandler(t *testing.T) {
 	if w.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d", w.Code)
 	}
+	// Without liveness checks the body is unchanged from earlier releases.
+	var body response.Envelope
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Message != "OK" || body.Data != nil {
+		t.Fatalf("expected message OK without data, got %s", w.Body.String())
+	}
 }
 
 func TestDefaultTimeout(t *testing.T) {
@@ -203,3 +212,139 @@ func TestWithTimeout(t *testing.T) {
 		t.Fatalf("expected timeout 10s, got %s", c.timeout)
 	}
 }
+
+func TestDraining(t *testing.T) {
+	c := NewChecker()
+	called := false
+	c.AddCheck("db", func(ctx context.Context) error { called = true; return nil })
+
+	c.SetDraining(true)
+	resp := c.Check(context.Background())
+	if resp.Status != StatusUnhealthy || resp.Checks[DrainingCheck].Status != StatusUnhealthy || called {
+		t.Fatalf("expected draining to fail readiness without running checks, got %+v", resp)
+	}
+
+	w := httptest.NewRecorder()
+	_ = c.LiveHandler()(w, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected liveness to stay healthy, got %d", w.Code)
+	}
+
+	c.SetDraining(false)
+	if resp := c.Check(context.Background()); resp.Status != StatusHealthy || !called {
+		t.Fatalf("expected checks to run again, got %+v", resp)
+	}
+}
+
+func TestCheckInterval_CachesResult(t *testing.T) {
+	var runs atomic.Int32
+	c := NewChecker()
+	c.AddCheck("db", func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	}, WithInterval(time.Hour))
+
+	for range 3 {
+		if resp := c.Check(context.Background()); resp.Status != StatusHealthy {
+			t.Fatalf("expected %q, got %q", StatusHealthy, resp.Status)
+		}
+	}
+	if n := runs.Load(); n != 1 {
+		t.Errorf("expected 1 run, got %d", n)
+	}
+}
+
+func TestCheckInterval_ExpiresWithoutRun(t *testing.T) {
+	var runs atomic.Int32
+	c := NewChecker()
+	c.AddCheck("db", func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	}, WithInterval(10*time.Millisecond))
+
+	c.Check(context.Background())
+	time.Sleep(20 * time.Millisecond)
+	c.Check(context.Background())
+	if n := runs.Load(); n != 2 {
+		t.Errorf("expected 2 runs, got %d", n)
+	}
+}
+
+func TestRun_PollsInBackground(t *testing.T) {
+	var runs atomic.Int32
+	c := NewChecker()
+	c.AddCheck("db", func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	}, WithInterval(5*time.Millisecond))
+	c.AddCheck("inline", func(ctx context.Context) error { return nil })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- c.Run(ctx) }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for runs.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := runs.Load(); n < 3 {
+		t.Fatalf("expected background runs, got %d", n)
+	}
+	before := runs.Load()
+	resp := c.Check(context.Background())
+	if resp.Status != StatusHealthy || resp.Checks["db"].LastSuccess == nil {
+		t.Errorf("unexpected result: %+v", resp)
+	}
+	// Probes read the polled result; at most the poller itself ran meanwhile.
+	if runs.Load() > before+1 {
+		t.Errorf("Check ran the polled check itself")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Run returned %v", err)
+	}
+}
+
+func TestFailureThreshold(t *testing.T) {
+	var fail atomic.Bool
+	c := NewChecker()
+	c.AddCheck("db", func(ctx context.Context) error {
+		if fail.Load() {
+			return errors.New("timeout")
+		}
+		return nil
+	}, WithFailureThreshold(3), WithSuccessThreshold(2))
+
+	c.Check(context.Background())
+	fail.Store(true)
+	for i := 1; i <= 2; i++ {
+		cr := c.Check(context.Background()).Checks["db"]
+		if cr.Status != StatusHealthy || cr.ConsecutiveFailures != i || cr.Error != "timeout" {
+			t.Fatalf("failure %d: %+v", i, cr)
+		}
+	}
+	if cr := c.Check(context.Background()).Checks["db"]; cr.Status != StatusUnhealthy || cr.ConsecutiveFailures != 3 {
+		t.Fatalf("expected unhealthy after 3 failures: %+v", cr)
+	}
+
+	fail.Store(false)
+	if cr := c.Check(context.Background()).Checks["db"]; cr.Status != StatusUnhealthy || cr.ConsecutiveFailures != 0 {
+		t.Fatalf("expected unhealthy after 1 success: %+v", cr)
+	}
+	if cr := c.Check(context.Background()).Checks["db"]; cr.Status != StatusHealthy {
+		t.Fatalf("expected healthy after 2 successes: %+v", cr)
+	}
+}
+
+func TestCheckTimeoutOverride(t *testing.T) {
+	c := NewChecker(WithTimeout(time.Hour))
+	c.AddCheck("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithCheckTimeout(10*time.Millisecond))
+
+	if resp := c.Check(context.Background()); resp.Status != StatusUnhealthy {
+		t.Fatalf("expected %q, got %q", StatusUnhealthy, resp.Status)
+	}
+}
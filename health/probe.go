@@ -0,0 +1,246 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/KARTIKrocks/apikit/errors"
+	"github.com/KARTIKrocks/apikit/response"
+)
+
+// Probe groups. Checks without tags belong to TagReadiness.
+const (
+	TagStartup   = "startup"
+	TagReadiness = "readiness"
+	TagLiveness  = "liveness"
+)
+
+// WithTags adds the check to probe groups: TagStartup, TagReadiness,
+// TagLiveness, or custom names served by GroupHandler. A check can belong to
+// several groups; Check and Handler run every check regardless of tags.
+//
+//	h.AddCheck("migrations", checkMigrations, health.WithTags(health.TagStartup))
+//	h.AddCheck("deadlock", checkWorkers, health.WithTags(health.TagLiveness))
+//	h.AddCheck("db", health.PingCheck(db), health.WithTags(health.TagStartup, health.TagReadiness))
+func WithTags(tags ...string) CheckOption {
+	return func(nc *namedCheck) {
+		nc.tags = append(nc.tags, tags...)
+	}
+}
+
+// in reports whether the check belongs to the probe group; "" matches all.
+func (nc *namedCheck) in(tag string) bool {
+	if tag == "" {
+		return true
+	}
+	if len(nc.tags) == 0 {
+		return tag == TagReadiness
+	}
+	return slices.Contains(nc.tags, tag)
+}
+
+// Format selects the body written by the HTTP handlers.
+type Format int
+
+const (
+	// FormatDefault wraps the Response in the standard response envelope.
+	FormatDefault Format = iota
+
+	// FormatIETF writes application/health+json as described by the IETF
+	// "Health Check Response Format for HTTP APIs" draft, with statuses
+	// pass, warn, and fail.
+	FormatIETF
+)
+
+// WithFormat sets the handlers' output format. Regardless of the format,
+// requests that accept application/health+json get FormatIETF.
+func WithFormat(f Format) Option {
+	return func(c *Checker) {
+		c.format = f
+	}
+}
+
+// CheckGroup runs the checks in a probe group (see WithTags) and returns the
+// aggregated result. Draining affects every group except TagStartup and
+// TagLiveness.
+func (c *Checker) CheckGroup(ctx context.Context, tag string) Response {
+	selected, _ := c.selectChecks(tag, nil)
+	return c.check(ctx, selected, drains(tag))
+}
+
+// ReadyHandler returns an HTTP handler for readiness probes: it runs the
+// checks tagged TagReadiness, including untagged checks, and fails while
+// draining.
+func (c *Checker) ReadyHandler() func(http.ResponseWriter, *http.Request) error {
+	return c.handler(TagReadiness, true, nil)
+}
+
+// StartupHandler returns an HTTP handler for startup probes. It runs the
+// checks tagged TagStartup until they first pass and from then on responds
+// with 200 without running them, so slow initialization (migrations, cache
+// warm-up) is checked once and never restarts a running process. With no
+// startup checks it passes immediately.
+func (c *Checker) StartupHandler() func(http.ResponseWriter, *http.Request) error {
+	return c.handler(TagStartup, false, &c.started)
+}
+
+// Started reports whether the startup probe has passed.
+func (c *Checker) Started() bool {
+	return c.started.Load()
+}
+
+// GroupHandler returns an HTTP handler that runs the checks in a custom
+// probe group, e.g. one per downstream team's dashboard.
+func (c *Checker) GroupHandler(tag string) func(http.ResponseWriter, *http.Request) error {
+	return c.handler(tag, drains(tag), nil)
+}
+
+// drains reports whether draining fails the probe group.
+func drains(tag string) bool {
+	return tag != TagStartup && tag != TagLiveness
+}
+
+// handler serves a probe group. When latch is set, the first passing
+// unfiltered run sets it and later requests skip the checks.
+func (c *Checker) handler(tag string, honorDraining bool, latch *atomic.Bool) func(http.ResponseWriter, *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if latch != nil && latch.Load() {
+			c.write(w, r, Response{Status: StatusHealthy, Timestamp: time.Now().Unix()})
+			return nil
+		}
+
+		names := r.URL.Query()["check"]
+		selected, err := c.selectChecks(tag, names)
+		if err != nil {
+			return err
+		}
+		resp := c.check(r.Context(), selected, honorDraining)
+		if latch != nil && len(names) == 0 && resp.Status != StatusUnhealthy {
+			latch.Store(true)
+		}
+		c.write(w, r, resp)
+		return nil
+	}
+}
+
+// hasTag reports whether any check is in the tag's group.
+func (c *Checker) hasTag(tag string) bool {
+	return slices.ContainsFunc(c.checks, func(nc *namedCheck) bool { return nc.in(tag) })
+}
+
+// selectChecks returns the checks in the group, restricted to names
+// (repeated or comma-separated) when given.
+func (c *Checker) selectChecks(tag string, names []string) ([]*namedCheck, error) {
+	var group []*namedCheck
+	for _, nc := range c.checks {
+		if nc.in(tag) {
+			group = append(group, nc)
+		}
+	}
+	if len(names) == 0 {
+		return group, nil
+	}
+
+	var selected []*namedCheck
+	for _, list := range names {
+		for _, name := range strings.Split(list, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			i := slices.IndexFunc(group, func(nc *namedCheck) bool { return nc.name == name })
+			if i < 0 {
+				return nil, errors.NotFound("Health check " + name)
+			}
+			if !slices.Contains(selected, group[i]) {
+				selected = append(selected, group[i])
+			}
+		}
+	}
+	return selected, nil
+}
+
+// write sends resp with 200 (healthy/degraded) or 503 (unhealthy) in the
+// negotiated format.
+func (c *Checker) write(w http.ResponseWriter, r *http.Request, resp Response) {
+	status := http.StatusOK
+	if resp.Status == StatusUnhealthy {
+		status = http.StatusServiceUnavailable
+	}
+
+	if c.wantsIETF(r) {
+		body, err := json.Marshal(newIETFResponse(resp))
+		if err != nil {
+			response.InternalServerError(w, "Health check")
+			return
+		}
+		response.Raw(w, status, ietfContentType, body)
+		return
+	}
+
+	response.New().
+		Status(status).
+		Message("Health check").
+		Data(resp).
+		Send(w)
+}
+
+const ietfContentType = "application/health+json"
+
+// wantsIETF reports whether to answer r in the application/health+json
+// format.
+func (c *Checker) wantsIETF(r *http.Request) bool {
+	return c.format == FormatIETF || strings.Contains(r.Header.Get("Accept"), ietfContentType)
+}
+
+// ietfResponse is the application/health+json body.
+type ietfResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string][]ietfCheck `json:"checks,omitempty"`
+}
+
+// ietfCheck is one entry of the "checks" object. Keys are
+// "<check>:responseTime", except for DrainingCheck.
+type ietfCheck struct {
+	Status        string `json:"status"`
+	Time          string `json:"time"`
+	ObservedValue *int64 `json:"observedValue,omitempty"`
+	ObservedUnit  string `json:"observedUnit,omitempty"`
+	Output        string `json:"output,omitempty"`
+}
+
+func newIETFResponse(resp Response) ietfResponse {
+	out := ietfResponse{Status: ietfStatus(resp.Status)}
+	if len(resp.Checks) == 0 {
+		return out
+	}
+	at := time.Unix(resp.Timestamp, 0).UTC().Format(time.RFC3339)
+	out.Checks = make(map[string][]ietfCheck, len(resp.Checks))
+	for name, cr := range resp.Checks {
+		ic := ietfCheck{Status: ietfStatus(cr.Status), Time: at, Output: cr.Error}
+		key := name
+		if name != DrainingCheck {
+			key += ":responseTime"
+			d := cr.Duration
+			ic.ObservedValue, ic.ObservedUnit = &d, "ms"
+		}
+		out.Checks[key] = []ietfCheck{ic}
+	}
+	return out
+}
+
+func ietfStatus(status string) string {
+	switch status {
+	case StatusHealthy:
+		return "pass"
+	case StatusDegraded:
+		return "warn"
+	default:
+		return "fail"
+	}
+}
@@ -0,0 +1,150 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	apierrors "github.com/KARTIKrocks/apikit/errors"
+)
+
+func serve(t *testing.T, h func(http.ResponseWriter, *http.Request) error, target string, accept string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	if err := h(w, req); err != nil {
+		w.Code = apierrors.HTTPStatus(err)
+	}
+	return w
+}
+
+func TestProbeGroups(t *testing.T) {
+	c := NewChecker()
+	c.AddCheck("db", func(ctx context.Context) error { return errors.New("down") })
+	c.AddCheck("workers", func(ctx context.Context) error { return nil }, WithTags(TagLiveness))
+	c.AddCheck("search", func(ctx context.Context) error { return nil }, WithTags("search"))
+
+	if w := serve(t, c.ReadyHandler(), "/ready", ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("readiness: expected 503 from untagged db, got %d", w.Code)
+	}
+	if w := serve(t, c.LiveHandler(), "/live", ""); w.Code != http.StatusOK {
+		t.Errorf("liveness: expected 200, got %d", w.Code)
+	}
+	resp := c.CheckGroup(context.Background(), "search")
+	if resp.Status != StatusHealthy || len(resp.Checks) != 1 {
+		t.Errorf("custom group: unexpected %+v", resp)
+	}
+	if resp := c.Check(context.Background()); len(resp.Checks) != 3 {
+		t.Errorf("Check should run all checks, got %d", len(resp.Checks))
+	}
+
+	c.SetDraining(true)
+	if w := serve(t, c.LiveHandler(), "/live", ""); w.Code != http.StatusOK {
+		t.Errorf("liveness while draining: expected 200, got %d", w.Code)
+	}
+	if w := serve(t, c.GroupHandler("search"), "/search", ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("custom group while draining: expected 503, got %d", w.Code)
+	}
+}
+
+func TestStartupHandler_Latches(t *testing.T) {
+	var runs atomic.Int32
+	var fail atomic.Bool
+	fail.Store(true)
+	c := NewChecker()
+	c.AddCheck("migrations", func(ctx context.Context) error {
+		runs.Add(1)
+		if fail.Load() {
+			return errors.New("pending")
+		}
+		return nil
+	}, WithTags(TagStartup))
+	h := c.StartupHandler()
+
+	if w := serve(t, h, "/startup", ""); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 before startup completes, got %d", w.Code)
+	}
+	fail.Store(false)
+	if w := serve(t, h, "/startup", ""); w.Code != http.StatusOK || !c.Started() {
+		t.Fatalf("expected 200 once started, got %d", w.Code)
+	}
+
+	fail.Store(true)
+	if w := serve(t, h, "/startup", ""); w.Code != http.StatusOK {
+		t.Errorf("expected latched 200, got %d", w.Code)
+	}
+	if n := runs.Load(); n != 2 {
+		t.Errorf("expected 2 runs, got %d", n)
+	}
+}
+
+func TestHandler_CheckFilter(t *testing.T) {
+	var dbRuns atomic.Int32
+	c := NewChecker()
+	c.AddCheck("db", func(ctx context.Context) error { dbRuns.Add(1); return nil })
+	c.AddCheck("cache", func(ctx context.Context) error { return errors.New("down") })
+
+	w := serve(t, c.Handler(), "/health?check=db", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200 for db only, got %d", w.Code)
+	}
+	var body struct{ Data Response }
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Data.Checks) != 1 {
+		t.Errorf("expected only db, got %v", body.Data.Checks)
+	}
+
+	if w := serve(t, c.Handler(), "/health?check=db,cache", ""); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 with cache, got %d", w.Code)
+	}
+	if w := serve(t, c.Handler(), "/health?check=nope", ""); w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown check, got %d", w.Code)
+	}
+}
+
+func TestHandler_IETFFormat(t *testing.T) {
+	newChecker := func(opts ...Option) *Checker {
+		c := NewChecker(opts...)
+		c.AddCheck("db", func(ctx context.Context) error { return nil })
+		c.AddNonCriticalCheck("cache", func(ctx context.Context) error { return errors.New("down") })
+		return c
+	}
+
+	check := func(w *httptest.ResponseRecorder) {
+		t.Helper()
+		if ct := w.Header().Get("Content-Type"); ct != "application/health+json" {
+			t.Fatalf("Content-Type = %q", ct)
+		}
+		var body struct {
+			Status string
+			Checks map[string][]struct {
+				Status        string
+				ObservedValue *int64
+				ObservedUnit  string
+				Output        string
+			}
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body.Status != "warn" {
+			t.Errorf("status = %q, want warn", body.Status)
+		}
+		cache := body.Checks["cache:responseTime"]
+		if len(cache) != 1 || cache[0].Status != "fail" || cache[0].Output != "down" || cache[0].ObservedUnit != "ms" {
+			t.Errorf("cache entry = %+v", cache)
+		}
+	}
+
+	check(serve(t, newChecker().Handler(), "/health", "application/health+json"))
+	check(serve(t, newChecker(WithFormat(FormatIETF)).Handler(), "/health", ""))
+}
@@ -0,0 +1,361 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ErrBodyNotReplayable is returned when a request whose body can only be read
+// once (a plain io.Reader) would have to be sent again. Requests with such
+// bodies are never retried or hedged; this error surfaces only when a
+// CallInterceptor re-sends the call.
+var ErrBodyNotReplayable = errors.New("request body cannot be replayed")
+
+// requestBody is a non-JSON request body set through the RequestBuilder.
+// Replayable bodies produce a fresh, independent reader for every attempt, so
+// they can be retried and hedged; one-shot bodies can be opened once.
+type requestBody struct {
+	contentType string
+	length      int64 // -1 when unknown
+	getBody     func() (io.ReadCloser, error)
+	oneShot     bool
+	used        atomic.Bool
+}
+
+// open returns the reader for one attempt.
+func (b *requestBody) open() (io.ReadCloser, error) {
+	if b.oneShot && b.used.Swap(true) {
+		return nil, ErrBodyNotReplayable
+	}
+	return b.getBody()
+}
+
+// bodyReplayable reports whether body can be sent more than once.
+func bodyReplayable(body any) bool {
+	rb, ok := body.(*requestBody)
+	return !ok || !rb.oneShot
+}
+
+// bytesBody returns a replayable body over b.
+func bytesBody(b []byte, contentType string) *requestBody {
+	return &requestBody{
+		contentType: contentType,
+		length:      int64(len(b)),
+		getBody: func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(b)), nil
+		},
+	}
+}
+
+// readerBody wraps r without buffering it. Readers that support random access
+// from their current offset (*os.File, *bytes.Reader, *strings.Reader, ...)
+// are replayed through independent section readers; a *bytes.Buffer is
+// replayed from its unread bytes; any other reader can be sent only once.
+func readerBody(r io.Reader, contentType string) *requestBody {
+	switch v := r.(type) {
+	case *bytes.Buffer:
+		return bytesBody(v.Bytes(), contentType)
+	case interface {
+		io.ReaderAt
+		io.Seeker
+	}:
+		if off, size, err := seekerRange(v); err == nil {
+			return &requestBody{
+				contentType: contentType,
+				length:      size - off,
+				getBody: func() (io.ReadCloser, error) {
+					return io.NopCloser(io.NewSectionReader(v, off, size-off)), nil
+				},
+			}
+		}
+	}
+	return &requestBody{
+		contentType: contentType,
+		length:      -1,
+		getBody:     func() (io.ReadCloser, error) { return io.NopCloser(r), nil },
+		oneShot:     true,
+	}
+}
+
+// seekerRange returns the current offset and total size of s, leaving the
+// offset unchanged.
+func seekerRange(s io.Seeker) (off, size int64, err error) {
+	if off, err = s.Seek(0, io.SeekCurrent); err != nil {
+		return 0, 0, err
+	}
+	if size, err = s.Seek(0, io.SeekEnd); err != nil {
+		return 0, 0, err
+	}
+	if _, err = s.Seek(off, io.SeekStart); err != nil {
+		return 0, 0, err
+	}
+	return off, size, nil
+}
+
+// --- Multipart ---
+
+// Multipart is a multipart/form-data body built from fields and file parts.
+// Parts are streamed to the connection as the request is sent, so large files
+// are never held in memory. The body is replayable (and so can be retried)
+// when every file part is: see File and FileFunc.
+//
+//	f, _ := os.Open("report.csv")
+//	defer f.Close()
+//	resp, err := client.Request().
+//	    Path("/uploads").
+//	    Multipart(httpclient.NewMultipart().
+//	        Field("title", "Q3 report").
+//	        File("file", "report.csv", f)).
+//	    Post(ctx)
+type Multipart struct {
+	parts    []multipartPart
+	boundary string
+}
+
+type multipartPart struct {
+	header textproto.MIMEHeader
+	value  string
+	body   *requestBody // nil for plain fields
+}
+
+// NewMultipart creates an empty multipart body.
+func NewMultipart() *Multipart {
+	var b [30]byte
+	_, _ = rand.Read(b[:])
+	return &Multipart{boundary: hex.EncodeToString(b[:])}
+}
+
+// Field adds a form field.
+func (m *Multipart) Field(name, value string) *Multipart {
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"`, escapeQuotes(name)))
+	m.parts = append(m.parts, multipartPart{header: h, value: value})
+	return m
+}
+
+// File adds a file part read from r, with a Content-Type of
+// application/octet-stream. r is replayable under the same rules as
+// RequestBuilder.BodyReader: files and other io.ReaderAt+io.Seeker readers
+// are re-read from their current offset on retry, while a plain io.Reader
+// makes the whole request one-shot. The caller remains responsible for
+// closing r.
+func (m *Multipart) File(field, filename string, r io.Reader) *Multipart {
+	return m.part(field, filename, readerBody(r, ""))
+}
+
+// FileFunc adds a file part whose content is produced by open, which is
+// called once per attempt and whose result is closed after sending. It keeps
+// the request replayable for any source, such as opening a file by name.
+func (m *Multipart) FileFunc(field, filename string, open func() (io.ReadCloser, error)) *Multipart {
+	return m.part(field, filename, &requestBody{length: -1, getBody: open})
+}
+
+// Part adds a part with a custom header, such as a file with a specific
+// Content-Type. r follows the same replay rules as File.
+func (m *Multipart) Part(header textproto.MIMEHeader, r io.Reader) *Multipart {
+	m.parts = append(m.parts, multipartPart{header: header, body: readerBody(r, "")})
+	return m
+}
+
+func (m *Multipart) part(field, filename string, body *requestBody) *Multipart {
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition",
+		fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(field), escapeQuotes(filename)))
+	h.Set("Content-Type", "application/octet-stream")
+	m.parts = append(m.parts, multipartPart{header: h, body: body})
+	return m
+}
+
+// ContentType returns the multipart/form-data content type with its boundary.
+func (m *Multipart) ContentType() string {
+	return "multipart/form-data; boundary=" + m.boundary
+}
+
+// body returns the multipart request body.
+func (m *Multipart) body() *requestBody {
+	oneShot := false
+	for _, p := range m.parts {
+		if p.body != nil && p.body.oneShot {
+			oneShot = true
+		}
+	}
+	return &requestBody{
+		contentType: m.ContentType(),
+		length:      -1,
+		getBody:     m.stream,
+		oneShot:     oneShot,
+	}
+}
+
+// stream writes the parts into a pipe from a goroutine. If the transport
+// stops reading and closes the body, the writer fails and the goroutine exits.
+func (m *Multipart) stream() (io.ReadCloser, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(m.writeTo(pw))
+	}()
+	return pr, nil
+}
+
+func (m *Multipart) writeTo(w io.Writer) error {
+	mw := multipart.NewWriter(w)
+	if err := mw.SetBoundary(m.boundary); err != nil {
+		return err
+	}
+	for _, p := range m.parts {
+		pw, err := mw.CreatePart(p.header)
+		if err != nil {
+			return err
+		}
+		if p.body == nil {
+			if _, err := io.WriteString(pw, p.value); err != nil {
+				return err
+			}
+			continue
+		}
+		r, err := p.body.open()
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(pw, r)
+		_ = r.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+// --- RequestBuilder ---
+
+// Form sets a URL-encoded form body (application/x-www-form-urlencoded).
+func (rb *RequestBuilder) Form(values url.Values) *RequestBuilder {
+	rb.body = bytesBody([]byte(values.Encode()), "application/x-www-form-urlencoded")
+	return rb
+}
+
+// Multipart sets a multipart/form-data body.
+func (rb *RequestBuilder) Multipart(m *Multipart) *RequestBuilder {
+	rb.body = m.body()
+	return rb
+}
+
+// BodyReader sets a raw body streamed from r with the given Content-Type.
+// Readers that support random access (io.ReaderAt and io.Seeker, such as
+// *os.File, *bytes.Reader, and *strings.Reader) and *bytes.Buffer are re-read
+// on retry. Any other reader can be sent only once, so the request is neither
+// retried nor hedged; use BodyFunc to make it replayable.
+func (rb *RequestBuilder) BodyReader(r io.Reader, contentType string) *RequestBuilder {
+	rb.body = readerBody(r, contentType)
+	return rb
+}
+
+// BodyFunc sets a raw body produced by getBody, which is called once per
+// attempt (like http.Request.GetBody), so the request can be retried with any
+// body source. length is the body size, or -1 if unknown.
+func (rb *RequestBuilder) BodyFunc(getBody func() (io.ReadCloser, error), contentType string, length int64) *RequestBuilder {
+	if length < 0 {
+		length = -1
+	}
+	rb.body = &requestBody{contentType: contentType, length: length, getBody: getBody}
+	return rb
+}
+
+// ProgressFunc reports transfer progress: the bytes transferred so far and
+// the total, or -1 if the total is unknown.
+type ProgressFunc func(transferred, total int64)
+
+// Progress sets a callback reporting progress of Download.
+func (rb *RequestBuilder) Progress(fn ProgressFunc) *RequestBuilder {
+	rb.progress = fn
+	return rb
+}
+
+// Download sends the request (GET unless a method is set) and streams the
+// response body to w instead of buffering it, so WithMaxResponseBody does not
+// apply. Connecting goes through the client's retry and circuit-breaker
+// policy; once data has been written to w the transfer is not retried.
+// Like streaming requests, downloads are not cut short by WithTimeout and end
+// when ctx is done. A non-2xx response returns an *HTTPError, regardless of
+// the error-on-status policy.
+//
+// The returned Response has no Body; its Duration covers the whole transfer.
+//
+//	f, _ := os.Create("backup.tar.gz")
+//	defer f.Close()
+//	_, err := client.Request().Path("/backups/latest").
+//	    Progress(func(n, total int64) { log.Printf("%d/%d", n, total) }).
+//	    Download(ctx, f)
+func (rb *RequestBuilder) Download(ctx context.Context, w io.Writer) (*Response, error) {
+	if rb == nil || rb.client == nil {
+		return nil, errors.New("request builder or client is nil")
+	}
+	if rb.method == "" {
+		rb.method = http.MethodGet
+	}
+	path, err := rb.buildPath()
+	if err != nil {
+		return nil, err
+	}
+
+	c := rb.client
+	start := time.Now()
+	raw, meta, err := c.openStream(ctx, streamRequest{method: rb.method, path: path, body: rb.body, headers: rb.headers})
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = raw.Body.Close() }()
+
+	var src io.Reader = raw.Body
+	if rb.progress != nil {
+		src = &progressReader{r: raw.Body, total: raw.ContentLength, fn: rb.progress}
+		rb.progress(0, raw.ContentLength)
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+
+	return &Response{
+		StatusCode: raw.StatusCode,
+		Status:     raw.Status,
+		Headers:    raw.Header,
+		Duration:   time.Since(start),
+		Attempts:   meta.Attempts,
+		RetryWait:  meta.RetryWait,
+	}, nil
+}
+
+// progressReader reports bytes read to a ProgressFunc.
+type progressReader struct {
+	r     io.Reader
+	n     int64
+	total int64
+	fn    ProgressFunc
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.n += int64(n)
+		p.fn(p.n, p.total)
+	}
+	return n, err
+}
@@ -0,0 +1,197 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	stderrors "errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRequestBuilder_Form(t *testing.T) {
+	t.Parallel()
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/x-www-form-urlencoded" {
+			t.Errorf("unexpected Content-Type %q", ct)
+		}
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("q") != "a b" || r.ContentLength != 5 {
+			t.Errorf("unexpected form %v (len %d, err %v)", r.PostForm, r.ContentLength, err)
+		}
+	})
+	t.Cleanup(ts.Close)
+
+	if _, err := c.Request().Path("/search").Form(url.Values{"q": {"a b"}}).Post(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRequestBuilder_MultipartStreamsAndRetries(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "report.csv")
+	if err := os.WriteFile(path, []byte("a,b\n1,2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = f.Close() })
+
+	var calls atomic.Int32
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("parse multipart: %v", err)
+			return
+		}
+		file, hdr, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("form file: %v", err)
+			return
+		}
+		content, _ := io.ReadAll(file)
+		if string(content) != "a,b\n1,2\n" || hdr.Filename != "report.csv" || r.FormValue("title") != "Q3" {
+			t.Errorf("unexpected upload %q %q %q", content, hdr.Filename, r.FormValue("title"))
+		}
+		// Fail the first upload to check the file is re-read on retry.
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}, WithRetryDelay(time.Millisecond))
+	t.Cleanup(ts.Close)
+
+	resp, err := c.Request().Path("/uploads").
+		Multipart(NewMultipart().Field("title", "Q3").File("file", "report.csv", f)).
+		Post(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Attempts != 2 {
+		t.Fatalf("expected a retry with the rewound file, got %d attempts", resp.Attempts)
+	}
+}
+
+func TestRequestBuilder_OneShotBodyNotRetried(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, WithMaxRetries(3), WithRetryDelay(time.Millisecond))
+	t.Cleanup(ts.Close)
+
+	// io.MultiReader hides the random-access methods of the underlying reader.
+	body := io.MultiReader(strings.NewReader("payload"))
+	_, err := c.Request().Path("/ingest").BodyReader(body, "text/plain").Post(context.Background())
+	var he *HTTPError
+	if !stderrors.As(err, &he) || he.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected a one-shot body not to be retried, got %d calls", calls.Load())
+	}
+}
+
+func TestRequestBuilder_ReplayableBodies(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var bodies []string
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, r.Header.Get("Content-Type")+":"+string(b))
+		n := len(bodies)
+		mu.Unlock()
+		if n%2 == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}, WithRetryDelay(time.Millisecond))
+	t.Cleanup(ts.Close)
+
+	ctx := context.Background()
+	if _, err := c.Request().Path("/a").BodyReader(strings.NewReader("one"), "text/plain").Put(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Request().Path("/b").BodyReader(bytes.NewBufferString("two"), "text/csv").Put(ctx); err != nil {
+		t.Fatal(err)
+	}
+	getBody := func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader("three")), nil }
+	if _, err := c.Request().Path("/c").BodyFunc(getBody, "application/xml", 5).Put(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "[text/plain:one text/plain:one text/csv:two text/csv:two application/xml:three application/xml:three]"
+	if got := "[" + strings.Join(bodies, " ") + "]"; got != want {
+		t.Fatalf("got bodies %s, want %s", got, want)
+	}
+}
+
+func TestRequestBuilder_Download(t *testing.T) {
+	t.Parallel()
+	payload := bytes.Repeat([]byte("x"), 64<<10)
+	ts, _ := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.Itoa(len(payload)))
+		_, _ = w.Write(payload)
+	})
+	t.Cleanup(ts.Close)
+	// The download is larger than the buffering limit.
+	c := New(ts.URL, WithLogger(quietLogger()), WithMaxResponseBody(1024), WithMaxRetries(0))
+
+	var buf bytes.Buffer
+	var last, total int64
+	resp, err := c.Request().Path("/file").
+		Progress(func(n, t int64) { last, total = n, t }).
+		Download(context.Background(), &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 || buf.Len() != len(payload) {
+		t.Fatalf("unexpected download: status %d, %d bytes", resp.StatusCode, buf.Len())
+	}
+	if last != int64(len(payload)) || total != int64(len(payload)) {
+		t.Fatalf("unexpected progress %d/%d", last, total)
+	}
+
+	_, err = c.Request().Path("/missing").Download(context.Background(), io.Discard)
+	var he *HTTPError
+	if !stderrors.As(err, &he) || he.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected *HTTPError 404, got %v", err)
+	}
+}
+
+func TestRequestBuilder_DownloadReportsAttempts(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}, WithMaxRetries(2), WithRetryDelay(time.Millisecond))
+	t.Cleanup(ts.Close)
+
+	resp, err := c.Request().Path("/file").Download(context.Background(), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Attempts != 2 {
+		t.Fatalf("Attempts = %d, want 2", resp.Attempts)
+	}
+	if resp.RetryWait <= 0 {
+		t.Fatalf("RetryWait = %v, want > 0", resp.RetryWait)
+	}
+}
@@ -15,6 +15,9 @@ type RequestBuilder struct {
 	headers map[string]string
 	params  map[string]string
 
+	// progress reports Download progress when set.
+	progress ProgressFunc
+
 	// errorOnStatus overrides the client's error-on-status policy for this
 	// request when non-nil.
 	errorOnStatus *bool
@@ -32,7 +35,8 @@ func (rb *RequestBuilder) Path(path string) *RequestBuilder {
 	return rb
 }
 
-// Body sets the request body
+// Body sets the request body, sent as JSON. Use Form, Multipart, BodyReader,
+// or BodyFunc for other encodings.
 func (rb *RequestBuilder) Body(body any) *RequestBuilder {
 	rb.body = body
 	return rb
@@ -94,19 +98,9 @@ func (rb *RequestBuilder) Send(ctx context.Context) (*Response, error) {
 		return nil, errors.New("request builder or client is nil")
 	}
 
-	// Build query string
-	path := rb.path
-	if len(rb.params) > 0 {
-		u, err := url.Parse(rb.path)
-		if err != nil {
-			return nil, err
-		}
-		q := u.Query()
-		for k, v := range rb.params {
-			q.Add(k, v)
-		}
-		u.RawQuery = q.Encode()
-		path = u.String()
+	path, err := rb.buildPath()
+	if err != nil {
+		return nil, err
 	}
 
 	errorOnStatus := rb.client.errorOnStatus
@@ -117,6 +111,23 @@ func (rb *RequestBuilder) Send(ctx context.Context) (*Response, error) {
 	return rb.client.do(ctx, rb.method, path, rb.body, rb.headers, errorOnStatus)
 }
 
+// buildPath returns the path with the builder's query parameters merged in.
+func (rb *RequestBuilder) buildPath() (string, error) {
+	if len(rb.params) == 0 {
+		return rb.path, nil
+	}
+	u, err := url.Parse(rb.path)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	for k, v := range rb.params {
+		q.Add(k, v)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
 // Get is a shorthand for Method("GET").Send()
 func (rb *RequestBuilder) Get(ctx context.Context) (*Response, error) {
 	rb.method = "GET"
@@ -0,0 +1,512 @@
+package httpclient
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	stderrors "errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStatus reports how the response cache handled a request.
+type CacheStatus string
+
+const (
+	// CacheMiss means the response came from the origin server.
+	CacheMiss CacheStatus = "miss"
+	// CacheHit means a fresh stored response was served without contacting
+	// the server.
+	CacheHit CacheStatus = "hit"
+	// CacheRevalidated means a stored response was confirmed with a
+	// conditional request (304 Not Modified) and served.
+	CacheRevalidated CacheStatus = "revalidated"
+	// CacheStale means a stale stored response was served, under
+	// stale-while-revalidate or stale-if-error.
+	CacheStale CacheStatus = "stale"
+)
+
+// CacheEntry is a stored response. Its fields are exported so CacheStorage
+// implementations can serialize it.
+type CacheEntry struct {
+	StatusCode int
+	Status     string
+	Header     http.Header
+	Body       []byte
+
+	// RequestTime and ResponseTime bracket the request that produced (or
+	// last revalidated) the entry, for age calculation.
+	RequestTime  time.Time
+	ResponseTime time.Time
+
+	// Vary holds the request header values named by the response's Vary
+	// header; a stored response only matches requests with the same values.
+	Vary map[string]string
+}
+
+// size approximates the memory held by the entry.
+func (e *CacheEntry) size() int64 {
+	n := int64(len(e.Body))
+	for k, vs := range e.Header {
+		for _, v := range vs {
+			n += int64(len(k) + len(v))
+		}
+	}
+	return n
+}
+
+// CacheStorage stores cache entries by key. Implementations must be safe for
+// concurrent use and should treat entries as immutable.
+type CacheStorage interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, entry *CacheEntry)
+	Delete(key string)
+}
+
+// LRUCache is an in-memory CacheStorage bounded by the total size of the
+// stored bodies and headers, evicting the least recently used entries.
+type LRUCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+type lruItem struct {
+	key   string
+	entry *CacheEntry
+	size  int64
+}
+
+// NewLRUCache creates an in-memory cache holding up to maxBytes of responses.
+// Responses larger than maxBytes are not stored.
+func NewLRUCache(maxBytes int64) *LRUCache {
+	return &LRUCache{
+		maxBytes: maxBytes,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Get implements CacheStorage.
+func (c *LRUCache) Get(key string) (*CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return el.Value.(*lruItem).entry, true
+}
+
+// Set implements CacheStorage.
+func (c *LRUCache) Set(key string, entry *CacheEntry) {
+	size := int64(len(key)) + entry.size()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeLocked(key)
+	if size > c.maxBytes {
+		return
+	}
+	c.items[key] = c.ll.PushFront(&lruItem{key: key, entry: entry, size: size})
+	c.size += size
+	for c.size > c.maxBytes {
+		c.removeLocked(c.ll.Back().Value.(*lruItem).key)
+	}
+}
+
+// Delete implements CacheStorage.
+func (c *LRUCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.removeLocked(key)
+}
+
+// Len returns the number of stored entries.
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+func (c *LRUCache) removeLocked(key string) {
+	if el, ok := c.items[key]; ok {
+		c.ll.Remove(el)
+		delete(c.items, key)
+		c.size -= el.Value.(*lruItem).size
+	}
+}
+
+// --- Cache-Control ---
+
+// cacheControl holds parsed Cache-Control directives, keyed by lowercase name.
+type cacheControl map[string]string
+
+func parseCacheControl(h http.Header) cacheControl {
+	cc := cacheControl{}
+	for _, line := range h.Values("Cache-Control") {
+		for _, part := range strings.Split(line, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name == "" {
+				continue
+			}
+			cc[strings.ToLower(name)] = strings.Trim(value, `"`)
+		}
+	}
+	return cc
+}
+
+func (cc cacheControl) has(directive string) bool {
+	_, ok := cc[directive]
+	return ok
+}
+
+// seconds returns a delta-seconds directive as a duration.
+func (cc cacheControl) seconds(directive string) (time.Duration, bool) {
+	v, ok := cc[directive]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * time.Second, true
+}
+
+// --- Freshness (RFC 9111 section 4.2) ---
+
+// heuristicallyCacheable lists the status codes that may be stored without
+// explicit freshness information (RFC 9110 section 15.1).
+var heuristicallyCacheable = map[int]bool{
+	200: true, 203: true, 204: true, 300: true, 301: true, 308: true,
+	404: true, 405: true, 410: true, 414: true, 501: true,
+}
+
+// freshnessLifetime returns how long the entry is fresh after generation.
+func (e *CacheEntry) freshnessLifetime(cc cacheControl) time.Duration {
+	if d, ok := cc.seconds("max-age"); ok {
+		return d
+	}
+	date := e.date()
+	if v := e.Header.Get("Expires"); v != "" {
+		exp, err := http.ParseTime(v)
+		if err != nil || !exp.After(date) {
+			return 0 // invalid or past Expires means already stale
+		}
+		return exp.Sub(date)
+	}
+	// Heuristic freshness: 10% of the time since last modification.
+	if lm, err := http.ParseTime(e.Header.Get("Last-Modified")); err == nil && lm.Before(date) {
+		return date.Sub(lm) / 10
+	}
+	return 0
+}
+
+// date returns the Date header, or the response time if absent or invalid.
+func (e *CacheEntry) date() time.Time {
+	if d, err := http.ParseTime(e.Header.Get("Date")); err == nil {
+		return d
+	}
+	return e.ResponseTime
+}
+
+// age returns the entry's current age.
+func (e *CacheEntry) age(now time.Time) time.Duration {
+	apparent := max(0, e.ResponseTime.Sub(e.date()))
+	var ageValue time.Duration
+	if n, err := strconv.ParseInt(e.Header.Get("Age"), 10, 64); err == nil && n > 0 {
+		ageValue = time.Duration(n) * time.Second
+	}
+	corrected := ageValue + e.ResponseTime.Sub(e.RequestTime)
+	return max(apparent, corrected) + now.Sub(e.ResponseTime)
+}
+
+// response builds a Response from the entry. The header and body are copied
+// so callers can't modify the stored entry.
+func (e *CacheEntry) response(status CacheStatus, age time.Duration) (*Response, error) {
+	resp := &Response{
+		StatusCode:  e.StatusCode,
+		Status:      e.Status,
+		Headers:     e.Header.Clone(),
+		Body:        bytes.Clone(e.Body),
+		CacheStatus: status,
+	}
+	resp.Headers.Set("Age", strconv.FormatInt(int64(age/time.Second), 10))
+	if e.StatusCode >= 400 {
+		return resp, &HTTPError{StatusCode: e.StatusCode, Status: e.Status, Body: resp.Body}
+	}
+	return resp, nil
+}
+
+// --- Cache layer ---
+
+// responseCache is a private (single-user) HTTP cache per RFC 9111, wrapped
+// around the client's retry loop.
+type responseCache struct {
+	storage CacheStorage
+
+	mu           sync.Mutex
+	revalidating map[string]bool
+}
+
+// WithCache enables an RFC 9111 private cache for GET requests, stored in
+// storage (for example NewLRUCache(64 << 20)). Fresh responses are served
+// without contacting the server; stale ones are revalidated with
+// If-None-Match/If-Modified-Since. stale-while-revalidate serves a stale
+// response while refreshing it in the background, and stale-if-error serves
+// one when the server fails. Successful POST, PUT, PATCH, and DELETE requests
+// invalidate the stored response for their URL. Response.CacheStatus reports
+// the outcome.
+//
+// The cache wraps the retry loop and sits inside CallInterceptors, so call
+// interceptors see cache hits. Streaming requests and downloads bypass it.
+func WithCache(storage CacheStorage) Option {
+	return func(c *Client) {
+		if storage == nil {
+			c.cache = nil
+			return
+		}
+		c.cache = &responseCache{storage: storage, revalidating: make(map[string]bool)}
+	}
+}
+
+// cached wraps next with the client's response cache.
+func (c *Client) cached(next CallHandler) CallHandler {
+	rc := c.cache
+	return func(ctx context.Context, call *Call) (*Response, error) {
+		key := c.baseURL + call.Path
+
+		if call.Method != http.MethodGet {
+			resp, err := next(ctx, call)
+			if call.Method != http.MethodHead && call.Method != http.MethodOptions &&
+				resp != nil && resp.StatusCode < 400 {
+				rc.storage.Delete(key)
+			}
+			return resp, err
+		}
+
+		reqHeader := c.cacheRequestHeader(call)
+		reqCC := parseCacheControl(reqHeader)
+		if reqCC.has("no-store") {
+			return next(ctx, call)
+		}
+
+		entry, ok := rc.storage.Get(key)
+		if !ok || !varyMatches(entry, reqHeader) {
+			return rc.fetch(ctx, next, call, key, nil, reqHeader)
+		}
+
+		now := time.Now()
+		cc := parseCacheControl(entry.Header)
+		age := entry.age(now)
+		lifetime := entry.freshnessLifetime(cc)
+		// The request can insist on a validated response, which neither a
+		// fresh hit nor a stale-while-revalidate answer satisfies.
+		reqUsable := !reqCC.has("no-cache") && reqHeader.Get("Pragma") != "no-cache"
+		if maxAge, ok := reqCC.seconds("max-age"); ok && age > maxAge {
+			reqUsable = false
+		}
+		if age < lifetime && reqUsable && !cc.has("no-cache") {
+			return entry.response(CacheHit, age)
+		}
+
+		if swr, ok := cc.seconds("stale-while-revalidate"); ok && reqUsable && age >= lifetime &&
+			!cc.has("must-revalidate") && !cc.has("no-cache") && age-lifetime <= swr {
+			rc.revalidateAsync(ctx, next, call, key, entry, reqHeader)
+			return entry.response(CacheStale, age)
+		}
+
+		return rc.fetch(ctx, next, call, key, entry, reqHeader)
+	}
+}
+
+// fetch sends the request, conditionally when a stored entry has validators,
+// and updates the cache from the result.
+func (rc *responseCache) fetch(ctx context.Context, next CallHandler, call *Call, key string, entry *CacheEntry, reqHeader http.Header) (*Response, error) {
+	if entry != nil {
+		call = conditionalCall(call, entry)
+	}
+
+	reqTime := time.Now()
+	resp, err := next(ctx, call)
+	respTime := time.Now()
+
+	if entry != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotModified {
+			updated := revalidated(entry, resp.Headers, reqTime, respTime)
+			rc.storage.Set(key, updated)
+			out, outErr := updated.response(CacheRevalidated, updated.age(respTime))
+			out.Attempts, out.RetryWait, out.Duration = resp.Attempts, resp.RetryWait, resp.Duration
+			return out, outErr
+		}
+		if serveStaleOnError(entry, resp, err, respTime) {
+			return entry.response(CacheStale, entry.age(respTime))
+		}
+	}
+
+	if resp != nil {
+		resp.CacheStatus = CacheMiss
+		if stored, ok := storable(resp, err, reqHeader, reqTime, respTime); ok {
+			rc.storage.Set(key, stored)
+		}
+	}
+	return resp, err
+}
+
+// revalidateAsync refreshes entry in the background, at most once at a time
+// per key.
+func (rc *responseCache) revalidateAsync(ctx context.Context, next CallHandler, call *Call, key string, entry *CacheEntry, reqHeader http.Header) {
+	rc.mu.Lock()
+	if rc.revalidating[key] {
+		rc.mu.Unlock()
+		return
+	}
+	rc.revalidating[key] = true
+	rc.mu.Unlock()
+
+	go func() {
+		defer func() {
+			rc.mu.Lock()
+			delete(rc.revalidating, key)
+			rc.mu.Unlock()
+		}()
+		_, _ = rc.fetch(context.WithoutCancel(ctx), next, call, key, entry, reqHeader)
+	}()
+}
+
+// cacheRequestHeader returns the headers the request will be sent with, for
+// Vary matching and request directives.
+func (c *Client) cacheRequestHeader(call *Call) http.Header {
+	h := make(http.Header)
+	c.mu.RLock()
+	for k, v := range c.headers {
+		h.Set(k, v)
+	}
+	c.mu.RUnlock()
+	for k, v := range call.Headers {
+		h.Set(k, v)
+	}
+	return h
+}
+
+// conditionalCall returns a copy of call with validators from entry.
+func conditionalCall(call *Call, entry *CacheEntry) *Call {
+	etag, lastModified := entry.Header.Get("ETag"), entry.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return call
+	}
+	cp := *call
+	cp.Headers = make(map[string]string, len(call.Headers)+2)
+	for k, v := range call.Headers {
+		cp.Headers[k] = v
+	}
+	if etag != "" {
+		cp.Headers["If-None-Match"] = etag
+	}
+	if lastModified != "" {
+		cp.Headers["If-Modified-Since"] = lastModified
+	}
+	return &cp
+}
+
+// revalidated returns a copy of entry updated with the headers of a 304
+// response (RFC 9111 section 4.3.4).
+func revalidated(entry *CacheEntry, header http.Header, reqTime, respTime time.Time) *CacheEntry {
+	updated := *entry
+	updated.Header = entry.Header.Clone()
+	for k, vs := range header {
+		switch k {
+		case "Content-Length", "Content-Encoding", "Transfer-Encoding":
+			continue
+		}
+		updated.Header[k] = append([]string(nil), vs...)
+	}
+	updated.RequestTime, updated.ResponseTime = reqTime, respTime
+	return &updated
+}
+
+// serveStaleOnError reports whether entry may be served because the origin
+// failed, under the stored response's stale-if-error directive.
+func serveStaleOnError(entry *CacheEntry, resp *Response, err error, now time.Time) bool {
+	failed := false
+	switch {
+	case resp != nil:
+		switch resp.StatusCode {
+		case 500, 502, 503, 504:
+			failed = true
+		}
+	case err != nil:
+		failed = !stderrors.Is(err, context.Canceled)
+	}
+	if !failed {
+		return false
+	}
+	cc := parseCacheControl(entry.Header)
+	sie, ok := cc.seconds("stale-if-error")
+	if !ok || cc.has("must-revalidate") {
+		return false
+	}
+	return entry.age(now)-entry.freshnessLifetime(cc) <= sie
+}
+
+// storable converts a response into a cache entry if it may be stored.
+func storable(resp *Response, err error, reqHeader http.Header, reqTime, respTime time.Time) (*CacheEntry, bool) {
+	var he *HTTPError
+	if err != nil && !stderrors.As(err, &he) {
+		return nil, false
+	}
+	if !heuristicallyCacheable[resp.StatusCode] {
+		return nil, false
+	}
+	cc := parseCacheControl(resp.Headers)
+	if cc.has("no-store") {
+		return nil, false
+	}
+
+	vary := map[string]string{}
+	for _, line := range resp.Headers.Values("Vary") {
+		for _, name := range strings.Split(line, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "*" {
+				return nil, false
+			}
+			if name != "" {
+				vary[name] = reqHeader.Get(name)
+			}
+		}
+	}
+
+	entry := &CacheEntry{
+		StatusCode:   resp.StatusCode,
+		Status:       resp.Status,
+		Header:       resp.Headers.Clone(),
+		Body:         bytes.Clone(resp.Body),
+		RequestTime:  reqTime,
+		ResponseTime: respTime,
+		Vary:         vary,
+	}
+	usable := entry.freshnessLifetime(cc) > 0 ||
+		entry.Header.Get("ETag") != "" || entry.Header.Get("Last-Modified") != "" ||
+		cc.has("stale-while-revalidate") || cc.has("stale-if-error")
+	if !usable {
+		return nil, false
+	}
+	return entry, true
+}
+
+// varyMatches reports whether the request matches the entry's Vary values.
+func varyMatches(entry *CacheEntry, reqHeader http.Header) bool {
+	for name, value := range entry.Vary {
+		if reqHeader.Get(name) != value {
+			return false
+		}
+	}
+	return true
+}
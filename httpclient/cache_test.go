@@ -0,0 +1,290 @@
+package httpclient
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newCacheServer serves handler and returns a client with an LRU cache.
+func newCacheServer(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	ts, c := newTestServer(handler, WithCache(NewLRUCache(1<<20)), WithMaxRetries(0))
+	t.Cleanup(ts.Close)
+	return c
+}
+
+func TestCache_FreshHit(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	c := newCacheServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		fmt.Fprintf(w, "v%d", calls.Add(1))
+	})
+
+	ctx := context.Background()
+	first, err := c.Get(ctx, "/countries")
+	if err != nil || first.CacheStatus != CacheMiss {
+		t.Fatalf("expected miss, got %v %v", first.CacheStatus, err)
+	}
+	second, err := c.Get(ctx, "/countries")
+	if err != nil || second.CacheStatus != CacheHit || second.String() != "v1" {
+		t.Fatalf("expected hit with cached body, got %v %q %v", second.CacheStatus, second.String(), err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected one origin request, got %d", calls.Load())
+	}
+
+	// Other URLs and request no-cache bypass the stored response.
+	if resp, _ := c.Get(ctx, "/countries?page=2"); resp.CacheStatus != CacheMiss {
+		t.Fatalf("expected query string to be part of the key, got %v", resp.CacheStatus)
+	}
+	resp, err := c.Request().Path("/countries").Header("Cache-Control", "no-cache").Get(ctx)
+	if err != nil || resp.CacheStatus != CacheMiss || resp.String() != "v3" {
+		t.Fatalf("expected no-cache to refetch, got %v %q", resp.CacheStatus, resp.String())
+	}
+}
+
+func TestCache_RequestDirectivesBypassStaleWhileRevalidate(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	c := newCacheServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60, stale-while-revalidate=60")
+		fmt.Fprintf(w, "v%d", calls.Add(1))
+	})
+
+	ctx := context.Background()
+	if _, err := c.Get(ctx, "/countries"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A fresh entry allowing stale-while-revalidate must still be refetched
+	// when the request asks for a validated response.
+	for i, header := range [][2]string{
+		{"Cache-Control", "no-cache"},
+		{"Pragma", "no-cache"},
+		{"Cache-Control", "max-age=0"},
+	} {
+		time.Sleep(10 * time.Millisecond) // so the stored age exceeds max-age=0
+		resp, err := c.Request().Path("/countries").Header(header[0], header[1]).Get(ctx)
+		want := fmt.Sprintf("v%d", i+2)
+		if err != nil || resp.CacheStatus != CacheMiss || resp.String() != want {
+			t.Fatalf("%s: %s: expected a refetched %s, got %v %q %v", header[0], header[1], want, resp.CacheStatus, resp.String(), err)
+		}
+	}
+}
+
+func TestCache_RevalidatesWithETag(t *testing.T) {
+	t.Parallel()
+	var calls, notModified atomic.Int32
+	c := newCacheServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprint(w, "body")
+	})
+
+	ctx := context.Background()
+	if _, err := c.Get(ctx, "/ref"); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := c.Get(ctx, "/ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.CacheStatus != CacheRevalidated || resp.StatusCode != 200 || resp.String() != "body" {
+		t.Fatalf("expected revalidated 200, got %v %d %q", resp.CacheStatus, resp.StatusCode, resp.String())
+	}
+	if calls.Load() != 2 || notModified.Load() != 1 {
+		t.Fatalf("expected a conditional request, got %d calls / %d 304s", calls.Load(), notModified.Load())
+	}
+}
+
+func TestCache_RevalidatesWithLastModified(t *testing.T) {
+	t.Parallel()
+	lastModified := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+	c := newCacheServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=0")
+		w.Header().Set("Last-Modified", lastModified)
+		if r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprint(w, "body")
+	})
+
+	_, _ = c.Get(context.Background(), "/ref")
+	resp, err := c.Get(context.Background(), "/ref")
+	if err != nil || resp.CacheStatus != CacheRevalidated {
+		t.Fatalf("expected revalidation via If-Modified-Since, got %v %v", resp.CacheStatus, err)
+	}
+}
+
+func TestCache_StaleWhileRevalidate(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	refreshed := make(chan struct{}, 1)
+	c := newCacheServer(t, func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Cache-Control", "max-age=0, stale-while-revalidate=60")
+		fmt.Fprintf(w, "v%d", n)
+		if n == 2 {
+			refreshed <- struct{}{}
+		}
+	})
+
+	ctx := context.Background()
+	_, _ = c.Get(ctx, "/ref")
+	resp, err := c.Get(ctx, "/ref")
+	if err != nil || resp.CacheStatus != CacheStale || resp.String() != "v1" {
+		t.Fatalf("expected stale v1, got %v %q %v", resp.CacheStatus, resp.String(), err)
+	}
+
+	select {
+	case <-refreshed:
+	case <-time.After(time.Second):
+		t.Fatal("expected a background revalidation")
+	}
+	// The background fetch stores v2 once it completes.
+	deadline := time.Now().Add(time.Second)
+	for {
+		resp, _ = c.Get(ctx, "/ref")
+		if resp.String() == "v2" || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if resp.String() != "v2" {
+		t.Fatalf("expected refreshed body, got %q", resp.String())
+	}
+}
+
+func TestCache_StaleIfError(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	c := newCacheServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) > 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Cache-Control", "max-age=0, stale-if-error=60")
+		fmt.Fprint(w, "good")
+	})
+
+	_, _ = c.Get(context.Background(), "/ref")
+	resp, err := c.Get(context.Background(), "/ref")
+	if err != nil || resp.CacheStatus != CacheStale || resp.String() != "good" {
+		t.Fatalf("expected stale response on 503, got %v %v", resp.CacheStatus, err)
+	}
+}
+
+func TestCache_NotStoredAndInvalidation(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	c := newCacheServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		switch r.URL.Path {
+		case "/private":
+			w.Header().Set("Cache-Control", "no-store")
+		case "/vary-star":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "*")
+		default:
+			w.Header().Set("Cache-Control", "max-age=60")
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	ctx := context.Background()
+	for _, path := range []string{"/private", "/private", "/vary-star", "/vary-star"} {
+		if resp, _ := c.Get(ctx, path); resp.CacheStatus != CacheMiss {
+			t.Fatalf("%s: expected miss, got %v", path, resp.CacheStatus)
+		}
+	}
+
+	_, _ = c.Get(ctx, "/items/1")
+	if resp, _ := c.Get(ctx, "/items/1"); resp.CacheStatus != CacheHit {
+		t.Fatal("expected hit before invalidation")
+	}
+	if _, err := c.Put(ctx, "/items/1", map[string]int{"n": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if resp, _ := c.Get(ctx, "/items/1"); resp.CacheStatus != CacheMiss {
+		t.Fatalf("expected PUT to invalidate the stored response, got %v", resp.CacheStatus)
+	}
+}
+
+func TestCache_Vary(t *testing.T) {
+	t.Parallel()
+	c := newCacheServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		fmt.Fprint(w, r.Header.Get("Accept-Language"))
+	})
+
+	ctx := context.Background()
+	get := func(lang string) *Response {
+		resp, err := c.Request().Path("/greeting").Header("Accept-Language", lang).Get(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	get("en")
+	if resp := get("en"); resp.CacheStatus != CacheHit {
+		t.Fatalf("expected hit for the same language, got %v", resp.CacheStatus)
+	}
+	if resp := get("fr"); resp.CacheStatus != CacheMiss || resp.String() != "fr" {
+		t.Fatalf("expected miss for a different language, got %v %q", resp.CacheStatus, resp.String())
+	}
+}
+
+func TestCache_CachedErrorStatus(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	c := newCacheServer(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	for range 2 {
+		_, err := c.Get(context.Background(), "/missing")
+		var he *HTTPError
+		if !stderrors.As(err, &he) || he.StatusCode != http.StatusNotFound {
+			t.Fatalf("expected *HTTPError 404, got %v", err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected the 404 to be cached, got %d calls", calls.Load())
+	}
+}
+
+func TestLRUCache_EvictsBySize(t *testing.T) {
+	c := NewLRUCache(100)
+	entry := func(n int) *CacheEntry { return &CacheEntry{Header: http.Header{}, Body: make([]byte, n)} }
+
+	c.Set("a", entry(40))
+	c.Set("b", entry(40))
+	c.Get("a") // b is now least recently used
+	c.Set("c", entry(40))
+	if _, ok := c.Get("b"); ok {
+		t.Fatal("expected b to be evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("expected a to survive")
+	}
+	c.Set("huge", entry(200))
+	if _, ok := c.Get("huge"); ok || c.Len() != 2 {
+		t.Fatalf("expected an oversized entry to be skipped, len %d", c.Len())
+	}
+}
@@ -1,11 +1,18 @@
 package httpclient
 
 import (
+	"context"
 	"errors"
+	"net/http"
+	"net/url"
 	"sync"
 	"time"
 )
 
+// ErrCircuitOpen is returned when a circuit breaker rejects a call because it
+// is open, or half-open with all probe slots taken. Check with errors.Is.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
 // CircuitState represents the state of a circuit breaker
 type CircuitState int
 
@@ -16,20 +23,130 @@ const (
 	StateHalfOpen
 )
 
+// String returns the state name.
+func (s CircuitState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// CircuitBreakerConfig configures a CircuitBreaker.
+//
+// The breaker trips in one of two modes. With FailureRatio set, it trips when
+// at least MinRequests calls were seen in the rolling window and the share of
+// failures reaches FailureRatio. Otherwise it trips after
+// ConsecutiveFailures failures in a row.
+type CircuitBreakerConfig struct {
+	// Name identifies the breaker in OnStateChange. Breakers created by a
+	// CircuitBreakerGroup are named by their key.
+	Name string
+
+	// ConsecutiveFailures trips the breaker after this many failures in a row
+	// when FailureRatio is zero. Default: 5.
+	ConsecutiveFailures int
+
+	// FailureRatio (0–1] trips the breaker when failures/total in the window
+	// reaches it. Zero selects consecutive-failure mode.
+	FailureRatio float64
+
+	// MinRequests is the minimum number of calls in the window before
+	// FailureRatio is evaluated. Default: 10.
+	MinRequests int
+
+	// Window is the rolling time window for FailureRatio. Default: 10s.
+	Window time.Duration
+
+	// WindowBuckets is the number of buckets the time window is divided
+	// into; more buckets age out old outcomes more smoothly. Default: 10.
+	WindowBuckets int
+
+	// WindowSize, when > 0, uses a count-based window over the last
+	// WindowSize calls instead of the time window.
+	WindowSize int
+
+	// OpenTimeout is how long the breaker stays open before allowing probes.
+	// Default: 30s.
+	OpenTimeout time.Duration
+
+	// HalfOpenProbes is the number of concurrent probe calls allowed while
+	// half-open. Default: 1.
+	HalfOpenProbes int
+
+	// HalfOpenSuccesses is the number of successful probes needed to close
+	// the breaker. Default: HalfOpenProbes.
+	HalfOpenSuccesses int
+
+	// Classify decides how a call's error counts. Default: DefaultClassify.
+	Classify func(err error) Outcome
+
+	// OnStateChange is called (synchronously, without the breaker's lock
+	// held) after every state transition. Use it for alerting and metrics.
+	OnStateChange func(name string, from, to CircuitState)
+}
+
+// Outcome is how a call's result counts towards the breaker's state.
+type Outcome int
+
+const (
+	// OutcomeSuccess resets the consecutive-failure count and counts as a
+	// successful half-open probe.
+	OutcomeSuccess Outcome = iota
+
+	// OutcomeFailure counts towards tripping and re-opens a half-open
+	// breaker.
+	OutcomeFailure
+
+	// OutcomeIgnore is not recorded. A half-open probe with this outcome
+	// frees its slot without counting as a success.
+	OutcomeIgnore
+)
+
+// DefaultClassify counts nil errors as successes and transport errors and
+// 5xx/429 responses as failures. Other 4xx responses, context cancellation,
+// rejections by the breaker itself, and token source failures are ignored:
+// they say nothing about downstream health.
+func DefaultClassify(err error) Outcome {
+	if err == nil {
+		return OutcomeSuccess
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrCircuitOpen) {
+		return OutcomeIgnore
+	}
+	var tse *TokenSourceError
+	if errors.As(err, &tse) {
+		return OutcomeIgnore
+	}
+	var he *HTTPError
+	if errors.As(err, &he) && he.StatusCode < 500 && he.StatusCode != http.StatusTooManyRequests {
+		return OutcomeIgnore
+	}
+	return OutcomeFailure
+}
+
 // CircuitBreaker implements the circuit breaker pattern
 type CircuitBreaker struct {
-	mu sync.Mutex
+	mu  sync.Mutex
+	cfg CircuitBreakerConfig
+
+	state            CircuitState
+	generation       uint64 // incremented on every state change
+	consecutiveFails int
+	probesInFlight   int
+	probeSuccesses   int
+	openedAt         time.Time
 
-	state           CircuitState
-	failureCount    int
-	successCount    int
-	halfOpenAllowed bool // true when a half-open probe slot is available
-	threshold       int
-	timeout         time.Duration
-	lastFailureTime time.Time
+	window outcomeWindow
 }
 
-// NewCircuitBreaker creates a new circuit breaker
+// NewCircuitBreaker creates a circuit breaker that opens after threshold
+// consecutive failures, stays open for timeout, then needs threshold
+// successful probes (one at a time) to close again.
 func NewCircuitBreaker(threshold int, timeout time.Duration) *CircuitBreaker {
 	if threshold <= 0 {
 		threshold = 1
@@ -37,91 +154,176 @@ func NewCircuitBreaker(threshold int, timeout time.Duration) *CircuitBreaker {
 	if timeout <= 0 {
 		timeout = time.Second
 	}
-	return &CircuitBreaker{
-		state:     StateClosed,
-		threshold: threshold,
-		timeout:   timeout,
+	return NewCircuitBreakerWithConfig(CircuitBreakerConfig{
+		ConsecutiveFailures: threshold,
+		OpenTimeout:         timeout,
+		HalfOpenProbes:      1,
+		HalfOpenSuccesses:   threshold,
+	})
+}
+
+// NewCircuitBreakerWithConfig creates a circuit breaker from cfg.
+func NewCircuitBreakerWithConfig(cfg CircuitBreakerConfig) *CircuitBreaker {
+	if cfg.ConsecutiveFailures <= 0 {
+		cfg.ConsecutiveFailures = 5
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 10
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Second
+	}
+	if cfg.WindowBuckets <= 0 {
+		cfg.WindowBuckets = 10
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = 30 * time.Second
 	}
+	if cfg.HalfOpenProbes <= 0 {
+		cfg.HalfOpenProbes = 1
+	}
+	if cfg.HalfOpenSuccesses <= 0 {
+		cfg.HalfOpenSuccesses = cfg.HalfOpenProbes
+	}
+	if cfg.Classify == nil {
+		cfg.Classify = DefaultClassify
+	}
+
+	cb := &CircuitBreaker{cfg: cfg, state: StateClosed}
+	if cfg.WindowSize > 0 {
+		cb.window = &countWindow{outcomes: make([]bool, cfg.WindowSize)}
+	} else {
+		cb.window = newTimeWindow(cfg.Window, cfg.WindowBuckets)
+	}
+	return cb
 }
 
-// Call executes the function if circuit is closed
+// Call executes fn if the breaker allows it and records the outcome. It
+// returns ErrCircuitOpen without calling fn when the breaker is open.
+// Outcomes of calls admitted before the breaker last changed state are
+// discarded, so a slow call from the closed state cannot count as a
+// half-open probe.
 func (cb *CircuitBreaker) Call(fn func() error) error {
-	if !cb.allowRequest() {
-		return errors.New("circuit breaker is open")
+	gen, ok := cb.allowRequest()
+	if !ok {
+		return ErrCircuitOpen
 	}
 
 	err := fn()
-	if err != nil {
-		cb.onFailure()
-		return err
-	}
-
-	cb.onSuccess()
-	return nil
+	cb.record(gen, cb.cfg.Classify(err))
+	return err
 }
 
-// allowRequest checks if request is allowed
-func (cb *CircuitBreaker) allowRequest() bool {
+// allowRequest checks if request is allowed and returns the generation it
+// was admitted in.
+func (cb *CircuitBreaker) allowRequest() (uint64, bool) {
 	cb.mu.Lock()
-	defer cb.mu.Unlock()
 
+	var from CircuitState
+	transitioned := false
+	if cb.state == StateOpen && time.Since(cb.openedAt) > cb.cfg.OpenTimeout {
+		from, transitioned = cb.setStateLocked(StateHalfOpen), true
+	}
+
+	allowed := false
 	switch cb.state {
 	case StateClosed:
-		return true
-	case StateOpen:
-		if time.Since(cb.lastFailureTime) > cb.timeout {
-			cb.state = StateHalfOpen
-			cb.successCount = 0
-			cb.halfOpenAllowed = true
-		} else {
-			return false
-		}
-		fallthrough
+		allowed = true
 	case StateHalfOpen:
-		// Only allow one probe request at a time in half-open state.
-		if cb.halfOpenAllowed {
-			cb.halfOpenAllowed = false
-			return true
+		if cb.probesInFlight < cb.cfg.HalfOpenProbes {
+			cb.probesInFlight++
+			allowed = true
 		}
-		return false
 	}
+	gen := cb.generation
+	cb.mu.Unlock()
 
-	return false
+	if transitioned {
+		cb.notify(from, StateHalfOpen)
+	}
+	return gen, allowed
 }
 
-// onSuccess handles successful request
-func (cb *CircuitBreaker) onSuccess() {
+// record applies the outcome of a call admitted in generation gen.
+func (cb *CircuitBreaker) record(gen uint64, outcome Outcome) {
 	cb.mu.Lock()
-	defer cb.mu.Unlock()
+	if gen != cb.generation {
+		// Admitted under an earlier state; its probe slot, if any, was
+		// already reset by the transition.
+		cb.mu.Unlock()
+		return
+	}
 
-	if cb.state == StateHalfOpen {
-		cb.successCount++
-		if cb.successCount >= cb.threshold {
-			cb.state = StateClosed
-			cb.failureCount = 0
+	failed := outcome == OutcomeFailure
+	from, to := cb.state, cb.state
+	switch {
+	case outcome == OutcomeIgnore:
+		if cb.state == StateHalfOpen && cb.probesInFlight > 0 {
+			cb.probesInFlight--
+		}
+	case cb.state == StateHalfOpen:
+		if cb.probesInFlight > 0 {
+			cb.probesInFlight--
+		}
+		if failed {
+			// Any failure in half-open immediately re-opens the circuit.
+			cb.setStateLocked(StateOpen)
 		} else {
-			// Allow another probe request.
-			cb.halfOpenAllowed = true
+			cb.probeSuccesses++
+			if cb.probeSuccesses >= cb.cfg.HalfOpenSuccesses {
+				cb.setStateLocked(StateClosed)
+			}
+		}
+	case cb.state == StateClosed:
+		cb.window.add(failed)
+		if failed {
+			cb.consecutiveFails++
+		} else {
+			cb.consecutiveFails = 0
+		}
+		if cb.shouldTripLocked() {
+			cb.setStateLocked(StateOpen)
 		}
 	}
-}
+	to = cb.state
+	cb.mu.Unlock()
 
-// onFailure handles failed request
-func (cb *CircuitBreaker) onFailure() {
-	cb.mu.Lock()
-	defer cb.mu.Unlock()
+	if from != to {
+		cb.notify(from, to)
+	}
+}
 
-	cb.lastFailureTime = time.Now()
+// shouldTripLocked reports whether the closed breaker has seen enough failures.
+func (cb *CircuitBreaker) shouldTripLocked() bool {
+	if cb.cfg.FailureRatio <= 0 {
+		return cb.consecutiveFails >= cb.cfg.ConsecutiveFailures
+	}
+	total, failures := cb.window.counts()
+	return total >= cb.cfg.MinRequests && float64(failures)/float64(total) >= cb.cfg.FailureRatio
+}
 
-	// Any failure in half-open immediately re-opens the circuit.
-	if cb.state == StateHalfOpen {
-		cb.state = StateOpen
-		return
+// setStateLocked switches state and resets per-state counters, returning the
+// previous state. cb.mu must be held.
+func (cb *CircuitBreaker) setStateLocked(to CircuitState) CircuitState {
+	from := cb.state
+	cb.state = to
+	cb.generation++
+	cb.probesInFlight = 0
+	cb.probeSuccesses = 0
+	switch to {
+	case StateOpen:
+		cb.openedAt = time.Now()
+	case StateClosed:
+		cb.consecutiveFails = 0
+		cb.window.reset()
 	}
+	return from
+}
 
-	cb.failureCount++
-	if cb.failureCount >= cb.threshold {
-		cb.state = StateOpen
+// notify invokes the OnStateChange hook.
+func (cb *CircuitBreaker) notify(from, to CircuitState) {
+	if cb.cfg.OnStateChange != nil {
+		cb.cfg.OnStateChange(cb.cfg.Name, from, to)
 	}
 }
 
@@ -135,9 +337,162 @@ func (cb *CircuitBreaker) State() CircuitState {
 // Reset resets the circuit breaker
 func (cb *CircuitBreaker) Reset() {
 	cb.mu.Lock()
-	defer cb.mu.Unlock()
+	from := cb.setStateLocked(StateClosed)
+	cb.mu.Unlock()
+
+	if from != StateClosed {
+		cb.notify(from, StateClosed)
+	}
+}
+
+// outcomeWindow accumulates recent call outcomes for ratio-based tripping.
+type outcomeWindow interface {
+	add(failed bool)
+	counts() (total, failures int)
+	reset()
+}
+
+// countWindow tracks the outcomes of the last len(outcomes) calls.
+type countWindow struct {
+	outcomes []bool
+	next     int
+	filled   int
+	failures int
+}
+
+func (w *countWindow) add(failed bool) {
+	if w.filled == len(w.outcomes) {
+		if w.outcomes[w.next] {
+			w.failures--
+		}
+	} else {
+		w.filled++
+	}
+	w.outcomes[w.next] = failed
+	if failed {
+		w.failures++
+	}
+	w.next = (w.next + 1) % len(w.outcomes)
+}
+
+func (w *countWindow) counts() (int, int) { return w.filled, w.failures }
+
+func (w *countWindow) reset() {
+	clear(w.outcomes)
+	w.next, w.filled, w.failures = 0, 0, 0
+}
+
+// timeWindow tracks outcomes in fixed-width buckets covering a rolling span.
+type timeWindow struct {
+	width   time.Duration
+	buckets []timeBucket
+}
+
+type timeBucket struct {
+	epoch    int64 // bucket index since the Unix epoch
+	total    int
+	failures int
+}
 
-	cb.state = StateClosed
-	cb.failureCount = 0
-	cb.successCount = 0
+func newTimeWindow(span time.Duration, n int) *timeWindow {
+	width := span / time.Duration(n)
+	if width <= 0 {
+		width = time.Millisecond
+	}
+	return &timeWindow{width: width, buckets: make([]timeBucket, n)}
+}
+
+func (w *timeWindow) add(failed bool) {
+	epoch := time.Now().UnixNano() / int64(w.width)
+	b := &w.buckets[epoch%int64(len(w.buckets))]
+	if b.epoch != epoch {
+		*b = timeBucket{epoch: epoch}
+	}
+	b.total++
+	if failed {
+		b.failures++
+	}
+}
+
+func (w *timeWindow) counts() (total, failures int) {
+	now := time.Now().UnixNano() / int64(w.width)
+	for _, b := range w.buckets {
+		if now-b.epoch < int64(len(w.buckets)) {
+			total += b.total
+			failures += b.failures
+		}
+	}
+	return total, failures
+}
+
+func (w *timeWindow) reset() {
+	clear(w.buckets)
+}
+
+// CircuitBreakerKeyFunc maps a request to the key of the breaker guarding
+// it, e.g. its host or a route template.
+type CircuitBreakerKeyFunc func(method, rawURL string) string
+
+// ByHost keys breakers by the request URL's host, so one failing host does
+// not block calls to others.
+func ByHost(_, rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// CircuitBreakerGroup lazily creates one CircuitBreaker per key.
+type CircuitBreakerGroup struct {
+	cfg CircuitBreakerConfig
+	key CircuitBreakerKeyFunc
+
+	mu       sync.Mutex
+	breakers map[string]*CircuitBreaker
+}
+
+// NewCircuitBreakerGroup creates a group whose breakers share cfg and are
+// selected by key. Each breaker's Name is its key. Use a route-template key
+// to isolate endpoints:
+//
+//	httpclient.NewCircuitBreakerGroup(cfg, func(method, rawURL string) string {
+//	    u, _ := url.Parse(rawURL)
+//	    return method + " " + routeTemplate(u.Path) // "/users/42" → "/users/{id}"
+//	})
+func NewCircuitBreakerGroup(cfg CircuitBreakerConfig, key CircuitBreakerKeyFunc) *CircuitBreakerGroup {
+	if key == nil {
+		key = ByHost
+	}
+	return &CircuitBreakerGroup{cfg: cfg, key: key, breakers: make(map[string]*CircuitBreaker)}
+}
+
+// Get returns the breaker for key, creating it if needed.
+func (g *CircuitBreakerGroup) Get(key string) *CircuitBreaker {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	cb, ok := g.breakers[key]
+	if !ok {
+		cfg := g.cfg
+		cfg.Name = key
+		cb = NewCircuitBreakerWithConfig(cfg)
+		g.breakers[key] = cb
+	}
+	return cb
+}
+
+// For returns the breaker guarding the given request.
+func (g *CircuitBreakerGroup) For(method, rawURL string) *CircuitBreaker {
+	return g.Get(g.key(method, rawURL))
+}
+
+// States returns the current state of every breaker in the group.
+func (g *CircuitBreakerGroup) States() map[string]CircuitState {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	out := make(map[string]CircuitState, len(g.breakers))
+	for k, cb := range g.breakers {
+		out[k] = cb.State()
+	}
+	return out
 }
@@ -0,0 +1,286 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errBoom = errors.New("boom")
+
+func TestCircuitBreaker_ErrCircuitOpen(t *testing.T) {
+	cb := NewCircuitBreaker(1, time.Minute)
+	_ = cb.Call(func() error { return errBoom })
+
+	err := cb.Call(func() error { t.Fatal("must not be called"); return nil })
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+}
+
+func TestCircuitBreaker_FailureRatio(t *testing.T) {
+	cb := NewCircuitBreakerWithConfig(CircuitBreakerConfig{
+		FailureRatio: 0.5,
+		MinRequests:  4,
+		Window:       time.Minute,
+	})
+
+	// Three calls, all failing: below the minimum volume.
+	for range 3 {
+		_ = cb.Call(func() error { return errBoom })
+	}
+	if cb.State() != StateClosed {
+		t.Fatal("expected breaker to stay closed below MinRequests")
+	}
+
+	_ = cb.Call(func() error { return nil }) // 3/4 failures
+	if cb.State() != StateOpen {
+		t.Fatalf("expected open at 75%% failures, got %v", cb.State())
+	}
+}
+
+func TestCircuitBreaker_CountWindow(t *testing.T) {
+	cb := NewCircuitBreakerWithConfig(CircuitBreakerConfig{
+		FailureRatio: 0.5,
+		MinRequests:  4,
+		WindowSize:   4,
+	})
+
+	// Old failures age out of the count window as successes arrive.
+	for _, fail := range []bool{true, false, false, false, false, true} {
+		_ = cb.Call(func() error {
+			if fail {
+				return errBoom
+			}
+			return nil
+		})
+	}
+	if cb.State() != StateClosed {
+		t.Fatalf("expected closed with 1/4 failures in window, got %v", cb.State())
+	}
+
+	_ = cb.Call(func() error { return errBoom }) // window: S,S,F,F
+	if cb.State() != StateOpen {
+		t.Fatalf("expected open with 2/4 failures in window, got %v", cb.State())
+	}
+}
+
+func TestCircuitBreaker_TimeWindowAgesOut(t *testing.T) {
+	cb := NewCircuitBreakerWithConfig(CircuitBreakerConfig{
+		FailureRatio:  0.5,
+		MinRequests:   2,
+		Window:        40 * time.Millisecond,
+		WindowBuckets: 4,
+	})
+	_ = cb.Call(func() error { return errBoom })
+	time.Sleep(60 * time.Millisecond)
+	_ = cb.Call(func() error { return errBoom })
+	if cb.State() != StateClosed {
+		t.Fatal("expected the first failure to have aged out of the window")
+	}
+}
+
+func TestCircuitBreaker_HalfOpenProbes(t *testing.T) {
+	cb := NewCircuitBreakerWithConfig(CircuitBreakerConfig{
+		ConsecutiveFailures: 1,
+		OpenTimeout:         time.Millisecond,
+		HalfOpenProbes:      2,
+		HalfOpenSuccesses:   2,
+	})
+	_ = cb.Call(func() error { return errBoom })
+	time.Sleep(5 * time.Millisecond)
+
+	// Two concurrent probes are admitted; a third is rejected.
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	var admitted atomic.Int32
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = cb.Call(func() error { admitted.Add(1); <-release; return nil })
+		}()
+	}
+	for admitted.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := cb.Call(func() error { return nil }); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected third probe to be rejected, got %v", err)
+	}
+	close(release)
+	wg.Wait()
+
+	if cb.State() != StateClosed {
+		t.Fatalf("expected closed after 2 successful probes, got %v", cb.State())
+	}
+}
+
+func TestCircuitBreaker_CancelledProbeIgnored(t *testing.T) {
+	cb := NewCircuitBreaker(1, time.Millisecond)
+	_ = cb.Call(func() error { return errBoom })
+	time.Sleep(5 * time.Millisecond)
+
+	// A cancelled probe neither closes the breaker nor keeps its slot.
+	_ = cb.Call(func() error { return context.Canceled })
+	if cb.State() != StateHalfOpen {
+		t.Fatalf("expected half-open after cancelled probe, got %v", cb.State())
+	}
+	_ = cb.Call(func() error { return &HTTPError{StatusCode: 404} })
+	if cb.State() != StateHalfOpen {
+		t.Fatalf("expected half-open after 4xx probe, got %v", cb.State())
+	}
+	if err := cb.Call(func() error { return nil }); err != nil {
+		t.Fatalf("expected the probe slot to be free, got %v", err)
+	}
+	if cb.State() != StateClosed {
+		t.Fatalf("expected closed after successful probe, got %v", cb.State())
+	}
+}
+
+func TestCircuitBreaker_IgnoredOutcomeKeepsFailureCount(t *testing.T) {
+	cb := NewCircuitBreaker(2, time.Minute)
+	_ = cb.Call(func() error { return errBoom })
+	_ = cb.Call(func() error { return context.Canceled })
+	_ = cb.Call(func() error { return &HTTPError{StatusCode: 400} })
+	_ = cb.Call(func() error { return errBoom })
+	if cb.State() != StateOpen {
+		t.Fatalf("expected ignored outcomes not to reset the failure count, got %v", cb.State())
+	}
+}
+
+func TestCircuitBreaker_StaleCallDiscarded(t *testing.T) {
+	cb := NewCircuitBreaker(1, time.Millisecond)
+
+	// A slow call admitted while closed...
+	releaseSlow := make(chan struct{})
+	slowDone := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		defer close(slowDone)
+		_ = cb.Call(func() error { close(started); <-releaseSlow; return nil })
+	}()
+	<-started
+
+	// ...is still in flight when the breaker trips and goes half-open.
+	_ = cb.Call(func() error { return errBoom })
+	time.Sleep(5 * time.Millisecond)
+	releaseProbe := make(chan struct{})
+	probeDone := make(chan struct{})
+	probing := make(chan struct{})
+	go func() {
+		defer close(probeDone)
+		_ = cb.Call(func() error { close(probing); <-releaseProbe; return nil })
+	}()
+	<-probing
+
+	// Its success must not count as a probe or free the probe's slot.
+	close(releaseSlow)
+	<-slowDone
+	if cb.State() != StateHalfOpen {
+		t.Fatalf("stale call changed state to %v", cb.State())
+	}
+	if err := cb.Call(func() error { return nil }); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected probe slot to stay taken, got %v", err)
+	}
+
+	close(releaseProbe)
+	<-probeDone
+	if cb.State() != StateClosed {
+		t.Fatalf("expected closed after the real probe, got %v", cb.State())
+	}
+}
+
+func TestCircuitBreaker_ClassificationAndHooks(t *testing.T) {
+	var mu sync.Mutex
+	var transitions []string
+	cb := NewCircuitBreakerWithConfig(CircuitBreakerConfig{
+		Name:                "payments",
+		ConsecutiveFailures: 2,
+		OpenTimeout:         time.Millisecond,
+		OnStateChange: func(name string, from, to CircuitState) {
+			mu.Lock()
+			transitions = append(transitions, fmt.Sprintf("%s:%s->%s", name, from, to))
+			mu.Unlock()
+		},
+	})
+
+	// 4xx responses and cancellations don't count as failures by default.
+	for range 3 {
+		_ = cb.Call(func() error { return &HTTPError{StatusCode: 404} })
+		_ = cb.Call(func() error { return context.Canceled })
+	}
+	if cb.State() != StateClosed {
+		t.Fatal("expected 4xx and cancellations to be ignored")
+	}
+
+	for range 2 {
+		_ = cb.Call(func() error { return &HTTPError{StatusCode: 503} })
+	}
+	time.Sleep(5 * time.Millisecond)
+	_ = cb.Call(func() error { return nil })
+
+	want := "[payments:closed->open payments:open->half-open payments:half-open->closed]"
+	if got := fmt.Sprint(transitions); got != want {
+		t.Fatalf("got transitions %s, want %s", got, want)
+	}
+}
+
+func TestCircuitBreakerGroup_PerHost(t *testing.T) {
+	t.Parallel()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+	}))
+	t.Cleanup(down.Close)
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	t.Cleanup(up.Close)
+
+	group := NewCircuitBreakerGroup(CircuitBreakerConfig{ConsecutiveFailures: 2, OpenTimeout: time.Minute}, ByHost)
+	c := New("", WithLogger(quietLogger()), WithMaxRetries(0), WithCircuitBreakerGroup(group))
+
+	for range 2 {
+		_, _ = c.Get(context.Background(), down.URL+"/x")
+	}
+	if _, err := c.Get(context.Background(), down.URL+"/x"); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen for the failing host, got %v", err)
+	}
+	if _, err := c.Get(context.Background(), up.URL+"/x"); err != nil {
+		t.Fatalf("expected healthy host to be unaffected, got %v", err)
+	}
+
+	states := group.States()
+	if len(states) != 2 || states[ByHost("", down.URL)] != StateOpen || states[ByHost("", up.URL)] != StateClosed {
+		t.Fatalf("unexpected states %v", states)
+	}
+}
+
+func TestCircuitOpenNotRetried(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(500)
+	}, WithMaxRetries(5), WithRetryDelay(time.Millisecond), WithCircuitBreaker(2, time.Minute))
+	t.Cleanup(ts.Close)
+
+	resp, err := c.Get(context.Background(), "/x")
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen once the breaker trips, got %v", err)
+	}
+	if resp != nil {
+		t.Fatalf("expected no response from a rejected call, got %d", resp.StatusCode)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("expected retries to stop once the breaker opened, got %d calls", calls.Load())
+	}
+	if c.CircuitBreaker().State() != StateOpen {
+		t.Fatal("expected open breaker")
+	}
+}
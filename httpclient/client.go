@@ -16,6 +16,8 @@ import (
 	"net/http"
 	"sync"
 	"time"
+
+	"github.com/KARTIKrocks/apikit/middleware"
 )
 
 // HTTPClient is the interface implemented by Client and MockClient.
@@ -34,19 +36,34 @@ var _ HTTPClient = (*Client)(nil)
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
-	headers    map[string]string
-	mu         sync.RWMutex
+	// streamClient shares httpClient's transport but has no overall timeout,
+	// since streaming responses are open-ended; they end on context cancellation.
+	streamClient *http.Client
+	headers      map[string]string
+	mu           sync.RWMutex
 
 	// config fields
 	timeout         time.Duration
 	maxRetries      int
 	retryDelay      time.Duration
 	maxRetryDelay   time.Duration
+	maxRetryAfter   time.Duration
+	retryPolicy     RetryPolicy
+	backoffStrategy Backoff
+	retryBudget     *RetryBudget
 	maxResponseBody int64
 	logger          *slog.Logger
 	cb              *CircuitBreaker
+	cbGroup         *CircuitBreakerGroup
+	limiter         middleware.ConcurrencyLimiter
+	hedger          *hedger
+	cache           *responseCache
 	transport       http.RoundTripper
 	errorOnStatus   bool
+
+	interceptors     []Interceptor
+	callInterceptors []CallInterceptor
+	callHandler      CallHandler
 }
 
 // DefaultMaxResponseBody is the default maximum response body size (10 MB).
@@ -61,6 +78,8 @@ func New(baseURL string, opts ...Option) *Client {
 		maxRetries:      3,
 		retryDelay:      time.Second,
 		maxRetryDelay:   10 * time.Second,
+		maxRetryAfter:   time.Minute,
+		retryPolicy:     DefaultRetryPolicy(),
 		maxResponseBody: DefaultMaxResponseBody,
 		logger:          slog.Default(),
 		errorOnStatus:   true,
@@ -78,11 +97,20 @@ func New(baseURL string, opts ...Option) *Client {
 			IdleConnTimeout:     90 * time.Second,
 		}
 	}
+	if len(c.interceptors) > 0 {
+		transport = ChainInterceptors(c.interceptors...)(transport)
+	}
 
 	c.httpClient = &http.Client{
 		Timeout:   c.timeout,
 		Transport: transport,
 	}
+	c.streamClient = &http.Client{Transport: transport}
+	handler := CallHandler(c.invoke)
+	if c.cache != nil {
+		handler = c.cached(handler)
+	}
+	c.callHandler = ChainCallInterceptors(c.callInterceptors...)(handler)
 
 	return c
 }
@@ -156,13 +184,22 @@ func (c *Client) doRequest(ctx context.Context, method, path string, body any, h
 	return c.do(ctx, method, path, body, headers, c.errorOnStatus)
 }
 
-// do runs the request with the given error-on-status policy, allowing
-// per-request overrides of the client default.
+// do runs the request through the call interceptors with the given
+// error-on-status policy, allowing per-request overrides of the client default.
 func (c *Client) do(ctx context.Context, method, path string, body any, headers map[string]string, errorOnStatus bool) (*Response, error) {
-	resp, err := c.doRequestWithRetries(ctx, method, path, body, headers)
+	call := &Call{Method: method, Path: path, Body: body, Headers: make(map[string]string, len(headers))}
+	for k, v := range headers {
+		call.Headers[k] = v
+	}
+	resp, err := c.callHandler(ctx, call)
 	return finalize(resp, err, errorOnStatus)
 }
 
+// invoke is the innermost CallHandler: the retry loop itself.
+func (c *Client) invoke(ctx context.Context, call *Call) (*Response, error) {
+	return c.doRequestWithRetries(ctx, call.Method, call.Path, call.Body, call.Headers)
+}
+
 // finalize applies the error-on-status policy to a completed request.
 func finalize(resp *Response, err error, errorOnStatus bool) (*Response, error) {
 	if err == nil || errorOnStatus {
@@ -181,27 +218,55 @@ func finalize(resp *Response, err error, errorOnStatus bool) (*Response, error)
 // circuit breaker, always treating non-2xx as an error internally so retry
 // decisions stay consistent.
 func (c *Client) doRequestWithRetries(ctx context.Context, method, path string, body any, headers map[string]string) (*Response, error) {
-	var lastErr error
-	var lastResp *Response
-
-	for attempt := 0; attempt <= c.maxRetries; attempt++ {
-		if attempt > 0 {
-			delay := c.calculateRetryDelay(attempt)
-			c.logger.Info("retrying request", "attempt", attempt, "delay", delay)
-
-			select {
-			case <-time.After(delay):
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			}
+	replayable := bodyReplayable(body)
+	return c.withRetries(ctx, method, path, replayable, func() (*Response, error) {
+		if c.hedger != nil && c.hedger.methods[method] && replayable {
+			return c.hedger.do(ctx, func(ctx context.Context) (*Response, error) {
+				return c.executeRequest(ctx, method, path, body, headers)
+			}, func(n int) {
+				c.logger.Info("hedging request", "method", method, "path", path, "hedge", n)
+			})
+		}
+		return c.executeRequest(ctx, method, path, body, headers)
+	})
+}
+
+// withRetries runs attempt under the client's retry policy, backoff, retry
+// budget, and circuit breaker. It is shared by buffered requests and the
+// initial connection of streaming requests. The returned Response, if any,
+// carries the number of attempts made and the total time spent waiting.
+func (c *Client) withRetries(ctx context.Context, method, path string, replayable bool, attempt func() (*Response, error)) (*Response, error) {
+	if c.retryBudget != nil {
+		c.retryBudget.recordRequest()
+	}
+
+	cb := c.breakerFor(method, path)
+
+	var waited, delay time.Duration
+	annotate := func(resp *Response, n int) *Response {
+		if resp != nil {
+			resp.Attempts = n
+			resp.RetryWait = waited
 		}
+		return resp
+	}
 
+	for n := 1; ; n++ {
 		var resp *Response
 		var err error
 
-		if c.cb != nil {
-			cbErr := c.cb.Call(func() error {
-				resp, err = c.executeRequest(ctx, method, path, body, headers)
+		var release func(dropped bool)
+		if c.limiter != nil {
+			if release, err = c.limiter.Acquire(ctx); err != nil {
+				// Shed locally: retrying would only add load, and the
+				// downstream never saw the call, so the breaker is skipped.
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
+		}
+
+		if cb != nil {
+			cbErr := cb.Call(func() error {
+				resp, err = attempt()
 				if err != nil {
 					return err
 				}
@@ -212,64 +277,96 @@ func (c *Client) doRequestWithRetries(ctx context.Context, method, path string,
 				err = cbErr
 			}
 		} else {
-			resp, err = c.executeRequest(ctx, method, path, body, headers)
+			resp, err = attempt()
 		}
 
-		if err == nil {
-			return resp, nil
+		if release != nil {
+			release(isOverloaded(resp, err))
 		}
 
-		lastErr = err
-		lastResp = resp
+		if err == nil {
+			return annotate(resp, n), nil
+		}
 
 		if ctx.Err() != nil {
 			return nil, ctx.Err()
 		}
 
-		// Don't retry client errors (4xx).
-		if resp != nil && resp.StatusCode >= 400 && resp.StatusCode < 500 {
-			return resp, err
+		if !replayable {
+			// The body has been consumed; it cannot be sent again.
+			return annotate(resp, n), err
 		}
-	}
 
-	// Return the last response (if any) alongside the error so callers can
-	// still inspect the status and read the error body without errors.As.
-	return lastResp, fmt.Errorf("request failed after %d attempts: %w", c.maxRetries+1, lastErr)
-}
+		if n > c.maxRetries {
+			// Return the last response (if any) alongside the error so callers can
+			// still inspect the status and read the error body without errors.As.
+			return annotate(resp, n), fmt.Errorf("request failed after %d attempts: %w", n, err)
+		}
 
-// executeRequest executes a single HTTP request.
-func (c *Client) executeRequest(ctx context.Context, method, path string, body any, headers map[string]string) (*Response, error) {
-	url := c.baseURL + path
+		if !c.retryPolicy.ShouldRetry(ctx, RetryAttempt{Attempt: n, Method: method, Response: resp, Err: err}) {
+			return annotate(resp, n), err
+		}
 
-	var bodyReader io.Reader
-	if body != nil {
-		jsonBody, err := json.Marshal(body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal body: %w", err)
+		delay = c.backoff().Delay(n, delay)
+		if ra, ok := parseRetryAfter(resp); ok {
+			if c.maxRetryAfter > 0 && ra > c.maxRetryAfter {
+				c.logger.Warn("not retrying: Retry-After exceeds limit", "retry_after", ra, "limit", c.maxRetryAfter)
+				return annotate(resp, n), err
+			}
+			delay = ra
+		}
+
+		if c.retryBudget != nil && !c.retryBudget.tryRetry() {
+			c.logger.Warn("not retrying: retry budget exhausted", "attempt", n)
+			return annotate(resp, n), fmt.Errorf("%w: %w", ErrRetryBudgetExhausted, err)
+		}
+
+		c.logger.Info("retrying request", "attempt", n, "delay", delay)
+
+		select {
+		case <-time.After(delay):
+			waited += delay
+		case <-ctx.Done():
+			return nil, ctx.Err()
 		}
-		bodyReader = bytes.NewReader(jsonBody)
 	}
+}
 
-	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+// isOverloaded reports whether an attempt's outcome signals an overloaded
+// downstream to the concurrency limiter.
+func isOverloaded(resp *Response, err error) bool {
+	if resp != nil {
+		return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
 	}
+	var tse *TokenSourceError
+	return err != nil && !stderrors.Is(err, context.Canceled) && !stderrors.Is(err, ErrCircuitOpen) &&
+		!stderrors.As(err, &tse)
+}
 
-	// Copy default headers under read lock.
-	c.mu.RLock()
-	for k, v := range c.headers {
-		req.Header.Set(k, v)
+// breakerFor returns the circuit breaker guarding the request, if any.
+func (c *Client) breakerFor(method, path string) *CircuitBreaker {
+	if c.cbGroup != nil {
+		return c.cbGroup.For(method, c.baseURL+path)
 	}
-	c.mu.RUnlock()
+	return c.cb
+}
 
-	// Set per-request headers.
-	for k, v := range headers {
-		req.Header.Set(k, v)
+// backoff returns the configured Backoff, defaulting to exponential backoff
+// from WithRetryDelay and WithMaxRetryDelay.
+func (c *Client) backoff() Backoff {
+	if c.backoffStrategy != nil {
+		return c.backoffStrategy
 	}
+	return ExponentialBackoff(c.retryDelay, c.maxRetryDelay)
+}
 
-	if body != nil && req.Header.Get("Content-Type") == "" {
-		req.Header.Set("Content-Type", "application/json")
+// executeRequest executes a single HTTP request.
+func (c *Client) executeRequest(ctx context.Context, method, path string, body any, headers map[string]string) (*Response, error) {
+	req, err := c.newRequest(ctx, method, path, body, headers)
+	if err != nil {
+		return nil, err
 	}
+	url := req.URL.String()
 
 	c.logger.Info("sending request", "method", method, "url", url)
 
@@ -283,16 +380,9 @@ func (c *Client) executeRequest(ctx context.Context, method, path string, body a
 	}
 
 	defer func() { _ = resp.Body.Close() }()
-	maxBody := c.maxResponseBody
-	if maxBody <= 0 {
-		maxBody = DefaultMaxResponseBody
-	}
-	responseBody, err := io.ReadAll(io.LimitReader(resp.Body, maxBody+1))
+	responseBody, err := c.readBody(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-	if int64(len(responseBody)) > maxBody {
-		return nil, fmt.Errorf("response body exceeds maximum size of %d bytes", maxBody)
+		return nil, err
 	}
 
 	response := &Response{
@@ -321,16 +411,81 @@ func (c *Client) executeRequest(ctx context.Context, method, path string, body a
 	return response, nil
 }
 
-// calculateRetryDelay calculates delay with exponential backoff.
-func (c *Client) calculateRetryDelay(attempt int) time.Duration {
-	shift := attempt - 1
-	// Prevent overflow: 1<<63 flips the sign bit on 64-bit integers.
-	if shift >= 62 {
-		return c.maxRetryDelay
+// newRequest builds an *http.Request with the client's default headers, the
+// per-request headers, and a JSON-encoded body.
+func (c *Client) newRequest(ctx context.Context, method, path string, body any, headers map[string]string) (*http.Request, error) {
+	url := c.baseURL + path
+
+	var bodyReader io.Reader
+	contentType := "application/json"
+	rawBody, isRaw := body.(*requestBody)
+	switch {
+	case isRaw:
+		rc, err := rawBody.open()
+		if err != nil {
+			return nil, err
+		}
+		bodyReader, contentType = rc, rawBody.contentType
+	case body != nil:
+		jsonBody, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal body: %w", err)
+		}
+		bodyReader = bytes.NewReader(jsonBody)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bodyReader)
+	if err != nil {
+		if rc, ok := bodyReader.(io.Closer); ok {
+			_ = rc.Close()
+		}
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if isRaw {
+		switch {
+		case rawBody.length == 0:
+			req.Body, req.ContentLength = http.NoBody, 0
+		case rawBody.length > 0:
+			req.ContentLength = rawBody.length
+		}
+		if !rawBody.oneShot {
+			// Lets the transport rewind the body itself, e.g. on redirects.
+			req.GetBody = rawBody.getBody
+		}
+	}
+
+	// Copy default headers under read lock.
+	c.mu.RLock()
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+	c.mu.RUnlock()
+
+	// Set per-request headers.
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	if body != nil && contentType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	return req, nil
+}
+
+// readBody reads r up to the configured maximum response body size.
+func (c *Client) readBody(r io.Reader) ([]byte, error) {
+	maxBody := c.maxResponseBody
+	if maxBody <= 0 {
+		maxBody = DefaultMaxResponseBody
 	}
-	delay := c.retryDelay * time.Duration(1<<uint(shift))
-	if c.maxRetryDelay > 0 && delay > c.maxRetryDelay {
-		delay = c.maxRetryDelay
+	b, err := io.ReadAll(io.LimitReader(r, maxBody+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if int64(len(b)) > maxBody {
+		return nil, fmt.Errorf("response body exceeds maximum size of %d bytes", maxBody)
 	}
-	return delay
+	return b, nil
 }
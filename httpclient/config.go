@@ -4,6 +4,8 @@ import (
 	"log/slog"
 	"net/http"
 	"time"
+
+	"github.com/KARTIKrocks/apikit/middleware"
 )
 
 // Option configures the Client.
@@ -34,6 +36,58 @@ func WithMaxRetryDelay(d time.Duration) Option {
 	return func(c *Client) { c.maxRetryDelay = d }
 }
 
+// WithRetryPolicy sets the policy deciding which failed attempts are retried.
+// Default: DefaultRetryPolicy (transport errors, 5xx, and 429). Compose with
+// RetryOnStatus, RetryOnNetworkErrors, AnyOf, and IdempotentOnly.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) {
+		if p != nil {
+			c.retryPolicy = p
+		} else {
+			c.retryPolicy = DefaultRetryPolicy()
+		}
+	}
+}
+
+// WithBackoff sets the backoff strategy between retries, replacing the
+// default exponential backoff from WithRetryDelay and WithMaxRetryDelay.
+func WithBackoff(b Backoff) Option {
+	return func(c *Client) { c.backoffStrategy = b }
+}
+
+// WithMaxRetryAfter caps how long the client honours a Retry-After header on a
+// 429 or 503 response. A longer Retry-After ends retrying and returns the
+// response. Default: 1 minute. Zero honours any Retry-After.
+func WithMaxRetryAfter(d time.Duration) Option {
+	return func(c *Client) { c.maxRetryAfter = d }
+}
+
+// WithRetryBudget shares a RetryBudget across all requests of the client to
+// prevent retry storms against a struggling downstream.
+func WithRetryBudget(b *RetryBudget) Option {
+	return func(c *Client) { c.retryBudget = b }
+}
+
+// WithConcurrencyLimiter bounds the client's in-flight requests with a
+// middleware.Bulkhead or middleware.AdaptiveLimiter. Each attempt holds a slot
+// until its response is received (for streams, until the headers arrive).
+// Shed calls fail immediately with an error wrapping
+// middleware.ErrLimitExceeded; they are not retried and don't count against
+// the circuit breaker. 5xx, 429, and transport failures are reported to the
+// limiter as drops.
+func WithConcurrencyLimiter(l middleware.ConcurrencyLimiter) Option {
+	return func(c *Client) { c.limiter = l }
+}
+
+// WithHedging enables hedged requests for latency-sensitive reads: when an
+// attempt has no response after the hedge delay, an identical request is sent
+// and the first success wins, cancelling the others. Hedges run within a
+// single retry attempt, so they count once against the circuit breaker and
+// concurrency limiter. Streaming requests are never hedged.
+func WithHedging(cfg HedgeConfig) Option {
+	return func(c *Client) { c.hedger = newHedger(cfg) }
+}
+
 // WithLogger sets the structured logger.
 func WithLogger(logger *slog.Logger) Option {
 	return func(c *Client) {
@@ -49,9 +103,35 @@ func WithLogger(logger *slog.Logger) Option {
 func WithCircuitBreaker(threshold int, timeout time.Duration) Option {
 	return func(c *Client) {
 		c.cb = NewCircuitBreaker(threshold, timeout)
+		c.cbGroup = nil
+	}
+}
+
+// WithCircuitBreakerConfig enables a single client-wide circuit breaker
+// configured by cfg, e.g. with a rolling-window failure ratio.
+func WithCircuitBreakerConfig(cfg CircuitBreakerConfig) Option {
+	return func(c *Client) {
+		c.cb = NewCircuitBreakerWithConfig(cfg)
+		c.cbGroup = nil
 	}
 }
 
+// WithCircuitBreakerGroup guards requests with one breaker per key of g
+// (per host, per route template, ...) instead of a single client-wide
+// breaker. Keep a reference to g to inspect breaker states.
+func WithCircuitBreakerGroup(g *CircuitBreakerGroup) Option {
+	return func(c *Client) {
+		c.cbGroup = g
+		c.cb = nil
+	}
+}
+
+// CircuitBreaker returns the client-wide circuit breaker, or nil if none is
+// configured or breakers are grouped with WithCircuitBreakerGroup.
+func (c *Client) CircuitBreaker() *CircuitBreaker {
+	return c.cb
+}
+
 // WithMaxResponseBody sets the maximum response body size in bytes.
 // Responses larger than this are rejected. Default: 10 MB.
 func WithMaxResponseBody(n int64) Option {
@@ -63,6 +143,19 @@ func WithTransport(t http.RoundTripper) Option {
 	return func(c *Client) { c.transport = t }
 }
 
+// WithInterceptors appends interceptors that wrap the transport and run once
+// per attempt, inside the retry loop and circuit breaker. The first is
+// outermost. They also apply to streaming requests.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(c *Client) { c.interceptors = append(c.interceptors, interceptors...) }
+}
+
+// WithCallInterceptors appends interceptors that run once per logical call,
+// outside the retry loop. The first is outermost.
+func WithCallInterceptors(interceptors ...CallInterceptor) Option {
+	return func(c *Client) { c.callInterceptors = append(c.callInterceptors, interceptors...) }
+}
+
 // WithErrorOnStatus controls whether a non-2xx HTTP status is returned as an
 // error. It is enabled by default: requests to a 4xx/5xx endpoint return an
 // *HTTPError alongside the response.
@@ -70,7 +163,7 @@ func WithTransport(t http.RoundTripper) Option {
 // Disable it for services that return structured error bodies you want to
 // decode directly. Non-2xx responses then return (resp, nil) and you branch on
 // resp.IsClientError()/IsServerError() and read the body via resp.JSON(). Retry
-// behavior is unchanged — 5xx responses are still retried internally — and
+// behavior is unchanged — the retry policy still applies internally — and
 // transport, context, and circuit-breaker failures are still returned as errors.
 func WithErrorOnStatus(enabled bool) Option {
 	return func(c *Client) { c.errorOnStatus = enabled }
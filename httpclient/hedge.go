@@ -0,0 +1,162 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// HedgeConfig configures request hedging. See WithHedging.
+type HedgeConfig struct {
+	// Delay is how long to wait for a response before sending a hedge. It is
+	// also the fallback while too few latencies have been observed for
+	// Percentile. Default: 100ms.
+	Delay time.Duration
+
+	// Percentile, when set (e.g. 0.95), derives the delay from the observed
+	// latency of recent successful requests, so hedges fire only for the
+	// slowest tail. Zero always uses Delay.
+	Percentile float64
+
+	// MaxHedges is the number of extra requests that may be sent per attempt,
+	// one per elapsed delay. Default: 1.
+	MaxHedges int
+
+	// Methods lists the HTTP methods that are hedged. Default: GET, HEAD,
+	// and OPTIONS; hedging sends the request more than once, so only list
+	// methods that are safe to duplicate.
+	Methods []string
+}
+
+// latencySamples is the number of recent latencies kept for Percentile, and
+// minLatencySamples the number required before it is used.
+const (
+	latencySamples    = 256
+	minLatencySamples = 20
+)
+
+// hedger sends duplicate requests for slow responses and keeps the first
+// success.
+type hedger struct {
+	cfg     HedgeConfig
+	methods map[string]bool
+
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+func newHedger(cfg HedgeConfig) *hedger {
+	if cfg.Delay <= 0 {
+		cfg.Delay = 100 * time.Millisecond
+	}
+	if cfg.MaxHedges <= 0 {
+		cfg.MaxHedges = 1
+	}
+	if cfg.Percentile < 0 || cfg.Percentile >= 1 {
+		cfg.Percentile = 0
+	}
+	if len(cfg.Methods) == 0 {
+		cfg.Methods = []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+	}
+	h := &hedger{cfg: cfg, methods: make(map[string]bool, len(cfg.Methods))}
+	for _, m := range cfg.Methods {
+		h.methods[m] = true
+	}
+	return h
+}
+
+// hedgeResult is the outcome of one of the concurrent requests.
+type hedgeResult struct {
+	resp *Response
+	err  error
+}
+
+// do runs send, sending up to MaxHedges duplicates after each delay, and
+// returns the first success or definitive response. Once a result is chosen
+// the other requests are cancelled. If every request fails, the first failure
+// is returned.
+func (h *hedger) do(ctx context.Context, send func(ctx context.Context) (*Response, error), onHedge func(n int)) (*Response, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan hedgeResult, h.cfg.MaxHedges+1)
+	launch := func() {
+		go func() {
+			resp, err := send(ctx)
+			if err == nil {
+				h.observe(resp.Duration)
+			}
+			results <- hedgeResult{resp, err}
+		}()
+	}
+
+	launch()
+	sent, pending := 1, 1
+	delay := h.delay()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var first *hedgeResult
+	for {
+		select {
+		case r := <-results:
+			pending--
+			// A success, or a response that isn't a sign of overload (such
+			// as a 404), is a definitive answer.
+			if r.err == nil || (r.resp != nil && !isOverloaded(r.resp, r.err)) {
+				return r.resp, r.err
+			}
+			if first == nil {
+				first = &r
+			}
+			if pending == 0 {
+				return first.resp, first.err
+			}
+		case <-timer.C:
+			if sent <= h.cfg.MaxHedges {
+				onHedge(sent)
+				launch()
+				sent++
+				pending++
+				timer.Reset(delay)
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// delay returns the current hedge delay.
+func (h *hedger) delay() time.Duration {
+	if h.cfg.Percentile == 0 {
+		return h.cfg.Delay
+	}
+	h.mu.Lock()
+	if len(h.samples) < minLatencySamples {
+		h.mu.Unlock()
+		return h.cfg.Delay
+	}
+	sorted := slices.Clone(h.samples)
+	h.mu.Unlock()
+
+	slices.Sort(sorted)
+	return sorted[int(h.cfg.Percentile*float64(len(sorted)-1))]
+}
+
+// observe records the latency of a successful request.
+func (h *hedger) observe(d time.Duration) {
+	if h.cfg.Percentile == 0 {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.samples) < latencySamples {
+		h.samples = append(h.samples, d)
+		return
+	}
+	h.samples[h.next] = d
+	h.next = (h.next + 1) % latencySamples
+}
@@ -0,0 +1,124 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KARTIKrocks/apikit/middleware"
+)
+
+func TestHedging_FirstSuccessWinsAndLoserIsCancelled(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	loserCancelled := make(chan struct{})
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			// The primary request stalls until the hedge wins.
+			<-r.Context().Done()
+			close(loserCancelled)
+			return
+		}
+		w.WriteHeader(200)
+	}, WithHedging(HedgeConfig{Delay: 20 * time.Millisecond}))
+	t.Cleanup(ts.Close)
+
+	start := time.Now()
+	resp, err := c.Get(context.Background(), "/x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 || calls.Load() != 2 {
+		t.Fatalf("expected hedge to win, got status %d after %d calls", resp.StatusCode, calls.Load())
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("expected the hedge to cut latency")
+	}
+	select {
+	case <-loserCancelled:
+	case <-time.After(time.Second):
+		t.Fatal("expected the losing request to be cancelled")
+	}
+}
+
+func TestHedging_OnlyConfiguredMethods(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(30 * time.Millisecond)
+		w.WriteHeader(200)
+	}, WithHedging(HedgeConfig{Delay: time.Millisecond}))
+	t.Cleanup(ts.Close)
+
+	if _, err := c.Post(context.Background(), "/x", map[string]string{"a": "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected POST not to be hedged, got %d calls", calls.Load())
+	}
+}
+
+func TestHedging_DefinitiveResponseNotHedged(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}, WithHedging(HedgeConfig{Delay: time.Second}))
+	t.Cleanup(ts.Close)
+
+	resp, err := c.Get(context.Background(), "/missing")
+	if err == nil || resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 error, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected a single request, got %d", calls.Load())
+	}
+}
+
+func TestHedger_PercentileDelay(t *testing.T) {
+	h := newHedger(HedgeConfig{Delay: time.Second, Percentile: 0.9})
+	for i := range minLatencySamples - 1 {
+		h.observe(time.Duration(i+1) * time.Millisecond)
+	}
+	if h.delay() != time.Second {
+		t.Fatalf("expected fallback delay with too few samples, got %v", h.delay())
+	}
+
+	h.observe(20 * time.Millisecond)
+	if got := h.delay(); got != 18*time.Millisecond {
+		t.Fatalf("expected p90 of 1..20ms, got %v", got)
+	}
+}
+
+func TestPropagateDeadline(t *testing.T) {
+	t.Parallel()
+	got := make(chan string, 1)
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		got <- r.Header.Get(middleware.RequestTimeoutHeader)
+	}, WithInterceptors(PropagateDeadline()))
+	t.Cleanup(ts.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := c.Get(ctx, "/x"); err != nil {
+		t.Fatal(err)
+	}
+	ms, err := strconv.Atoi(<-got)
+	if err != nil || ms <= 1000 || ms > 2000 {
+		t.Fatalf("expected remaining deadline in ms, got %d (%v)", ms, err)
+	}
+
+	// Without a context deadline, only the client timeout applies.
+	c2 := New(ts.URL, WithLogger(quietLogger()), WithTimeout(0), WithInterceptors(PropagateDeadline()))
+	if _, err := c2.Get(context.Background(), "/x"); err != nil {
+		t.Fatal(err)
+	}
+	if v := <-got; v != "" {
+		t.Fatalf("expected no header without a deadline, got %q", v)
+	}
+}
@@ -19,11 +19,16 @@ import (
 func newTestServer(handler http.HandlerFunc, opts ...Option) (*httptest.Server, *Client) {
 	ts := httptest.NewServer(handler)
 	// Suppress log noise in tests.
-	opts = append([]Option{WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))}, opts...)
+	opts = append([]Option{WithLogger(quietLogger())}, opts...)
 	c := New(ts.URL, opts...)
 	return ts, c
 }
 
+// quietLogger returns a logger that discards output.
+func quietLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
 // --- Basic CRUD ---
 
 func TestGet(t *testing.T) {
@@ -0,0 +1,537 @@
+// Package httpmock provides a scriptable HTTP backend for testing code that
+// uses httpclient. Unlike httpclient.MockClient, requests go through a real
+// httpclient.Client, so retries, headers, interceptors, and circuit breakers
+// are exercised.
+//
+// A Mock is both an http.RoundTripper and an http.Handler:
+//
+//	m := httpmock.New()
+//	m.On("GET", "/users/42").
+//	    Header("Authorization", "Bearer token").
+//	    Reply(httpmock.Status(503), httpmock.Status(503), httpmock.JSON(200, user))
+//
+//	client := httpclient.New("http://users.test", httpclient.WithTransport(m))
+//	// or: srv := httptest.NewServer(m); client := httpclient.New(srv.URL)
+//
+//	resp, err := client.Get(ctx, "/users/42") // fails twice, then succeeds
+//
+//	m.AssertCallCount(t, "GET", "/users/42", 3)
+//	m.AssertNoUnmatched(t)
+package httpmock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Verify interface compliance at compile time.
+var (
+	_ http.RoundTripper = (*Mock)(nil)
+	_ http.Handler      = (*Mock)(nil)
+)
+
+// Mock matches incoming requests against registered routes and replies with
+// their scripted responses. Requests matching no route get a 404 and are
+// recorded as unmatched. It is safe for concurrent use.
+type Mock struct {
+	mu        sync.Mutex
+	routes    []*Route
+	calls     []Call
+	unmatched []Call
+}
+
+// New creates an empty Mock.
+func New() *Mock {
+	return &Mock{}
+}
+
+// Call is a request received by the Mock.
+type Call struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+	Time   time.Time
+
+	// Route is the route that handled the call, or nil if none matched.
+	Route *Route
+}
+
+// String returns "METHOD path", the format used by AssertCalledInOrder.
+func (c Call) String() string {
+	return c.Method + " " + c.Path
+}
+
+// On registers a route for method ("" or "*" for any) and path. The path may
+// be a path.Match pattern such as "/users/*". Routes are tried in the order
+// they were registered; the first matching, non-exhausted route wins.
+func (m *Mock) On(method, pattern string) *Route {
+	r := &Route{method: method, pattern: pattern}
+	m.mu.Lock()
+	m.routes = append(m.routes, r)
+	m.mu.Unlock()
+	return r
+}
+
+// RoundTrip implements http.RoundTripper, so the Mock can be passed to
+// httpclient.WithTransport.
+func (m *Mock) RoundTrip(req *http.Request) (*http.Response, error) {
+	call, reply := m.handle(req)
+	if call.readErr != nil {
+		return nil, call.readErr
+	}
+	if err := reply.wait(req); err != nil {
+		return nil, err
+	}
+	if reply.err != nil {
+		return nil, reply.err
+	}
+	return &http.Response{
+		StatusCode:    reply.status,
+		Status:        fmt.Sprintf("%d %s", reply.status, http.StatusText(reply.status)),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        reply.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(reply.body)),
+		ContentLength: int64(len(reply.body)),
+		Request:       req,
+	}, nil
+}
+
+// ServeHTTP implements http.Handler, so the Mock can back an
+// httptest.Server. An Error reply aborts the connection.
+func (m *Mock) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	_, reply := m.handle(req)
+	if err := reply.wait(req); err != nil {
+		return
+	}
+	if reply.err != nil {
+		panic(http.ErrAbortHandler)
+	}
+	for k, vs := range reply.header {
+		w.Header()[k] = append([]string(nil), vs...)
+	}
+	w.WriteHeader(reply.status)
+	_, _ = w.Write(reply.body)
+}
+
+// recordedCall pairs a Call with any error reading its body.
+type recordedCall struct {
+	Call
+	readErr error
+}
+
+// handle records req and picks the reply.
+func (m *Mock) handle(req *http.Request) (recordedCall, *Reply) {
+	call := recordedCall{Call: Call{
+		Method: req.Method,
+		Path:   req.URL.Path,
+		Query:  req.URL.Query(),
+		Header: req.Header.Clone(),
+		Time:   time.Now(),
+	}}
+	if req.Body != nil {
+		call.Body, call.readErr = io.ReadAll(req.Body)
+		_ = req.Body.Close()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, r := range m.routes {
+		if r.exhausted() || !r.matches(&call.Call) {
+			continue
+		}
+		call.Route = r
+		m.calls = append(m.calls, call.Call)
+		return call, r.next()
+	}
+
+	m.calls = append(m.calls, call.Call)
+	m.unmatched = append(m.unmatched, call.Call)
+	return call, Text(http.StatusNotFound, "httpmock: no route matches "+call.String())
+}
+
+// Calls returns all received requests in order.
+func (m *Mock) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Call(nil), m.calls...)
+}
+
+// Unmatched returns the requests that matched no route.
+func (m *Mock) Unmatched() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Call(nil), m.unmatched...)
+}
+
+// Reset removes all routes and recorded calls.
+func (m *Mock) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.routes, m.calls, m.unmatched = nil, nil, nil
+}
+
+// CallCount returns the number of matched calls to method and path (which
+// are compared literally, not as patterns).
+func (m *Mock) CallCount(method, path string) int {
+	n := 0
+	for _, c := range m.Calls() {
+		if c.Route != nil && c.Method == method && c.Path == path {
+			n++
+		}
+	}
+	return n
+}
+
+// --- Assertions ---
+
+// AssertCalled asserts that method and path were called at least once.
+func (m *Mock) AssertCalled(t testing.TB, method, path string) {
+	t.Helper()
+	if m.CallCount(method, path) == 0 {
+		t.Errorf("expected %s %s to be called; calls: %v", method, path, m.Calls())
+	}
+}
+
+// AssertNotCalled asserts that method and path were never called.
+func (m *Mock) AssertNotCalled(t testing.TB, method, path string) {
+	t.Helper()
+	if n := m.CallCount(method, path); n > 0 {
+		t.Errorf("expected %s %s not to be called, got %d calls", method, path, n)
+	}
+}
+
+// AssertCallCount asserts that method and path were called exactly n times.
+func (m *Mock) AssertCallCount(t testing.TB, method, path string, n int) {
+	t.Helper()
+	if got := m.CallCount(method, path); got != n {
+		t.Errorf("expected %s %s to be called %d times, got %d", method, path, n, got)
+	}
+}
+
+// AssertCalledInOrder asserts that the given calls, written as "METHOD path",
+// were received in this order. Other calls may be interleaved.
+func (m *Mock) AssertCalledInOrder(t testing.TB, calls ...string) {
+	t.Helper()
+	received := m.Calls()
+	i := 0
+	for _, c := range received {
+		if i < len(calls) && c.String() == calls[i] {
+			i++
+		}
+	}
+	if i < len(calls) {
+		t.Errorf("expected calls in order %v; missing %q in received %v", calls, calls[i], received)
+	}
+}
+
+// AssertExpectations asserts that every route was called at least once, or
+// exactly as many times as set with Times.
+func (m *Mock) AssertExpectations(t testing.TB) {
+	t.Helper()
+	m.mu.Lock()
+	routes := append([]*Route(nil), m.routes...)
+	m.mu.Unlock()
+	for _, r := range routes {
+		n := r.CallCount()
+		switch {
+		case r.times > 0 && n != r.times:
+			t.Errorf("expected %s to be called %d times, got %d", r, r.times, n)
+		case n == 0:
+			t.Errorf("expected %s to be called", r)
+		}
+	}
+}
+
+// AssertNoUnmatched asserts that every request matched a route, listing the
+// ones that didn't.
+func (m *Mock) AssertNoUnmatched(t testing.TB) {
+	t.Helper()
+	for _, c := range m.Unmatched() {
+		t.Errorf("unmatched request %s (query %v, headers %v, body %q)", c, c.Query, c.Header, c.Body)
+	}
+}
+
+// --- Routes ---
+
+// Route is a registered request matcher with its scripted replies.
+type Route struct {
+	method   string
+	pattern  string
+	matchers []func(*Call) bool
+	replies  []*Reply
+	delay    time.Duration
+	times    int
+
+	mu    sync.Mutex
+	calls int
+}
+
+// String describes the route for assertion messages.
+func (r *Route) String() string {
+	method := r.method
+	if method == "" {
+		method = "*"
+	}
+	return method + " " + r.pattern
+}
+
+// Header requires the request header key to equal value.
+func (r *Route) Header(key, value string) *Route {
+	return r.Match(func(c *Call) bool { return c.Header.Get(key) == value })
+}
+
+// Query requires the query parameter key to have value among its values.
+func (r *Route) Query(key, value string) *Route {
+	return r.Match(func(c *Call) bool {
+		for _, v := range c.Query[key] {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Body requires the request body to equal body exactly.
+func (r *Route) Body(body string) *Route {
+	return r.Match(func(c *Call) bool { return string(c.Body) == body })
+}
+
+// JSONBody requires the request body to be JSON containing v: objects match
+// when every expected key is present with a matching value (extra keys are
+// ignored, recursively), arrays when they have the same length and matching
+// elements, and scalars when equal. v may be a value to marshal, or a JSON
+// string or []byte.
+func (r *Route) JSONBody(v any) *Route {
+	want, err := toJSONValue(v)
+	if err != nil {
+		panic(fmt.Sprintf("httpmock: invalid JSONBody: %v", err))
+	}
+	return r.Match(func(c *Call) bool {
+		var got any
+		if json.Unmarshal(c.Body, &got) != nil {
+			return false
+		}
+		return jsonContains(got, want)
+	})
+}
+
+// Match adds a custom matcher.
+func (r *Route) Match(fn func(c *Call) bool) *Route {
+	r.matchers = append(r.matchers, fn)
+	return r
+}
+
+// Reply sets the responses returned by successive matching calls. The last
+// reply repeats once the sequence is exhausted, so
+//
+//	route.Reply(httpmock.Status(503), httpmock.Status(503), httpmock.Status(200))
+//
+// fails twice and then succeeds. A route without replies returns 200 with
+// an empty body.
+func (r *Route) Reply(replies ...*Reply) *Route {
+	r.replies = replies
+	return r
+}
+
+// Delay adds latency before every reply of the route, on top of any reply
+// delay. It stops early when the request context is done.
+func (r *Route) Delay(d time.Duration) *Route {
+	r.delay = d
+	return r
+}
+
+// Times limits the route to n matches; later requests fall through to the
+// next matching route. AssertExpectations checks that it was called exactly
+// n times.
+func (r *Route) Times(n int) *Route {
+	r.times = n
+	return r
+}
+
+// CallCount returns the number of calls the route handled.
+func (r *Route) CallCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls
+}
+
+func (r *Route) matches(c *Call) bool {
+	if r.method != "" && r.method != "*" && !strings.EqualFold(r.method, c.Method) {
+		return false
+	}
+	if ok, err := path.Match(r.pattern, c.Path); err != nil || !ok {
+		return false
+	}
+	for _, fn := range r.matchers {
+		if !fn(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Route) exhausted() bool {
+	return r.times > 0 && r.CallCount() >= r.times
+}
+
+// next records a call and returns its reply, with the route delay applied.
+func (r *Route) next() *Reply {
+	r.mu.Lock()
+	i := r.calls
+	r.calls++
+	r.mu.Unlock()
+
+	reply := Status(http.StatusOK)
+	if len(r.replies) > 0 {
+		reply = r.replies[min(i, len(r.replies)-1)]
+	}
+	if r.delay > 0 {
+		cp := *reply
+		cp.delay += r.delay
+		reply = &cp
+	}
+	return reply
+}
+
+// --- Replies ---
+
+// Reply is a scripted response.
+type Reply struct {
+	status int
+	header http.Header
+	body   []byte
+	delay  time.Duration
+	err    error
+}
+
+// Status replies with the status code and an empty body.
+func Status(code int) *Reply {
+	return &Reply{status: code, header: http.Header{}}
+}
+
+// Text replies with a text/plain body.
+func Text(code int, body string) *Reply {
+	r := Status(code)
+	r.header.Set("Content-Type", "text/plain; charset=utf-8")
+	r.body = []byte(body)
+	return r
+}
+
+// JSON replies with v encoded as JSON. It panics if v cannot be encoded.
+func JSON(code int, v any) *Reply {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("httpmock: cannot encode JSON reply: %v", err))
+	}
+	r := Status(code)
+	r.header.Set("Content-Type", "application/json")
+	r.body = b
+	return r
+}
+
+// Error fails the request at the transport level: RoundTrip returns err,
+// and ServeHTTP aborts the connection.
+func Error(err error) *Reply {
+	return &Reply{err: err, header: http.Header{}}
+}
+
+// WithHeader sets a response header.
+func (r *Reply) WithHeader(key, value string) *Reply {
+	r.header.Set(key, value)
+	return r
+}
+
+// WithBody sets the raw response body.
+func (r *Reply) WithBody(body []byte) *Reply {
+	r.body = body
+	return r
+}
+
+// WithDelay adds latency before the reply is sent.
+func (r *Reply) WithDelay(d time.Duration) *Reply {
+	r.delay = d
+	return r
+}
+
+// wait sleeps for the reply's delay, returning the context error if the
+// request is cancelled first.
+func (r *Reply) wait(req *http.Request) error {
+	if r.delay <= 0 {
+		return nil
+	}
+	t := time.NewTimer(r.delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+// --- JSON matching ---
+
+func toJSONValue(v any) (any, error) {
+	var raw []byte
+	switch x := v.(type) {
+	case string:
+		raw = []byte(x)
+	case []byte:
+		raw = x
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		raw = b
+	}
+	var out any
+	err := json.Unmarshal(raw, &out)
+	return out, err
+}
+
+// jsonContains reports whether got contains want, as described by JSONBody.
+func jsonContains(got, want any) bool {
+	switch w := want.(type) {
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok {
+			return false
+		}
+		for k, wv := range w {
+			gv, ok := g[k]
+			if !ok || !jsonContains(gv, wv) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		g, ok := got.([]any)
+		if !ok || len(g) != len(w) {
+			return false
+		}
+		for i := range w {
+			if !jsonContains(g[i], w[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return got == want
+	}
+}
@@ -0,0 +1,162 @@
+package httpmock
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/KARTIKrocks/apikit/httpclient"
+)
+
+// newClient returns an httpclient.Client backed by m with fast retries.
+func newClient(m *Mock, opts ...httpclient.Option) *httpclient.Client {
+	opts = append([]httpclient.Option{
+		httpclient.WithTransport(m),
+		httpclient.WithRetryDelay(time.Millisecond),
+		httpclient.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+	}, opts...)
+	return httpclient.New("http://mock.test", opts...)
+}
+
+func TestMock_SequenceExercisesRetries(t *testing.T) {
+	m := New()
+	m.On("GET", "/flaky").Reply(Status(503), Error(errors.New("connection reset")), JSON(200, map[string]string{"ok": "yes"}))
+	c := newClient(m)
+
+	resp, err := c.Get(context.Background(), "/flaky")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Attempts != 3 || resp.String() != `{"ok":"yes"}` {
+		t.Fatalf("expected success on the third attempt, got %d attempts, body %q", resp.Attempts, resp.String())
+	}
+	m.AssertCallCount(t, "GET", "/flaky", 3)
+	m.AssertExpectations(t)
+	m.AssertNoUnmatched(t)
+}
+
+func TestMock_Matchers(t *testing.T) {
+	m := New()
+	m.On("POST", "/users").
+		Header("Authorization", "Bearer secret").
+		Query("dry_run", "true").
+		JSONBody(`{"name":"alice","address":{"city":"Paris"}}`).
+		Reply(Status(201))
+	m.On("POST", "/users").Reply(Status(400))
+	c := newClient(m)
+	c.SetBearerToken("secret")
+
+	body := map[string]any{"name": "alice", "age": 30, "address": map[string]any{"city": "Paris", "zip": "75001"}}
+	resp, err := c.Request().Path("/users").Param("dry_run", "true").Body(body).Post(context.Background())
+	if err != nil || resp.StatusCode != 201 {
+		t.Fatalf("expected partial JSON match, got %v", err)
+	}
+
+	body["address"] = map[string]any{"city": "Lyon"}
+	_, err = c.Request().Path("/users").Param("dry_run", "true").Body(body).Post(context.Background())
+	var he *httpclient.HTTPError
+	if !errors.As(err, &he) || he.StatusCode != 400 {
+		t.Fatalf("expected the fallback route, got %v", err)
+	}
+}
+
+func TestMock_TimesAndPatterns(t *testing.T) {
+	m := New()
+	m.On("GET", "/items/*").Times(1).Reply(Text(200, "first"))
+	m.On("*", "/items/*").Reply(Text(200, "rest"))
+	c := newClient(m)
+
+	for _, want := range []string{"first", "rest", "rest"} {
+		resp, err := c.Get(context.Background(), "/items/7")
+		if err != nil || resp.String() != want {
+			t.Fatalf("expected %q, got %q (%v)", want, resp.String(), err)
+		}
+	}
+	m.AssertExpectations(t)
+}
+
+func TestMock_LatencyHonoursTimeout(t *testing.T) {
+	m := New()
+	m.On("GET", "/slow").Delay(time.Second)
+	c := newClient(m, httpclient.WithTimeout(20*time.Millisecond), httpclient.WithMaxRetries(0))
+
+	start := time.Now()
+	if _, err := c.Get(context.Background(), "/slow"); err == nil {
+		t.Fatal("expected a timeout")
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatal("expected the delay to stop when the request was cancelled")
+	}
+}
+
+func TestMock_CircuitBreaker(t *testing.T) {
+	m := New()
+	m.On("GET", "/down").Reply(Status(500))
+	c := newClient(m, httpclient.WithMaxRetries(0), httpclient.WithCircuitBreaker(2, time.Minute))
+
+	for range 3 {
+		_, _ = c.Get(context.Background(), "/down")
+	}
+	_, err := c.Get(context.Background(), "/down")
+	if !errors.Is(err, httpclient.ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	m.AssertCallCount(t, "GET", "/down", 2)
+}
+
+func TestMock_UnmatchedAndOrder(t *testing.T) {
+	m := New()
+	m.On("GET", "/a")
+	m.On("POST", "/b")
+	c := newClient(m, httpclient.WithMaxRetries(0))
+
+	ctx := context.Background()
+	_, _ = c.Get(ctx, "/a")
+	_, _ = c.Get(ctx, "/nope")
+	_, _ = c.Post(ctx, "/b", nil)
+
+	m.AssertCalledInOrder(t, "GET /a", "POST /b")
+	m.AssertNotCalled(t, "GET", "/nope")
+	if u := m.Unmatched(); len(u) != 1 || u[0].String() != "GET /nope" {
+		t.Fatalf("expected one unmatched request, got %v", u)
+	}
+
+	ft := &fakeT{TB: t}
+	m.AssertNoUnmatched(ft)
+	m.AssertCalledInOrder(ft, "POST /b", "GET /a")
+	if ft.errors != 2 {
+		t.Fatalf("expected 2 reported failures, got %d", ft.errors)
+	}
+}
+
+func TestMock_Server(t *testing.T) {
+	m := New()
+	m.On("GET", "/ping").Reply(Text(200, "pong").WithHeader("X-Mock", "1"))
+	m.On("GET", "/broken").Reply(Error(errors.New("boom")))
+	srv := httptest.NewServer(m)
+	t.Cleanup(srv.Close)
+
+	c := httpclient.New(srv.URL, httpclient.WithMaxRetries(0),
+		httpclient.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	resp, err := c.Get(context.Background(), "/ping")
+	if err != nil || resp.String() != "pong" || resp.Headers.Get("X-Mock") != "1" {
+		t.Fatalf("unexpected response %v %v", resp, err)
+	}
+	if _, err := c.Get(context.Background(), "/broken"); err == nil {
+		t.Fatal("expected an aborted connection to fail the request")
+	}
+}
+
+// fakeT counts reported failures instead of failing the test.
+type fakeT struct {
+	testing.TB
+	errors int
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(string, ...any) { f.errors++ }
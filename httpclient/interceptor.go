@@ -0,0 +1,125 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/KARTIKrocks/apikit/middleware"
+)
+
+// RoundTripperFunc adapts a function to the http.RoundTripper interface.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req).
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// Interceptor wraps the transport to observe or modify every attempt. It is
+// the client-side analogue of middleware.Middleware. Interceptors run inside
+// the retry loop and circuit breaker: a request retried three times passes
+// through each Interceptor three times, so they suit work that must be
+// redone per attempt — signing, fresh auth tokens, per-attempt metrics.
+//
+//	func Signer(key []byte) httpclient.Interceptor {
+//	    return func(next http.RoundTripper) http.RoundTripper {
+//	        return httpclient.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+//	            req = req.Clone(req.Context())
+//	            req.Header.Set("X-Signature", sign(key, req))
+//	            return next.RoundTrip(req)
+//	        })
+//	    }
+//	}
+type Interceptor func(next http.RoundTripper) http.RoundTripper
+
+// ChainInterceptors composes interceptors into one. The first is outermost:
+// it sees the request first and the response last.
+func ChainInterceptors(interceptors ...Interceptor) Interceptor {
+	return func(next http.RoundTripper) http.RoundTripper {
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			next = interceptors[i](next)
+		}
+		return next
+	}
+}
+
+// Call is a logical request as seen by a CallInterceptor, before retries.
+// Interceptors may modify it before passing it on.
+type Call struct {
+	Method  string
+	Path    string
+	Body    any
+	Headers map[string]string
+}
+
+// CallHandler executes a logical call, including all retries.
+type CallHandler func(ctx context.Context, call *Call) (*Response, error)
+
+// CallInterceptor wraps a whole logical call. It runs once, outside the retry
+// loop, and sees the final Response (with Attempts and RetryWait) or error —
+// suited to call-level logging, metrics, and policy such as refreshing a
+// token and retrying once on 401. CallInterceptors apply to buffered requests;
+// streaming requests (SSE, NDJSON) pass only through Interceptors.
+type CallInterceptor func(next CallHandler) CallHandler
+
+// ChainCallInterceptors composes call interceptors into one. The first is
+// outermost.
+func ChainCallInterceptors(interceptors ...CallInterceptor) CallInterceptor {
+	return func(next CallHandler) CallHandler {
+		for i := len(interceptors) - 1; i >= 0; i-- {
+			next = interceptors[i](next)
+		}
+		return next
+	}
+}
+
+// PropagateRequestID copies the request ID stored by middleware.RequestID in
+// the request context onto the outgoing header (default "X-Request-ID"), so
+// a call made while serving a request carries the same ID downstream. An
+// explicitly set header is left untouched.
+func PropagateRequestID(header ...string) Interceptor {
+	name := "X-Request-ID"
+	if len(header) > 0 && header[0] != "" {
+		name = header[0]
+	}
+	return SetHeaderFunc(name, func(req *http.Request) string {
+		return middleware.GetRequestID(req.Context())
+	})
+}
+
+// PropagateDeadline sends the time remaining before the request context's
+// deadline in the given header (default middleware.RequestTimeoutHeader), so
+// a downstream service using middleware.Deadline stops working once the
+// caller has given up. The client's WithTimeout also bounds the deadline.
+// Requests without any deadline are sent unchanged.
+func PropagateDeadline(header ...string) Interceptor {
+	name := middleware.RequestTimeoutHeader
+	if len(header) > 0 && header[0] != "" {
+		name = header[0]
+	}
+	return SetHeaderFunc(name, func(req *http.Request) string {
+		deadline, ok := req.Context().Deadline()
+		if !ok {
+			return ""
+		}
+		return middleware.FormatRequestTimeout(time.Until(deadline))
+	})
+}
+
+// SetHeaderFunc sets header to the value returned by fn on every attempt,
+// unless the request already carries it or fn returns "".
+func SetHeaderFunc(header string, fn func(req *http.Request) string) Interceptor {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get(header) == "" {
+				if v := fn(req); v != "" {
+					// RoundTrippers must not modify the caller's request.
+					req = req.Clone(req.Context())
+					req.Header.Set(header, v)
+				}
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KARTIKrocks/apikit/middleware"
+)
+
+func TestInterceptors_RunPerAttemptInOrder(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-Trace"); got != "outer,inner" {
+			t.Errorf("unexpected interceptor order %q", got)
+		}
+		if calls.Add(1) < 2 {
+			w.WriteHeader(500)
+			return
+		}
+		w.WriteHeader(200)
+	}))
+	t.Cleanup(ts.Close)
+
+	var mu sync.Mutex
+	var seen []string
+	tag := func(name string) Interceptor {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				mu.Lock()
+				seen = append(seen, name)
+				mu.Unlock()
+				req = req.Clone(req.Context())
+				trace := name
+				if prev := req.Header.Get("X-Trace"); prev != "" {
+					trace = prev + "," + name
+				}
+				req.Header.Set("X-Trace", trace)
+				return next.RoundTrip(req)
+			})
+		}
+	}
+	c := New(ts.URL, WithLogger(quietLogger()), WithMaxRetries(2), WithRetryDelay(time.Millisecond),
+		WithInterceptors(tag("outer"), tag("inner")))
+
+	if _, err := c.Get(context.Background(), "/x"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(seen, ",") != "outer,inner,outer,inner" {
+		t.Fatalf("expected interceptors to run once per attempt, got %v", seen)
+	}
+}
+
+func TestCallInterceptors_RunOncePerCall(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Tenant") != "acme" {
+			t.Errorf("expected header set by call interceptor")
+		}
+		if calls.Add(1) < 3 {
+			w.WriteHeader(502)
+			return
+		}
+		w.WriteHeader(200)
+	}))
+	t.Cleanup(ts.Close)
+
+	var invocations, attempts int
+	c := New(ts.URL, WithLogger(quietLogger()), WithMaxRetries(3), WithRetryDelay(time.Millisecond),
+		WithCallInterceptors(func(next CallHandler) CallHandler {
+			return func(ctx context.Context, call *Call) (*Response, error) {
+				invocations++
+				call.Headers["X-Tenant"] = "acme"
+				resp, err := next(ctx, call)
+				if resp != nil {
+					attempts = resp.Attempts
+				}
+				return resp, err
+			}
+		}))
+
+	if _, err := c.Request().Path("/x").Get(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if invocations != 1 || attempts != 3 {
+		t.Fatalf("expected 1 invocation seeing 3 attempts, got %d/%d", invocations, attempts)
+	}
+}
+
+func TestPropagateRequestID(t *testing.T) {
+	t.Parallel()
+	got := make(chan string, 2)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got <- r.Header.Get("X-Request-ID")
+	}))
+	t.Cleanup(ts.Close)
+	c := New(ts.URL, WithLogger(quietLogger()), WithInterceptors(PropagateRequestID()))
+
+	// Capture a context carrying a request ID the way a handler would see it.
+	var ctx context.Context
+	middleware.RequestID()(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		ctx = r.Context()
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	want := middleware.GetRequestID(ctx)
+
+	if _, err := c.Get(ctx, "/x"); err != nil {
+		t.Fatal(err)
+	}
+	if id := <-got; id != want || id == "" {
+		t.Fatalf("expected request ID %q, got %q", want, id)
+	}
+
+	// An explicit header wins.
+	if _, err := c.Request().Path("/x").Header("X-Request-ID", "explicit").Get(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if id := <-got; id != "explicit" {
+		t.Fatalf("expected explicit header to be kept, got %q", id)
+	}
+}
@@ -0,0 +1,415 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Token is an OAuth2 access token.
+type Token struct {
+	AccessToken  string
+	TokenType    string
+	RefreshToken string
+	// Expiry is when the access token expires. Zero means it never does.
+	Expiry time.Time
+}
+
+// Type returns the token type for the Authorization header, defaulting to
+// "Bearer".
+func (t *Token) Type() string {
+	if t.TokenType == "" || strings.EqualFold(t.TokenType, "bearer") {
+		return "Bearer"
+	}
+	return t.TokenType
+}
+
+// Valid reports whether the token is non-empty and not yet expired.
+func (t *Token) Valid() bool {
+	return t.validFor(0)
+}
+
+// validFor reports whether the token is still valid d from now.
+func (t *Token) validFor(d time.Duration) bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || time.Now().Add(d).Before(t.Expiry)
+}
+
+// TokenSource supplies OAuth2 tokens.
+type TokenSource interface {
+	Token(ctx context.Context) (*Token, error)
+}
+
+// TokenSourceFunc adapts a function to the TokenSource interface.
+type TokenSourceFunc func(ctx context.Context) (*Token, error)
+
+// Token calls f(ctx).
+func (f TokenSourceFunc) Token(ctx context.Context) (*Token, error) {
+	return f(ctx)
+}
+
+// StaticTokenSource always returns the given token. Useful in tests.
+func StaticTokenSource(t *Token) TokenSource {
+	return TokenSourceFunc(func(context.Context) (*Token, error) { return t, nil })
+}
+
+// TokenError is returned when the token endpoint rejects a request. Code and
+// Description carry the RFC 6749 "error" and "error_description" fields.
+type TokenError struct {
+	StatusCode  int
+	Code        string
+	Description string
+}
+
+// Error implements error interface
+func (e *TokenError) Error() string {
+	msg := fmt.Sprintf("oauth2: token request failed: HTTP %d", e.StatusCode)
+	if e.Code != "" {
+		msg += ": " + e.Code
+	}
+	if e.Description != "" {
+		msg += ": " + e.Description
+	}
+	return msg
+}
+
+// OAuth2Config describes an OAuth2 token endpoint and client.
+type OAuth2Config struct {
+	// TokenURL is the token endpoint.
+	TokenURL string
+
+	// ClientID and ClientSecret identify the client.
+	ClientID     string
+	ClientSecret string
+
+	// Scopes are requested space-separated in the "scope" parameter.
+	Scopes []string
+
+	// EndpointParams are extra form parameters, e.g. "audience".
+	EndpointParams url.Values
+
+	// AuthInParams sends the client credentials as form parameters instead
+	// of HTTP Basic auth. Some providers require it. Default: false (Basic).
+	AuthInParams bool
+
+	// HTTPClient performs token requests. Default: a client with a 30s timeout.
+	HTTPClient *http.Client
+}
+
+// ClientCredentials returns a TokenSource using the client-credentials grant.
+// Wrap it with NewCachedTokenSource, or pass it to WithTokenSource which
+// does so automatically.
+func ClientCredentials(cfg OAuth2Config) TokenSource {
+	return TokenSourceFunc(func(ctx context.Context) (*Token, error) {
+		return cfg.fetch(ctx, url.Values{"grant_type": {"client_credentials"}})
+	})
+}
+
+// RefreshTokenSource returns a TokenSource using the refresh-token grant,
+// starting from refreshToken. If the server rotates the refresh token, the
+// new one is used for subsequent refreshes.
+func RefreshTokenSource(cfg OAuth2Config, refreshToken string) TokenSource {
+	var mu sync.Mutex
+	return TokenSourceFunc(func(ctx context.Context) (*Token, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		tok, err := cfg.fetch(ctx, url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {refreshToken},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if tok.RefreshToken != "" {
+			refreshToken = tok.RefreshToken
+		} else {
+			tok.RefreshToken = refreshToken
+		}
+		return tok, nil
+	})
+}
+
+// fetch performs a token request with the given grant parameters.
+func (cfg OAuth2Config) fetch(ctx context.Context, form url.Values) (*Token, error) {
+	if len(cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(cfg.Scopes, " "))
+	}
+	for k, vs := range cfg.EndpointParams {
+		form[k] = vs
+	}
+	if cfg.AuthInParams {
+		form.Set("client_id", cfg.ClientID)
+		if cfg.ClientSecret != "" {
+			form.Set("client_secret", cfg.ClientSecret)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if !cfg.AuthInParams {
+		req.SetBasicAuth(url.QueryEscape(cfg.ClientID), url.QueryEscape(cfg.ClientSecret))
+	}
+
+	hc := cfg.HTTPClient
+	if hc == nil {
+		hc = &http.Client{Timeout: 30 * time.Second}
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: token request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: failed to read token response: %w", err)
+	}
+
+	var tr struct {
+		AccessToken      string `json:"access_token"`
+		TokenType        string `json:"token_type"`
+		RefreshToken     string `json:"refresh_token"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	jsonErr := json.Unmarshal(body, &tr)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &TokenError{StatusCode: resp.StatusCode, Code: tr.Error, Description: tr.ErrorDescription}
+	}
+	if jsonErr != nil {
+		return nil, fmt.Errorf("oauth2: failed to decode token response: %w", jsonErr)
+	}
+	if tr.AccessToken == "" {
+		return nil, errors.New("oauth2: server response missing access_token")
+	}
+
+	tok := &Token{
+		AccessToken:  tr.AccessToken,
+		TokenType:    tr.TokenType,
+		RefreshToken: tr.RefreshToken,
+	}
+	if tr.ExpiresIn > 0 {
+		tok.Expiry = time.Now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+	}
+	return tok, nil
+}
+
+// CachedTokenSource caches tokens from an underlying source. It is safe for
+// concurrent use: a token is refreshed in the background ahead of its
+// expiry, and concurrent callers needing a refresh share a single request
+// to the token endpoint.
+type CachedTokenSource struct {
+	src         TokenSource
+	earlyExpiry time.Duration
+
+	mu          sync.Mutex
+	tok         *Token
+	inflight    *tokenRefresh
+	failedEarly time.Time // when an early refresh last failed
+}
+
+// earlyRetryDelay spaces out early refreshes after one fails, so a failing
+// token endpoint isn't hit on every call while the cached token lasts.
+const earlyRetryDelay = time.Second
+
+// tokenRefresh is a refresh shared by concurrent callers.
+type tokenRefresh struct {
+	done chan struct{}
+	tok  *Token
+	err  error
+}
+
+// NewCachedTokenSource wraps src with caching. Tokens are refreshed
+// earlyExpiry before their actual expiry, so requests never race the
+// deadline; the cached token keeps being served while that refresh runs or
+// if it fails. A negative earlyExpiry defaults to 10s.
+func NewCachedTokenSource(src TokenSource, earlyExpiry time.Duration) *CachedTokenSource {
+	if earlyExpiry < 0 {
+		earlyExpiry = 10 * time.Second
+	}
+	return &CachedTokenSource{src: src, earlyExpiry: earlyExpiry}
+}
+
+// Token returns the cached token, refreshing it if it is missing or about to
+// expire. A token inside the early-expiry window is returned at once while
+// it is refreshed in the background; callers only wait when there is no
+// valid token. Cancelling ctx abandons the wait, not the shared refresh.
+func (s *CachedTokenSource) Token(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	if s.tok.validFor(s.earlyExpiry) {
+		tok := s.tok
+		s.mu.Unlock()
+		return tok, nil
+	}
+	if s.tok.validFor(0) {
+		tok := s.tok
+		if s.inflight == nil && time.Since(s.failedEarly) >= earlyRetryDelay {
+			s.startRefresh(ctx)
+		}
+		s.mu.Unlock()
+		return tok, nil
+	}
+	call := s.inflight
+	if call == nil {
+		call = s.startRefresh(ctx)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.tok, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// startRefresh starts a shared refresh detached from ctx's cancellation.
+// s.mu must be held.
+func (s *CachedTokenSource) startRefresh(ctx context.Context) *tokenRefresh {
+	call := &tokenRefresh{done: make(chan struct{})}
+	s.inflight = call
+	go s.refresh(context.WithoutCancel(ctx), call)
+	return call
+}
+
+// refresh fetches a new token and publishes it to waiting callers.
+func (s *CachedTokenSource) refresh(ctx context.Context, call *tokenRefresh) {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	tok, err := s.src.Token(ctx)
+	if err == nil && (tok == nil || tok.AccessToken == "") {
+		err = errors.New("oauth2: token source returned an empty token")
+	}
+
+	s.mu.Lock()
+	switch {
+	case err == nil:
+		s.tok = tok
+		s.failedEarly = time.Time{}
+	case s.tok.validFor(0):
+		s.failedEarly = time.Now()
+	}
+	s.inflight = nil
+	s.mu.Unlock()
+
+	call.tok, call.err = tok, err
+	close(call.done)
+}
+
+// Invalidate discards tok if it is still the cached token, forcing the next
+// call to Token to refresh. Passing the token that was rejected, rather than
+// clearing unconditionally, keeps concurrent 401s from triggering a refresh
+// storm. A nil tok always clears the cache.
+func (s *CachedTokenSource) Invalidate(tok *Token) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tok == nil || s.tok == tok {
+		s.tok = nil
+	}
+}
+
+// TokenSourceError is returned by calls made with WithTokenSource when no
+// token could be obtained. The request was never sent, so it is not
+// retried unless Temporary reports true, and it never counts against the
+// circuit breaker or concurrency limiter.
+type TokenSourceError struct {
+	Err error
+}
+
+// Error implements error interface
+func (e *TokenSourceError) Error() string {
+	return "oauth2: cannot obtain token: " + e.Err.Error()
+}
+
+// Unwrap returns the token source's error.
+func (e *TokenSourceError) Unwrap() error {
+	return e.Err
+}
+
+// Temporary reports whether retrying may succeed: the token endpoint could
+// not be reached or answered 5xx or 429. Rejections such as invalid_client
+// or invalid_grant are permanent.
+func (e *TokenSourceError) Temporary() bool {
+	var te *TokenError
+	if errors.As(e.Err, &te) {
+		return te.StatusCode >= 500 || te.StatusCode == http.StatusTooManyRequests
+	}
+	var ne net.Error
+	return errors.As(e.Err, &ne)
+}
+
+// tokenHolderKey carries the token used by an attempt back to the call level.
+type tokenHolderKey struct{}
+
+type tokenHolder struct {
+	mu  sync.Mutex
+	tok *Token
+}
+
+// WithTokenSource authenticates every attempt with a token from ts in the
+// Authorization header, replacing any static one such as SetBearerToken.
+// Sources other than *CachedTokenSource are wrapped with
+// NewCachedTokenSource(ts, 10s). When a call ends in 401 Unauthorized, the
+// token is invalidated and the call is retried once with a fresh token.
+// Failures to obtain a token are returned as *TokenSourceError.
+func WithTokenSource(ts TokenSource) Option {
+	cached, ok := ts.(*CachedTokenSource)
+	if !ok {
+		cached = NewCachedTokenSource(ts, 10*time.Second)
+	}
+
+	attempt := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			tok, err := cached.Token(req.Context())
+			if err != nil {
+				return nil, &TokenSourceError{Err: err}
+			}
+			if h, ok := req.Context().Value(tokenHolderKey{}).(*tokenHolder); ok {
+				h.mu.Lock()
+				h.tok = tok
+				h.mu.Unlock()
+			}
+			req = req.Clone(req.Context())
+			req.Header.Set("Authorization", tok.Type()+" "+tok.AccessToken)
+			return next.RoundTrip(req)
+		})
+	}
+
+	call := func(next CallHandler) CallHandler {
+		return func(ctx context.Context, call *Call) (*Response, error) {
+			h := &tokenHolder{}
+			resp, err := next(context.WithValue(ctx, tokenHolderKey{}, h), call)
+			if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+				return resp, err
+			}
+			h.mu.Lock()
+			used := h.tok
+			h.mu.Unlock()
+			cached.Invalidate(used)
+			return next(ctx, call)
+		}
+	}
+
+	return func(c *Client) {
+		c.interceptors = append(c.interceptors, attempt)
+		c.callInterceptors = append(c.callInterceptors, call)
+	}
+}
@@ -0,0 +1,301 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTokenServer returns a token endpoint issuing "tok-1", "tok-2", ... and
+// counting requests.
+func newTokenServer(t *testing.T, expiresIn int, check func(r *http.Request)) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var n atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("parse form: %v", err)
+		}
+		if check != nil {
+			check(r)
+		}
+		i := n.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"tok-%d","token_type":"bearer","expires_in":%d,"refresh_token":"rt-%d"}`, i, expiresIn, i)
+	}))
+	t.Cleanup(ts.Close)
+	return ts, &n
+}
+
+func TestClientCredentials_Fetch(t *testing.T) {
+	t.Parallel()
+	ts, _ := newTokenServer(t, 3600, func(r *http.Request) {
+		if r.PostForm.Get("grant_type") != "client_credentials" {
+			t.Errorf("unexpected grant_type %q", r.PostForm.Get("grant_type"))
+		}
+		if r.PostForm.Get("scope") != "read write" || r.PostForm.Get("audience") != "api" {
+			t.Errorf("unexpected form %v", r.PostForm)
+		}
+		if id, secret, ok := r.BasicAuth(); !ok || id != "id" || secret != "secret" {
+			t.Errorf("expected basic auth, got %q/%q", id, secret)
+		}
+	})
+
+	src := ClientCredentials(OAuth2Config{
+		TokenURL:       ts.URL,
+		ClientID:       "id",
+		ClientSecret:   "secret",
+		Scopes:         []string{"read", "write"},
+		EndpointParams: map[string][]string{"audience": {"api"}},
+	})
+	tok, err := src.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.AccessToken != "tok-1" || tok.Type() != "Bearer" || !tok.Valid() {
+		t.Fatalf("unexpected token %+v", tok)
+	}
+	if time.Until(tok.Expiry) < 59*time.Minute {
+		t.Fatalf("unexpected expiry %v", tok.Expiry)
+	}
+}
+
+func TestRefreshTokenSource_RotatesRefreshToken(t *testing.T) {
+	t.Parallel()
+	var got []string
+	var mu sync.Mutex
+	ts, _ := newTokenServer(t, 60, func(r *http.Request) {
+		mu.Lock()
+		got = append(got, r.PostForm.Get("refresh_token"))
+		mu.Unlock()
+		if r.PostForm.Get("client_id") != "id" {
+			t.Errorf("expected credentials in params")
+		}
+	})
+
+	src := RefreshTokenSource(OAuth2Config{TokenURL: ts.URL, ClientID: "id", AuthInParams: true}, "rt-0")
+	for range 2 {
+		if _, err := src.Token(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fmt.Sprint(got) != "[rt-0 rt-1]" {
+		t.Fatalf("expected rotated refresh tokens, got %v", got)
+	}
+}
+
+func TestTokenError(t *testing.T) {
+	t.Parallel()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":"invalid_client","error_description":"bad secret"}`)
+	}))
+	t.Cleanup(ts.Close)
+
+	_, err := ClientCredentials(OAuth2Config{TokenURL: ts.URL}).Token(context.Background())
+	var te *TokenError
+	if !errors.As(err, &te) || te.Code != "invalid_client" || te.StatusCode != 400 {
+		t.Fatalf("expected TokenError, got %v", err)
+	}
+}
+
+func TestCachedTokenSource_SingleFlightAndEarlyRefresh(t *testing.T) {
+	t.Parallel()
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	src := TokenSourceFunc(func(ctx context.Context) (*Token, error) {
+		n := fetches.Add(1)
+		<-release
+		return &Token{AccessToken: fmt.Sprintf("tok-%d", n), Expiry: time.Now().Add(5 * time.Second)}, nil
+	})
+	cached := NewCachedTokenSource(src, 0)
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cached.Token(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if fetches.Load() != 1 {
+		t.Fatalf("expected a single shared fetch, got %d", fetches.Load())
+	}
+
+	// With an early-expiry window larger than the remaining lifetime, the
+	// still-valid token is served while it is refreshed in the background.
+	early := NewCachedTokenSource(src, 10*time.Second)
+	tok1, _ := early.Token(context.Background())
+	tok2, _ := early.Token(context.Background())
+	if tok1 != tok2 {
+		t.Fatal("expected the still-valid token to be served during the refresh")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		tok, _ := early.Token(context.Background())
+		if tok != tok1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected token inside the early-expiry window to be refreshed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCachedTokenSource_EarlyRefreshFailureKeepsToken(t *testing.T) {
+	t.Parallel()
+	var fetches atomic.Int32
+	src := TokenSourceFunc(func(ctx context.Context) (*Token, error) {
+		if fetches.Add(1) > 1 {
+			return nil, &TokenError{StatusCode: http.StatusServiceUnavailable}
+		}
+		return &Token{AccessToken: "tok-1", Expiry: time.Now().Add(5 * time.Second)}, nil
+	})
+	cached := NewCachedTokenSource(src, 10*time.Second)
+
+	first, err := cached.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range 3 {
+		tok, err := cached.Token(context.Background())
+		if err != nil {
+			t.Fatalf("expected the failed early refresh to keep the valid token, got %v", err)
+		}
+		if tok != first {
+			t.Fatalf("expected the cached token, got %q", tok.AccessToken)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if n := fetches.Load(); n != 2 {
+		t.Fatalf("expected one early refresh attempt within the retry delay, got %d fetches", n-1)
+	}
+}
+
+func TestCachedTokenSource_InvalidateOnlyMatchingToken(t *testing.T) {
+	t.Parallel()
+	var n atomic.Int32
+	cached := NewCachedTokenSource(TokenSourceFunc(func(context.Context) (*Token, error) {
+		return &Token{AccessToken: fmt.Sprint(n.Add(1))}, nil
+	}), 0)
+
+	old, _ := cached.Token(context.Background())
+	cached.Invalidate(old)
+	current, _ := cached.Token(context.Background())
+	cached.Invalidate(old) // stale: must not discard the newer token
+	again, _ := cached.Token(context.Background())
+	if current == old || again != current {
+		t.Fatalf("unexpected tokens %v %v %v", old.AccessToken, current.AccessToken, again.AccessToken)
+	}
+}
+
+func TestWithTokenSource_AuthorizesAndRetriesOn401(t *testing.T) {
+	t.Parallel()
+	tokenTS, fetches := newTokenServer(t, 3600, nil)
+
+	var seen []string
+	var mu sync.Mutex
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		mu.Lock()
+		seen = append(seen, auth)
+		mu.Unlock()
+		if auth != "Bearer tok-2" {
+			w.WriteHeader(http.StatusUnauthorized) // tok-1 was revoked server-side
+			return
+		}
+		w.WriteHeader(200)
+	}))
+	t.Cleanup(api.Close)
+
+	c := New(api.URL, WithLogger(quietLogger()),
+		WithTokenSource(ClientCredentials(OAuth2Config{TokenURL: tokenTS.URL})))
+	c.SetBearerToken("static") // overridden by the token source
+
+	resp, err := c.Get(context.Background(), "/me")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected 200 after refresh, got %d", resp.StatusCode)
+	}
+	if fmt.Sprint(seen) != "[Bearer tok-1 Bearer tok-2]" {
+		t.Fatalf("unexpected Authorization headers %v", seen)
+	}
+	if fetches.Load() != 2 {
+		t.Fatalf("expected 2 token fetches, got %d", fetches.Load())
+	}
+
+	// Subsequent calls reuse the cached token.
+	if _, err := c.Get(context.Background(), "/me"); err != nil {
+		t.Fatal(err)
+	}
+	if fetches.Load() != 2 {
+		t.Fatalf("expected cached token reuse, got %d fetches", fetches.Load())
+	}
+}
+
+func TestWithTokenSource_TokenErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name        string
+		status      int
+		wantFetches int32
+	}{
+		{"permanent rejection is not retried", http.StatusBadRequest, 1},
+		{"token endpoint 5xx is retried", http.StatusServiceUnavailable, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var fetches atomic.Int32
+			tokenTS := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fetches.Add(1)
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, `{"error":"invalid_client"}`)
+			}))
+			t.Cleanup(tokenTS.Close)
+
+			var apiCalls atomic.Int32
+			api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				apiCalls.Add(1)
+			}))
+			t.Cleanup(api.Close)
+
+			c := New(api.URL, WithLogger(quietLogger()),
+				WithMaxRetries(2), WithRetryDelay(time.Millisecond),
+				WithCircuitBreaker(1, time.Hour),
+				WithTokenSource(ClientCredentials(OAuth2Config{TokenURL: tokenTS.URL})))
+
+			_, err := c.Get(context.Background(), "/me")
+			var tse *TokenSourceError
+			if !errors.As(err, &tse) {
+				t.Fatalf("expected TokenSourceError, got %v", err)
+			}
+			var te *TokenError
+			if !errors.As(err, &te) || te.StatusCode != tt.status {
+				t.Fatalf("expected wrapped TokenError with status %d, got %v", tt.status, err)
+			}
+			if got := fetches.Load(); got != tt.wantFetches {
+				t.Errorf("token fetches = %d, want %d", got, tt.wantFetches)
+			}
+			if apiCalls.Load() != 0 {
+				t.Errorf("API was called %d times without a token", apiCalls.Load())
+			}
+			if state := c.CircuitBreaker().State(); state != StateClosed {
+				t.Errorf("breaker state = %v, want closed", state)
+			}
+		})
+	}
+}
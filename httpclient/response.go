@@ -14,6 +14,17 @@ type Response struct {
 	Headers    http.Header
 	Body       []byte
 	Duration   time.Duration
+
+	// Attempts is the number of attempts made, including the first (1 when
+	// the request was not retried).
+	Attempts int
+	// RetryWait is the total time spent waiting between attempts.
+	RetryWait time.Duration
+
+	// CacheStatus reports how the response cache handled the request (see
+	// WithCache). It is empty when the cache was not consulted. Responses
+	// served from the cache have Attempts 0.
+	CacheStatus CacheStatus
 }
 
 // JSON unmarshals response body into v
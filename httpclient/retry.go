@@ -0,0 +1,283 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrRetryBudgetExhausted is returned (wrapping the attempt's error) when a
+// retry was warranted but the client's RetryBudget had no capacity left.
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
+// RetryAttempt describes a failed attempt passed to a RetryPolicy.
+type RetryAttempt struct {
+	// Attempt is the 1-based number of the attempt that just failed.
+	Attempt int
+	// Method is the HTTP method of the request.
+	Method string
+	// Response is the attempt's response, or nil if none was received.
+	Response *Response
+	// Err is the error returned by the attempt.
+	Err error
+}
+
+// RetryPolicy decides whether a failed attempt should be retried. It is only
+// consulted while attempts remain under WithMaxRetries and the context is live.
+type RetryPolicy interface {
+	ShouldRetry(ctx context.Context, a RetryAttempt) bool
+}
+
+// RetryPolicyFunc adapts a function to the RetryPolicy interface.
+type RetryPolicyFunc func(ctx context.Context, a RetryAttempt) bool
+
+// ShouldRetry calls f(ctx, a).
+func (f RetryPolicyFunc) ShouldRetry(ctx context.Context, a RetryAttempt) bool {
+	return f(ctx, a)
+}
+
+// DefaultRetryPolicy retries failures without a response (transport errors),
+// 5xx responses, and 429 Too Many Requests. Other 4xx responses, calls
+// rejected by an open circuit breaker, and permanent token source failures
+// (see TokenSourceError.Temporary) are final.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicyFunc(func(_ context.Context, a RetryAttempt) bool {
+		if a.Response == nil {
+			return retryableNetworkError(a.Err)
+		}
+		return a.Response.StatusCode >= 500 || a.Response.StatusCode == http.StatusTooManyRequests
+	})
+}
+
+// RetryOnStatus retries responses with any of the given status codes.
+func RetryOnStatus(codes ...int) RetryPolicy {
+	set := make(map[int]bool, len(codes))
+	for _, code := range codes {
+		set[code] = true
+	}
+	return RetryPolicyFunc(func(_ context.Context, a RetryAttempt) bool {
+		return a.Response != nil && set[a.Response.StatusCode]
+	})
+}
+
+// RetryOnNetworkErrors retries attempts that failed without receiving a
+// response, such as refused connections, resets, and timeouts.
+func RetryOnNetworkErrors() RetryPolicy {
+	return RetryPolicyFunc(func(_ context.Context, a RetryAttempt) bool {
+		return a.Response == nil && a.Err != nil && retryableNetworkError(a.Err)
+	})
+}
+
+// retryableNetworkError reports whether an attempt that got no response may
+// be retried.
+func retryableNetworkError(err error) bool {
+	if errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	var tse *TokenSourceError
+	if errors.As(err, &tse) {
+		return tse.Temporary()
+	}
+	return true
+}
+
+// AnyOf retries when any of the given policies would.
+func AnyOf(policies ...RetryPolicy) RetryPolicy {
+	return RetryPolicyFunc(func(ctx context.Context, a RetryAttempt) bool {
+		for _, p := range policies {
+			if p.ShouldRetry(ctx, a) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// IdempotentOnly restricts p to idempotent methods (GET, HEAD, OPTIONS,
+// TRACE, PUT, DELETE), so a POST or PATCH is never sent twice.
+func IdempotentOnly(p RetryPolicy) RetryPolicy {
+	return RetryPolicyFunc(func(ctx context.Context, a RetryAttempt) bool {
+		return isIdempotent(a.Method) && p.ShouldRetry(ctx, a)
+	})
+}
+
+// isIdempotent reports whether method is idempotent per RFC 9110.
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// Backoff computes the delay before a retry. attempt is the 1-based retry
+// number and prev is the previous delay (zero before the first retry).
+type Backoff interface {
+	Delay(attempt int, prev time.Duration) time.Duration
+}
+
+// BackoffFunc adapts a function to the Backoff interface.
+type BackoffFunc func(attempt int, prev time.Duration) time.Duration
+
+// Delay calls f(attempt, prev).
+func (f BackoffFunc) Delay(attempt int, prev time.Duration) time.Duration {
+	return f(attempt, prev)
+}
+
+// ConstantBackoff waits d before every retry.
+func ConstantBackoff(d time.Duration) Backoff {
+	return BackoffFunc(func(int, time.Duration) time.Duration { return d })
+}
+
+// ExponentialBackoff doubles the delay from base on each retry, capped at
+// max (no cap when max <= 0). This is the default, driven by WithRetryDelay
+// and WithMaxRetryDelay.
+func ExponentialBackoff(base, max time.Duration) Backoff {
+	return BackoffFunc(func(attempt int, _ time.Duration) time.Duration {
+		return expDelay(base, max, attempt)
+	})
+}
+
+// FullJitterBackoff picks a random delay in [0, exponential delay). It
+// spreads retries from many clients the most and is a good default for
+// avoiding synchronized retry storms.
+func FullJitterBackoff(base, max time.Duration) Backoff {
+	return BackoffFunc(func(attempt int, _ time.Duration) time.Duration {
+		return randDuration(expDelay(base, max, attempt))
+	})
+}
+
+// EqualJitterBackoff keeps half of the exponential delay and randomizes the
+// other half, guaranteeing some minimum wait.
+func EqualJitterBackoff(base, max time.Duration) Backoff {
+	return BackoffFunc(func(attempt int, _ time.Duration) time.Duration {
+		d := expDelay(base, max, attempt)
+		return d/2 + randDuration(d/2)
+	})
+}
+
+// DecorrelatedJitterBackoff picks a random delay between base and three times
+// the previous delay, capped at max.
+func DecorrelatedJitterBackoff(base, max time.Duration) Backoff {
+	return BackoffFunc(func(_ int, prev time.Duration) time.Duration {
+		if prev < base {
+			prev = base
+		}
+		d := base + randDuration(prev*3-base)
+		if max > 0 && d > max {
+			d = max
+		}
+		return d
+	})
+}
+
+// expDelay returns base*2^(attempt-1), capped at max.
+func expDelay(base, max time.Duration, attempt int) time.Duration {
+	shift := attempt - 1
+	// Prevent overflow: 1<<63 flips the sign bit on 64-bit integers.
+	if shift >= 62 {
+		return max
+	}
+	delay := base * time.Duration(1<<uint(shift))
+	if max > 0 && (delay > max || delay < 0) {
+		delay = max
+	}
+	return delay
+}
+
+// randDuration returns a random duration in [0, d).
+func randDuration(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(d)))
+}
+
+// parseRetryAfter returns the delay requested by a Retry-After header on a
+// 429 or 503 response, in either delta-seconds or HTTP-date form.
+func parseRetryAfter(resp *Response) (time.Duration, bool) {
+	if resp == nil || resp.Headers == nil {
+		return 0, false
+	}
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+	v := resp.Headers.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// RetryBudget caps retries across a client to a fraction of its requests,
+// so a failing downstream isn't hammered with up to (1+retries)× its normal
+// load. Within each window, a retry is allowed while retries stay below
+// minRetries or below ratio × requests.
+type RetryBudget struct {
+	mu          sync.Mutex
+	ratio       float64
+	minRetries  int
+	window      time.Duration
+	windowStart time.Time
+	requests    int
+	retries     int
+}
+
+// NewRetryBudget creates a budget allowing retries up to ratio of requests
+// (e.g. 0.1 for 10%) per window, with minRetries always permitted so
+// low-traffic clients can still retry. A window <= 0 defaults to 10s.
+func NewRetryBudget(ratio float64, minRetries int, window time.Duration) *RetryBudget {
+	if window <= 0 {
+		window = 10 * time.Second
+	}
+	return &RetryBudget{
+		ratio:       ratio,
+		minRetries:  minRetries,
+		window:      window,
+		windowStart: time.Now(),
+	}
+}
+
+// recordRequest counts a logical request (not a retry).
+func (b *RetryBudget) recordRequest() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollLocked()
+	b.requests++
+}
+
+// tryRetry reserves capacity for one retry, reporting whether it is allowed.
+func (b *RetryBudget) tryRetry() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rollLocked()
+	if b.retries < b.minRetries || float64(b.retries) < b.ratio*float64(b.requests) {
+		b.retries++
+		return true
+	}
+	return false
+}
+
+// rollLocked starts a new window if the current one has expired.
+func (b *RetryBudget) rollLocked() {
+	if now := time.Now(); now.Sub(b.windowStart) >= b.window {
+		b.windowStart = now
+		b.requests = 0
+		b.retries = 0
+	}
+}
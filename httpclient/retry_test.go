@@ -0,0 +1,247 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KARTIKrocks/apikit/middleware"
+)
+
+func TestRetry_AttemptsOnResponse(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(500)
+			return
+		}
+		w.WriteHeader(200)
+	}, WithMaxRetries(3), WithBackoff(ConstantBackoff(2*time.Millisecond)))
+	t.Cleanup(ts.Close)
+
+	resp, err := c.Get(context.Background(), "/flaky")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", resp.Attempts)
+	}
+	if resp.RetryWait != 4*time.Millisecond {
+		t.Fatalf("expected 4ms total wait, got %v", resp.RetryWait)
+	}
+}
+
+func TestRetry_IdempotentOnly(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(503)
+	}, WithMaxRetries(2), WithRetryDelay(time.Millisecond),
+		WithRetryPolicy(IdempotentOnly(DefaultRetryPolicy())))
+	t.Cleanup(ts.Close)
+
+	resp, err := c.Post(context.Background(), "/orders", map[string]int{"qty": 1})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if calls.Load() != 1 || resp.Attempts != 1 {
+		t.Fatalf("expected POST not to be retried, got %d calls", calls.Load())
+	}
+
+	calls.Store(0)
+	_, _ = c.Put(context.Background(), "/orders/1", nil)
+	if calls.Load() != 3 {
+		t.Fatalf("expected PUT to be retried, got %d calls", calls.Load())
+	}
+}
+
+func TestRetry_RetryOnStatus(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(500)
+	}, WithMaxRetries(2), WithRetryDelay(time.Millisecond), WithRetryPolicy(RetryOnStatus(502, 503)))
+	t.Cleanup(ts.Close)
+
+	_, _ = c.Get(context.Background(), "/x")
+	if calls.Load() != 1 {
+		t.Fatalf("expected 500 not to be retried, got %d calls", calls.Load())
+	}
+}
+
+func TestRetry_HonoursRetryAfter(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(200)
+	}, WithMaxRetries(1), WithRetryDelay(time.Millisecond))
+	t.Cleanup(ts.Close)
+
+	resp, err := c.Get(context.Background(), "/limited")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.RetryWait != time.Second {
+		t.Fatalf("expected to wait the Retry-After second, got %v", resp.RetryWait)
+	}
+}
+
+func TestRetry_RetryAfterBeyondLimit(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, WithMaxRetries(3), WithMaxRetryAfter(time.Second))
+	t.Cleanup(ts.Close)
+
+	resp, err := c.Get(context.Background(), "/maintenance")
+	var he *HTTPError
+	if !errors.As(err, &he) || he.StatusCode != 503 {
+		t.Fatalf("expected 503 HTTPError, got %v", err)
+	}
+	if calls.Load() != 1 || resp.StatusCode != 503 {
+		t.Fatalf("expected a single attempt, got %d", calls.Load())
+	}
+}
+
+func TestRetry_BudgetExhausted(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(500)
+	}, WithMaxRetries(5), WithRetryDelay(time.Millisecond),
+		WithRetryBudget(NewRetryBudget(0, 2, time.Minute)))
+	t.Cleanup(ts.Close)
+
+	_, err := c.Get(context.Background(), "/down")
+	if !errors.Is(err, ErrRetryBudgetExhausted) {
+		t.Fatalf("expected ErrRetryBudgetExhausted, got %v", err)
+	}
+	var he *HTTPError
+	if !errors.As(err, &he) {
+		t.Fatalf("expected the HTTPError to remain reachable, got %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("expected 1 attempt + 2 budgeted retries, got %d", calls.Load())
+	}
+}
+
+func TestRetryBudget_Ratio(t *testing.T) {
+	b := NewRetryBudget(0.5, 0, time.Minute)
+	for range 4 {
+		b.recordRequest()
+	}
+	allowed := 0
+	for range 5 {
+		if b.tryRetry() {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Fatalf("expected 2 retries for 4 requests at 50%%, got %d", allowed)
+	}
+}
+
+func TestBackoffStrategies(t *testing.T) {
+	base, max := 10*time.Millisecond, 80*time.Millisecond
+
+	exp := ExponentialBackoff(base, max)
+	for attempt, want := range map[int]time.Duration{1: 10 * time.Millisecond, 2: 20 * time.Millisecond, 4: 80 * time.Millisecond, 10: max, 100: max} {
+		if got := exp.Delay(attempt, 0); got != want {
+			t.Errorf("exponential attempt %d: got %v, want %v", attempt, got, want)
+		}
+	}
+
+	if got := ConstantBackoff(time.Second).Delay(7, 0); got != time.Second {
+		t.Errorf("constant: got %v", got)
+	}
+
+	for range 100 {
+		if d := FullJitterBackoff(base, max).Delay(3, 0); d < 0 || d >= 40*time.Millisecond {
+			t.Fatalf("full jitter out of range: %v", d)
+		}
+		if d := EqualJitterBackoff(base, max).Delay(3, 0); d < 20*time.Millisecond || d >= 40*time.Millisecond {
+			t.Fatalf("equal jitter out of range: %v", d)
+		}
+		if d := DecorrelatedJitterBackoff(base, max).Delay(2, 20*time.Millisecond); d < base || d > max {
+			t.Fatalf("decorrelated jitter out of range: %v", d)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	tests := []struct {
+		status int
+		value  string
+		ok     bool
+	}{
+		{429, "5", true},
+		{503, date, true},
+		{500, "5", false},
+		{429, "soon", false},
+		{429, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d_%s", tt.status, tt.value), func(t *testing.T) {
+			resp := &Response{StatusCode: tt.status, Headers: http.Header{}}
+			if tt.value != "" {
+				resp.Headers.Set("Retry-After", tt.value)
+			}
+			_, ok := parseRetryAfter(resp)
+			if ok != tt.ok {
+				t.Fatalf("expected ok=%v, got %v", tt.ok, ok)
+			}
+		})
+	}
+}
+
+func TestConcurrencyLimiter_ShedsWithoutRetry(t *testing.T) {
+	t.Parallel()
+	block := make(chan struct{})
+	entered := make(chan struct{}, 1)
+	var calls atomic.Int32
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		entered <- struct{}{}
+		<-block
+		w.WriteHeader(200)
+	}, WithMaxRetries(3), WithRetryDelay(time.Millisecond),
+		WithConcurrencyLimiter(middleware.NewBulkhead(1, 0, 0)))
+	t.Cleanup(ts.Close)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Get(context.Background(), "/slow")
+		done <- err
+	}()
+	<-entered
+
+	_, err := c.Get(context.Background(), "/x")
+	if !errors.Is(err, middleware.ErrLimitExceeded) {
+		t.Fatalf("expected ErrLimitExceeded, got %v", err)
+	}
+
+	close(block)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected the shed call not to reach the server, got %d calls", calls.Load())
+	}
+}
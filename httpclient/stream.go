@@ -0,0 +1,506 @@
+package httpclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// errStreamTooLarge reports a line or event beyond the client's
+// WithMaxResponseBody limit. Unlike a dropped connection, it is not retried.
+var errStreamTooLarge = errors.New("stream data exceeds maximum size")
+
+// SSEEvent is a single Server-Sent Event read from an EventStream.
+type SSEEvent struct {
+	// ID is the last event ID seen on the stream, as defined by the SSE spec
+	// (it persists across events until the server sends a new one).
+	ID string
+	// Event is the event type. Empty means the default "message" type.
+	Event string
+	// Data is the payload; multiple data lines are joined with "\n".
+	Data string
+}
+
+// JSON unmarshals the event data into v.
+func (e SSEEvent) JSON(v any) error {
+	if err := json.Unmarshal([]byte(e.Data), v); err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+	return nil
+}
+
+// streamRequest captures what is needed to (re)open a streaming request.
+type streamRequest struct {
+	method  string
+	path    string
+	body    any
+	headers map[string]string
+}
+
+// openStream connects under the client's retry and circuit-breaker policy and
+// returns the response with its body still open. Streaming responses are not
+// subject to WithTimeout and end when ctx is done; WithMaxResponseBody bounds
+// each line or event rather than the whole body.
+// Non-2xx responses always return an *HTTPError, regardless of WithErrorOnStatus.
+// The returned *Response carries the status, headers, and retry statistics.
+func (c *Client) openStream(ctx context.Context, sr streamRequest) (*http.Response, *Response, error) {
+	var raw *http.Response
+	meta, err := c.withRetries(ctx, sr.method, sr.path, bodyReplayable(sr.body), func() (*Response, error) {
+		req, err := c.newRequest(ctx, sr.method, sr.path, sr.body, sr.headers)
+		if err != nil {
+			return nil, err
+		}
+
+		c.logger.Info("opening stream", "method", sr.method, "url", req.URL.String())
+
+		start := time.Now()
+		resp, err := c.streamClient.Do(req)
+		if err != nil {
+			c.logger.Error("stream request failed", "method", sr.method, "url", req.URL.String(), "error", err)
+			return nil, fmt.Errorf("request failed: %w", err)
+		}
+
+		response := &Response{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Headers:    resp.Header,
+			Duration:   time.Since(start),
+		}
+
+		if resp.StatusCode >= 400 {
+			defer func() { _ = resp.Body.Close() }()
+			body, readErr := c.readBody(resp.Body)
+			if readErr != nil {
+				return nil, readErr
+			}
+			response.Body = body
+			return response, &HTTPError{
+				StatusCode: resp.StatusCode,
+				Status:     resp.Status,
+				Body:       body,
+			}
+		}
+
+		raw = resp
+		return response, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return raw, meta, nil
+}
+
+// SSE opens a Server-Sent Events stream with a GET request. See
+// RequestBuilder.SSE for details.
+func (c *Client) SSE(ctx context.Context, path string) (*EventStream, error) {
+	return c.Request().Path(path).SSE(ctx)
+}
+
+// NDJSON opens a newline-delimited JSON stream with a GET request. See
+// RequestBuilder.NDJSON for details.
+func (c *Client) NDJSON(ctx context.Context, path string) (*JSONStream, error) {
+	return c.Request().Path(path).NDJSON(ctx)
+}
+
+// EventStream reads Server-Sent Events from a streaming response. When the
+// connection drops it reconnects automatically, sending the last seen event ID
+// in the Last-Event-ID header and waiting for the server's "retry:" hint (or
+// the client's retry delay). A 204 No Content on reconnect ends the stream.
+//
+// Use it like bufio.Scanner:
+//
+//	stream, err := client.SSE(ctx, "/events")
+//	if err != nil {
+//	    return err
+//	}
+//	defer stream.Close()
+//	for stream.Next() {
+//	    ev := stream.Event()
+//	    fmt.Println(ev.Event, ev.Data)
+//	}
+//	return stream.Err()
+type EventStream struct {
+	client *Client
+	ctx    context.Context
+	cancel context.CancelFunc
+	req    streamRequest
+
+	body    io.ReadCloser
+	reader  *bufio.Reader
+	atStart bool   // nothing read yet from the current response
+	skipLF  bool   // the last line ended in CR; drop an LF that follows
+	parseID string // the parser's last-event-ID buffer
+	lastID  string // ID of the last event the consumer moved past
+	retry   time.Duration
+	event   SSEEvent
+	pending bool // event was returned by Next and not yet moved past
+	err     error
+	done    bool
+	closed  atomic.Bool
+}
+
+// SSE sends the request and returns an EventStream over the response. The
+// initial connection honours the client's retry and circuit-breaker policy;
+// an error is returned if it cannot be established. The method defaults to GET.
+func (rb *RequestBuilder) SSE(ctx context.Context) (*EventStream, error) {
+	sr, err := rb.streamRequest("text/event-stream")
+	if err != nil {
+		return nil, err
+	}
+	sr.headers["Cache-Control"] = "no-cache"
+
+	ctx, cancel := context.WithCancel(ctx)
+	resp, _, err := rb.client.openStream(ctx, sr)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	s := &EventStream{
+		client: rb.client,
+		ctx:    ctx,
+		cancel: cancel,
+		req:    sr,
+	}
+	s.attach(resp)
+	return s, nil
+}
+
+// attach starts reading from a freshly opened response.
+func (s *EventStream) attach(resp *http.Response) {
+	if resp.StatusCode == http.StatusNoContent {
+		_ = resp.Body.Close()
+		s.done = true
+		return
+	}
+	s.body = resp.Body
+	s.reader = bufio.NewReader(resp.Body)
+	s.atStart, s.skipLF = true, false
+}
+
+// Next advances to the next event, reconnecting if the connection dropped.
+// It returns false when the stream ends, the context is done, Close is
+// called, or reconnecting fails; check Err afterwards.
+func (s *EventStream) Next() bool {
+	// The previous event has been handled: reconnects resume after it.
+	if s.pending {
+		s.lastID = s.event.ID
+		s.pending = false
+	}
+
+	for !s.done && s.err == nil {
+		ev, err := s.readEvent()
+		if err == nil {
+			s.event = ev
+			s.pending = true
+			return true
+		}
+
+		_ = s.body.Close()
+		if s.closed.Load() {
+			s.done = true
+			return false
+		}
+		if s.ctx.Err() != nil {
+			s.err = s.ctx.Err()
+			return false
+		}
+		if errors.Is(err, errStreamTooLarge) {
+			s.err = err
+			return false
+		}
+		s.reconnect()
+	}
+	return false
+}
+
+// reconnect waits for the retry interval and reopens the stream.
+func (s *EventStream) reconnect() {
+	delay := s.retry
+	if delay <= 0 {
+		delay = s.client.retryDelay
+	}
+	s.client.logger.Info("reconnecting stream", "delay", delay, "last_event_id", s.lastID)
+
+	select {
+	case <-time.After(delay):
+	case <-s.ctx.Done():
+		if s.closed.Load() {
+			s.done = true
+		} else {
+			s.err = s.ctx.Err()
+		}
+		return
+	}
+
+	if s.lastID != "" {
+		s.req.headers["Last-Event-ID"] = s.lastID
+	}
+	s.parseID = s.lastID
+	resp, _, err := s.client.openStream(s.ctx, s.req)
+	if err != nil {
+		if s.closed.Load() {
+			s.done = true
+		} else {
+			s.err = err
+		}
+		return
+	}
+	s.attach(resp)
+}
+
+// Event returns the event read by the most recent call to Next.
+func (s *EventStream) Event() SSEEvent {
+	return s.event
+}
+
+// LastEventID returns the ID sent in Last-Event-ID on reconnect: that of the
+// last event the consumer moved past by calling Next again. An event the
+// consumer stopped on is therefore replayed by a later stream resumed from
+// this ID.
+func (s *EventStream) LastEventID() string {
+	return s.lastID
+}
+
+// Err returns the error that ended the stream, or nil if it ended normally
+// (server sent 204 on reconnect, or Close was called).
+func (s *EventStream) Err() error {
+	return s.err
+}
+
+// Close stops the stream and releases the connection. It is safe to call
+// from another goroutine to interrupt a blocked Next.
+func (s *EventStream) Close() error {
+	s.closed.Store(true)
+	s.cancel()
+	return nil
+}
+
+// readEvent parses lines until a complete event is dispatched, following the
+// WHATWG event-stream interpretation rules.
+func (s *EventStream) readEvent() (SSEEvent, error) {
+	limit := s.client.streamLineLimit()
+	var data strings.Builder
+	var hasData bool
+	var eventType string
+
+	for {
+		line, err := s.readLine(limit)
+		if err != nil {
+			// A partially received event is discarded, per the spec.
+			return SSEEvent{}, err
+		}
+		if s.atStart {
+			line = strings.TrimPrefix(line, "\ufeff")
+			s.atStart = false
+		}
+
+		if line == "" {
+			if !hasData {
+				eventType = ""
+				continue
+			}
+			return SSEEvent{ID: s.parseID, Event: eventType, Data: data.String()}, nil
+		}
+		if line[0] == ':' {
+			continue // comment / heartbeat
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+			if int64(data.Len()) > limit {
+				return SSEEvent{}, fmt.Errorf("%w: event exceeds %d bytes", errStreamTooLarge, limit)
+			}
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				s.parseID = value
+			}
+		case "retry":
+			if ms, err := strconv.Atoi(value); err == nil && ms >= 0 {
+				s.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+}
+
+// readLine reads one line of at most limit bytes terminated by CRLF, LF, or
+// CR, as the event-stream format allows. The LF of a CRLF is dropped when
+// the next line is read, so a line ending in CR is returned without waiting
+// for more data.
+func (s *EventStream) readLine(limit int64) (string, error) {
+	if s.skipLF {
+		s.skipLF = false
+		b, err := s.reader.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if b != '\n' {
+			_ = s.reader.UnreadByte()
+		}
+	}
+
+	var buf []byte
+	for {
+		if _, err := s.reader.Peek(1); err != nil {
+			return string(buf), err
+		}
+		chunk, _ := s.reader.Peek(s.reader.Buffered())
+		i := bytes.IndexAny(chunk, "\r\n")
+		if i < 0 {
+			i = len(chunk)
+		}
+		buf = append(buf, chunk[:i]...)
+		if int64(len(buf)) > limit {
+			return "", fmt.Errorf("%w: line exceeds %d bytes", errStreamTooLarge, limit)
+		}
+		if i < len(chunk) {
+			s.skipLF = chunk[i] == '\r'
+			_, _ = s.reader.Discard(i + 1)
+			return string(buf), nil
+		}
+		_, _ = s.reader.Discard(i)
+	}
+}
+
+// JSONStream decodes a newline-delimited JSON (NDJSON / JSON Lines) response
+// one value at a time. Blank lines are skipped.
+//
+//	stream, err := client.NDJSON(ctx, "/export")
+//	if err != nil {
+//	    return err
+//	}
+//	defer stream.Close()
+//	for {
+//	    var rec Record
+//	    if err := stream.Decode(&rec); err == io.EOF {
+//	        break
+//	    } else if err != nil {
+//	        return err
+//	    }
+//	    process(rec)
+//	}
+type JSONStream struct {
+	cancel context.CancelFunc
+	body   io.ReadCloser
+	reader *bufio.Reader
+	limit  int64
+	eof    bool
+}
+
+// NDJSON sends the request and returns a JSONStream over the response. The
+// initial connection honours the client's retry and circuit-breaker policy.
+// The method defaults to GET.
+func (rb *RequestBuilder) NDJSON(ctx context.Context) (*JSONStream, error) {
+	sr, err := rb.streamRequest("application/x-ndjson")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	resp, _, err := rb.client.openStream(ctx, sr)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &JSONStream{
+		cancel: cancel,
+		body:   resp.Body,
+		reader: bufio.NewReader(resp.Body),
+		limit:  rb.client.streamLineLimit(),
+	}, nil
+}
+
+// Decode reads the next JSON value into v. It returns io.EOF when the stream
+// is exhausted, or the context error if the context was cancelled.
+func (s *JSONStream) Decode(v any) error {
+	for !s.eof {
+		line, err := readLine(s.reader, s.limit)
+		if errors.Is(err, io.EOF) {
+			s.eof = true
+		} else if err != nil {
+			return err
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if err := json.Unmarshal([]byte(line), v); err != nil {
+			return fmt.Errorf("failed to unmarshal JSON: %w", err)
+		}
+		return nil
+	}
+	return io.EOF
+}
+
+// Close stops the stream and releases the connection.
+func (s *JSONStream) Close() error {
+	s.cancel()
+	return s.body.Close()
+}
+
+// streamRequest builds the streaming request description from the builder.
+func (rb *RequestBuilder) streamRequest(accept string) (streamRequest, error) {
+	if rb == nil || rb.client == nil {
+		return streamRequest{}, errors.New("request builder or client is nil")
+	}
+	path, err := rb.buildPath()
+	if err != nil {
+		return streamRequest{}, err
+	}
+	method := rb.method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	headers := make(map[string]string, len(rb.headers)+2)
+	headers["Accept"] = accept
+	for k, v := range rb.headers {
+		headers[k] = v
+	}
+	return streamRequest{method: method, path: path, body: rb.body, headers: headers}, nil
+}
+
+// streamLineLimit bounds a single line or event of a streaming response.
+func (c *Client) streamLineLimit() int64 {
+	if c.maxResponseBody <= 0 {
+		return DefaultMaxResponseBody
+	}
+	return c.maxResponseBody
+}
+
+// readLine reads one line of at most limit bytes, stripping the trailing
+// "\n" or "\r\n". A final line without a newline is returned with io.EOF.
+func readLine(r *bufio.Reader, limit int64) (string, error) {
+	var buf []byte
+	for {
+		chunk, err := r.ReadSlice('\n')
+		buf = append(buf, chunk...)
+		if int64(len(buf)) > limit+2 {
+			return "", fmt.Errorf("%w: line exceeds %d bytes", errStreamTooLarge, limit)
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		line := strings.TrimSuffix(strings.TrimSuffix(string(buf), "\n"), "\r")
+		return line, err
+	}
+}
@@ -0,0 +1,299 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSSE_ParsesEvents(t *testing.T) {
+	t.Parallel()
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Accept"); got != "text/event-stream" {
+			t.Errorf("expected Accept text/event-stream, got %q", got)
+		}
+		if r.URL.Query().Get("room") != "lobby" {
+			t.Errorf("expected query param to be forwarded, got %q", r.URL.RawQuery)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": comment\n\n")
+		fmt.Fprint(w, "id: 1\nevent: greet\ndata: hello\n\n")
+		fmt.Fprint(w, "data: line1\r\ndata: line2\r\n\r\n")
+		fmt.Fprint(w, "id: 3\ndata: {\"n\":3}\n\n")
+	})
+	t.Cleanup(ts.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := c.Request().Path("/events").Param("room", "lobby").SSE(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	var got []SSEEvent
+	for len(got) < 3 && stream.Next() {
+		got = append(got, stream.Event())
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected 3 events, got %d (err %v)", len(got), stream.Err())
+	}
+	if got[0] != (SSEEvent{ID: "1", Event: "greet", Data: "hello"}) {
+		t.Errorf("unexpected first event %+v", got[0])
+	}
+	if got[1].Data != "line1\nline2" || got[1].ID != "1" {
+		t.Errorf("unexpected multi-line event %+v", got[1])
+	}
+	var v struct{ N int }
+	if err := got[2].JSON(&v); err != nil || v.N != 3 {
+		t.Errorf("unexpected JSON event %+v (%v)", got[2], err)
+	}
+}
+
+func TestSSE_LineEndingsAndBOM(t *testing.T) {
+	t.Parallel()
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		flush := w.(http.Flusher).Flush
+		fmt.Fprint(w, "\ufeffid: 1\rdata: cr\r\r")
+		fmt.Fprint(w, "data: a\r")
+		flush()
+		// The LF of a CRLF split across reads is still one line break.
+		fmt.Fprint(w, "\ndata: b\r\n\r\n")
+		fmt.Fprint(w, "data: lf\n\n")
+		flush()
+	})
+	t.Cleanup(ts.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := c.SSE(ctx, "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	var got []SSEEvent
+	for len(got) < 3 && stream.Next() {
+		got = append(got, stream.Event())
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected 3 events, got %d (err %v)", len(got), stream.Err())
+	}
+	if got[0] != (SSEEvent{ID: "1", Data: "cr"}) {
+		t.Errorf("expected the BOM stripped and CR-only lines split, got %+v", got[0])
+	}
+	if got[1].Data != "a\nb" {
+		t.Errorf("expected a split CRLF to end one line, got %q", got[1].Data)
+	}
+	if got[2].Data != "lf" {
+		t.Errorf("unexpected LF event %+v", got[2])
+	}
+}
+
+func TestSSE_ReconnectsWithLastEventID(t *testing.T) {
+	t.Parallel()
+	var conns atomic.Int32
+	var lastIDs [3]atomic.Value
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n := conns.Add(1)
+		if n <= 3 {
+			lastIDs[n-1].Store(r.Header.Get("Last-Event-ID"))
+		}
+		switch n {
+		case 1:
+			fmt.Fprint(w, "retry: 5\nid: 41\ndata: a\n\n")
+		case 2:
+			fmt.Fprint(w, "id: 42\ndata: b\n\n")
+		default:
+			w.WriteHeader(http.StatusNoContent) // tells the client to stop
+		}
+	}, WithRetryDelay(time.Hour))
+	t.Cleanup(ts.Close)
+
+	stream, err := c.SSE(context.Background(), "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	var data []string
+	for stream.Next() {
+		data = append(data, stream.Event().Data)
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("expected clean end on 204, got %v", err)
+	}
+	if strings.Join(data, ",") != "a,b" {
+		t.Fatalf("unexpected events %v", data)
+	}
+	if conns.Load() != 3 {
+		t.Fatalf("expected 3 connections, got %d", conns.Load())
+	}
+	if lastIDs[1].Load() != "41" || lastIDs[2].Load() != "42" {
+		t.Fatalf("expected Last-Event-ID 41 then 42, got %v and %v", lastIDs[1].Load(), lastIDs[2].Load())
+	}
+}
+
+func TestSSE_LastEventIDExcludesUnhandledEvent(t *testing.T) {
+	t.Parallel()
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "id: 1\ndata: a\n\nid: 2\ndata: b\n\n")
+	})
+	t.Cleanup(ts.Close)
+
+	stream, err := c.SSE(context.Background(), "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	if !stream.Next() || stream.Event().ID != "1" {
+		t.Fatalf("expected event 1, got %+v (%v)", stream.Event(), stream.Err())
+	}
+	if id := stream.LastEventID(); id != "" {
+		t.Fatalf("event 1 is still being handled; LastEventID = %q, want empty", id)
+	}
+	if !stream.Next() || stream.Event().ID != "2" {
+		t.Fatalf("expected event 2, got %+v (%v)", stream.Event(), stream.Err())
+	}
+	// The consumer stops on event 2: resuming must replay it.
+	if id := stream.LastEventID(); id != "1" {
+		t.Fatalf("LastEventID = %q, want %q", id, "1")
+	}
+}
+
+func TestSSE_InitialConnectRetries(t *testing.T) {
+	t.Parallel()
+	var calls atomic.Int32
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, "data: ok\n\n")
+	}, WithMaxRetries(3), WithRetryDelay(time.Millisecond))
+	t.Cleanup(ts.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := c.SSE(ctx, "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	if !stream.Next() || stream.Event().Data != "ok" {
+		t.Fatalf("expected event after retries, err %v", stream.Err())
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("expected 3 connection attempts, got %d", calls.Load())
+	}
+}
+
+func TestSSE_InitialConnectClientError(t *testing.T) {
+	t.Parallel()
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":"nope"}`)
+	})
+	t.Cleanup(ts.Close)
+
+	_, err := c.SSE(context.Background(), "/events")
+	var he *HTTPError
+	if !errors.As(err, &he) || he.StatusCode != 401 {
+		t.Fatalf("expected 401 HTTPError, got %v", err)
+	}
+}
+
+func TestSSE_CloseUnblocksNext(t *testing.T) {
+	t.Parallel()
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}, WithTimeout(50*time.Millisecond))
+	t.Cleanup(ts.Close)
+
+	stream, err := c.SSE(context.Background(), "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan bool)
+	go func() { done <- stream.Next() }()
+
+	// WithTimeout must not cut the stream short.
+	time.Sleep(100 * time.Millisecond)
+	_ = stream.Close()
+
+	select {
+	case ok := <-done:
+		if ok {
+			t.Fatal("expected Next to return false after Close")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close did not unblock Next")
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("expected nil error after Close, got %v", err)
+	}
+}
+
+func TestNDJSON_Decode(t *testing.T) {
+	t.Parallel()
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Accept"); got != "application/x-ndjson" {
+			t.Errorf("expected Accept application/x-ndjson, got %q", got)
+		}
+		fmt.Fprint(w, "{\"id\":1}\n\n{\"id\":2}\r\n{\"id\":3}")
+	})
+	t.Cleanup(ts.Close)
+
+	stream, err := c.NDJSON(context.Background(), "/export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	var ids []int
+	for {
+		var rec struct{ ID int }
+		err := stream.Decode(&rec)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, rec.ID)
+	}
+	if fmt.Sprint(ids) != "[1 2 3]" {
+		t.Fatalf("unexpected records %v", ids)
+	}
+}
+
+func TestNDJSON_LineTooLarge(t *testing.T) {
+	t.Parallel()
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "{\"s\":%q}\n", strings.Repeat("x", 100))
+	}, WithMaxResponseBody(32))
+	t.Cleanup(ts.Close)
+
+	stream, err := c.NDJSON(context.Background(), "/export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	var v map[string]string
+	if err := stream.Decode(&v); !errors.Is(err, errStreamTooLarge) {
+		t.Fatalf("expected size error, got %v", err)
+	}
+}
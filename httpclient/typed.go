@@ -0,0 +1,186 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+
+	"github.com/KARTIKrocks/apikit/errors"
+	"github.com/KARTIKrocks/apikit/response"
+)
+
+// --- Plain JSON ---
+
+// GetJSON performs a GET request and decodes the JSON response body into T.
+//
+//	user, err := httpclient.GetJSON[User](ctx, client, "/users/42")
+func GetJSON[T any](ctx context.Context, c HTTPClient, path string) (T, error) {
+	return DecodeJSON[T](c.Get(ctx, path))
+}
+
+// PostJSON performs a POST request with a JSON body and decodes the JSON
+// response body into Out.
+//
+//	created, err := httpclient.PostJSON[CreateUser, User](ctx, client, "/users", in)
+func PostJSON[In, Out any](ctx context.Context, c HTTPClient, path string, body In) (Out, error) {
+	return DecodeJSON[Out](c.Post(ctx, path, body))
+}
+
+// PutJSON performs a PUT request with a JSON body and decodes the JSON
+// response body into Out.
+func PutJSON[In, Out any](ctx context.Context, c HTTPClient, path string, body In) (Out, error) {
+	return DecodeJSON[Out](c.Put(ctx, path, body))
+}
+
+// PatchJSON performs a PATCH request with a JSON body and decodes the JSON
+// response body into Out.
+func PatchJSON[In, Out any](ctx context.Context, c HTTPClient, path string, body In) (Out, error) {
+	return DecodeJSON[Out](c.Patch(ctx, path, body))
+}
+
+// DeleteJSON performs a DELETE request and decodes the JSON response body
+// into T.
+func DeleteJSON[T any](ctx context.Context, c HTTPClient, path string) (T, error) {
+	return DecodeJSON[T](c.Delete(ctx, path))
+}
+
+// DecodeJSON decodes the JSON body of a completed request into T. It accepts
+// a request's results directly, so it composes with the RequestBuilder:
+//
+//	user, err := httpclient.DecodeJSON[User](client.Request().
+//	    Path("/users/42").Header("X-Tenant", "acme").Get(ctx))
+//
+// A request error is returned as-is, and a non-2xx response is reported as an
+// *HTTPError even when error-on-status is disabled. An empty body decodes to
+// the zero value.
+func DecodeJSON[T any](resp *Response, err error) (T, error) {
+	var out T
+	if err = statusError(resp, err); err != nil {
+		return out, err
+	}
+	if len(resp.Body) == 0 {
+		return out, nil
+	}
+	if err := resp.JSON(&out); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
+// --- Envelope-aware ---
+
+// GetEnvelope performs a GET request against an apikit service and decodes
+// the "data" field of its response.Envelope into T. An error envelope is
+// returned as an *errors.Error; see DecodeTypedEnvelope.
+//
+//	user, err := httpclient.GetEnvelope[User](ctx, client, "/users/42")
+//	if errors.Is(err, errors.ErrNotFound) { ... }
+func GetEnvelope[T any](ctx context.Context, c HTTPClient, path string) (T, error) {
+	return DecodeEnvelope[T](c.Get(ctx, path))
+}
+
+// PostEnvelope performs a POST request with a JSON body against an apikit
+// service and decodes the envelope's "data" field into Out.
+func PostEnvelope[In, Out any](ctx context.Context, c HTTPClient, path string, body In) (Out, error) {
+	return DecodeEnvelope[Out](c.Post(ctx, path, body))
+}
+
+// PutEnvelope performs a PUT request with a JSON body against an apikit
+// service and decodes the envelope's "data" field into Out.
+func PutEnvelope[In, Out any](ctx context.Context, c HTTPClient, path string, body In) (Out, error) {
+	return DecodeEnvelope[Out](c.Put(ctx, path, body))
+}
+
+// PatchEnvelope performs a PATCH request with a JSON body against an apikit
+// service and decodes the envelope's "data" field into Out.
+func PatchEnvelope[In, Out any](ctx context.Context, c HTTPClient, path string, body In) (Out, error) {
+	return DecodeEnvelope[Out](c.Patch(ctx, path, body))
+}
+
+// DeleteEnvelope performs a DELETE request against an apikit service and
+// decodes the envelope's "data" field into T.
+func DeleteEnvelope[T any](ctx context.Context, c HTTPClient, path string) (T, error) {
+	return DecodeEnvelope[T](c.Delete(ctx, path))
+}
+
+// DecodeEnvelope decodes the "data" field of a response.Envelope into T.
+// Like DecodeJSON, it accepts a request's results directly.
+func DecodeEnvelope[T any](resp *Response, err error) (T, error) {
+	env, err := DecodeTypedEnvelope[T](resp, err)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return env.Data, nil
+}
+
+// DecodeTypedEnvelope decodes a response.Envelope with its data typed as T,
+// keeping the message and meta (such as pagination) available.
+//
+// An envelope carrying an "error" object, on any status, is converted to an
+// *errors.Error with the response's status code and the envelope's code,
+// message, fields, and details, so errors.Is matches the same sentinels the
+// remote service used. The underlying *HTTPError, if any, is wrapped. A
+// non-2xx response without an error envelope is reported as an *HTTPError.
+func DecodeTypedEnvelope[T any](resp *Response, err error) (*response.TypedEnvelope[T], error) {
+	if resp == nil {
+		if err == nil {
+			err = stderrors.New("httpclient: no response")
+		}
+		return nil, err
+	}
+
+	if apiErr := envelopeError(resp, err); apiErr != nil {
+		return nil, apiErr
+	}
+	if err = statusError(resp, err); err != nil {
+		return nil, err
+	}
+
+	var env response.TypedEnvelope[T]
+	if err := json.Unmarshal(resp.Body, &env); err != nil {
+		return nil, fmt.Errorf("failed to decode response envelope: %w", err)
+	}
+	return &env, nil
+}
+
+// statusError returns the request error, or an *HTTPError for a non-2xx
+// response that was returned without one (error-on-status disabled).
+func statusError(resp *Response, err error) error {
+	if err != nil {
+		return err
+	}
+	if resp == nil {
+		return stderrors.New("httpclient: no response")
+	}
+	if resp.StatusCode >= 400 {
+		return &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status, Body: resp.Body}
+	}
+	return nil
+}
+
+// envelopeError converts an error envelope in resp into an *errors.Error,
+// returning nil if the body isn't one.
+func envelopeError(resp *Response, err error) *errors.Error {
+	if len(resp.Body) == 0 {
+		return nil
+	}
+	var env struct {
+		Error *response.ErrorBody `json:"error"`
+	}
+	if json.Unmarshal(resp.Body, &env) != nil || env.Error == nil || env.Error.Code == "" {
+		return nil
+	}
+	if err == nil && resp.StatusCode >= 400 {
+		err = &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status, Body: resp.Body}
+	}
+	return &errors.Error{
+		StatusCode: resp.StatusCode,
+		Code:       env.Error.Code,
+		Message:    env.Error.Message,
+		Fields:     env.Error.Fields,
+		Details:    env.Error.Details,
+		Err:        err,
+	}
+}
@@ -0,0 +1,124 @@
+package httpclient
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"testing"
+
+	"github.com/KARTIKrocks/apikit/errors"
+	"github.com/KARTIKrocks/apikit/response"
+)
+
+type user struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestGetJSON_PostJSON(t *testing.T) {
+	t.Parallel()
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":2,"name":"bob"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"id":1,"name":"alice"}`))
+	})
+	t.Cleanup(ts.Close)
+
+	got, err := GetJSON[user](context.Background(), c, "/users/1")
+	if err != nil || got != (user{1, "alice"}) {
+		t.Fatalf("GetJSON = %+v, %v", got, err)
+	}
+
+	created, err := PostJSON[user, *user](context.Background(), c, "/users", user{Name: "bob"})
+	if err != nil || created == nil || created.ID != 2 {
+		t.Fatalf("PostJSON = %+v, %v", created, err)
+	}
+}
+
+func TestDecodeJSON_StatusErrorWithoutErrorOnStatus(t *testing.T) {
+	t.Parallel()
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"id":0}`))
+	}, WithMaxRetries(0))
+	t.Cleanup(ts.Close)
+
+	_, err := DecodeJSON[user](c.Request().Path("/x").ErrorOnStatus(false).Get(context.Background()))
+	var he *HTTPError
+	if !stderrors.As(err, &he) || he.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected *HTTPError 404, got %v", err)
+	}
+}
+
+func TestGetEnvelope_DecodesData(t *testing.T) {
+	t.Parallel()
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		response.OK(w, "found", user{ID: 1, Name: "alice"})
+	})
+	t.Cleanup(ts.Close)
+
+	got, err := GetEnvelope[user](context.Background(), c, "/users/1")
+	if err != nil || got != (user{1, "alice"}) {
+		t.Fatalf("GetEnvelope = %+v, %v", got, err)
+	}
+
+	env, err := DecodeTypedEnvelope[user](c.Get(context.Background(), "/users/1"))
+	if err != nil || !env.Success || env.Message != "found" || env.Data.Name != "alice" {
+		t.Fatalf("DecodeTypedEnvelope = %+v, %v", env, err)
+	}
+}
+
+func TestGetEnvelope_ConvertsErrorEnvelope(t *testing.T) {
+	t.Parallel()
+	ts, c := newTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/missing":
+			response.Err(w, errors.NotFound("User"))
+		case "/invalid":
+			response.Err(w, errors.Validation("invalid input", map[string]string{"email": "is required"}).
+				WithDetail("hint", "check the docs"))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte("upstream down"))
+		}
+	}, WithMaxRetries(0))
+	t.Cleanup(ts.Close)
+
+	_, err := GetEnvelope[user](context.Background(), c, "/missing")
+	if !stderrors.Is(err, errors.ErrNotFound) {
+		t.Fatalf("expected errors.ErrNotFound, got %v", err)
+	}
+	var he *HTTPError
+	if !stderrors.As(err, &he) || he.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected the *HTTPError to stay reachable, got %v", err)
+	}
+
+	_, err = PostEnvelope[user, user](context.Background(), c, "/invalid", user{})
+	var apiErr *errors.Error
+	if !stderrors.As(err, &apiErr) {
+		t.Fatalf("expected *errors.Error, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusUnprocessableEntity || apiErr.Code != errors.CodeValidation ||
+		apiErr.Fields["email"] != "is required" || apiErr.Details["hint"] != "check the docs" {
+		t.Fatalf("unexpected error %+v", apiErr)
+	}
+
+	// A non-envelope error body is reported as a plain status error.
+	_, err = GetEnvelope[user](context.Background(), c, "/proxy")
+	if stderrors.As(err, &apiErr) || !stderrors.As(err, &he) || he.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected *HTTPError 502, got %v", err)
+	}
+}
+
+func TestGetEnvelope_WithMockClient(t *testing.T) {
+	mock := NewMockClient().OnGet("/users/1", 200, []byte(`{"success":true,"data":{"id":1,"name":"alice"}}`))
+
+	got, err := GetEnvelope[user](context.Background(), mock, "/users/1")
+	if err != nil || got.Name != "alice" {
+		t.Fatalf("GetEnvelope = %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,451 @@
+// Package vcr records real HTTP interactions to cassette files and replays
+// them offline, so integration tests against third-party APIs run in CI
+// without network access. A Recorder is an http.RoundTripper for use with
+// httpclient.WithTransport:
+//
+//	rec, err := vcr.New(vcr.Config{
+//	    Path: "testdata/stripe_charge.json",
+//	    Mode: vcr.ModeReplayOrRecord,
+//	})
+//	if err != nil {
+//	    t.Fatal(err)
+//	}
+//	client := httpclient.New("https://api.stripe.com", httpclient.WithTransport(rec))
+//
+// Cassettes are stored as JSON. Secrets are redacted before anything is
+// written: by default the Authorization, Cookie, Set-Cookie,
+// Proxy-Authorization, and X-Api-Key headers; query parameters and JSON body
+// fields can be added through Config.
+package vcr
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrInteractionNotFound is returned in replay mode when no recorded
+// interaction matches a request.
+var ErrInteractionNotFound = errors.New("vcr: no recorded interaction matches request")
+
+// Redacted replaces secret values in cassettes.
+const Redacted = "REDACTED"
+
+// Mode selects whether a Recorder records, replays, or both.
+type Mode int
+
+const (
+	// ModeReplay serves requests from the cassette only; a request with no
+	// matching interaction fails with ErrInteractionNotFound. Repeated
+	// requests replay matches in recorded order, then repeat the last one.
+	// Use it in CI.
+	ModeReplay Mode = iota
+	// ModeRecord sends every request to the real server and records it,
+	// replacing the cassette's previous contents.
+	ModeRecord
+	// ModeReplayOrRecord replays matching interactions and records requests
+	// that have none, including repeats beyond the recorded count.
+	ModeReplayOrRecord
+)
+
+// Config configures a Recorder.
+type Config struct {
+	// Path is the cassette file.
+	Path string
+
+	// Mode selects recording or replay. Default: ModeReplay.
+	Mode Mode
+
+	// Transport performs real requests when recording.
+	// Default: http.DefaultTransport.
+	Transport http.RoundTripper
+
+	// Matcher decides whether a recorded request matches a live one.
+	// Default: DefaultMatcher (method, URL, and body).
+	Matcher Matcher
+
+	// RedactHeaders lists headers whose values are replaced with Redacted in
+	// requests and responses, in addition to the defaults.
+	RedactHeaders []string
+
+	// RedactQuery lists query parameters whose values are redacted.
+	RedactQuery []string
+
+	// RedactJSONFields lists JSON object keys, at any depth, whose values are
+	// redacted in request and response bodies.
+	RedactJSONFields []string
+
+	// BeforeSave, if set, can modify each interaction before it is written,
+	// for redaction the options above don't cover.
+	BeforeSave func(*Interaction)
+}
+
+// defaultRedactHeaders are always redacted.
+var defaultRedactHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization", "X-Api-Key"}
+
+// Cassette is the file format: a list of recorded interactions.
+type Cassette struct {
+	Version      int            `json:"version"`
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Interaction is one recorded request and its response.
+type Interaction struct {
+	Request    Request   `json:"request"`
+	Response   Response  `json:"response"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// Request is a recorded request.
+type Request struct {
+	Method       string      `json:"method"`
+	URL          string      `json:"url"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	StatusCode   int         `json:"status_code"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"`
+}
+
+// Matcher reports whether a recorded request matches a live one. The live
+// request has already been redacted like the recorded one.
+type Matcher func(live, recorded Request) bool
+
+// DefaultMatcher matches on method, URL, and body.
+var DefaultMatcher = MatchAll(MatchMethod, MatchURL, MatchBody)
+
+// MatchMethod matches requests with the same method.
+func MatchMethod(live, recorded Request) bool {
+	return strings.EqualFold(live.Method, recorded.Method)
+}
+
+// MatchURL matches requests with the same URL, ignoring query parameter order.
+func MatchURL(live, recorded Request) bool {
+	lu, err1 := url.Parse(live.URL)
+	ru, err2 := url.Parse(recorded.URL)
+	if err1 != nil || err2 != nil {
+		return live.URL == recorded.URL
+	}
+	return lu.Scheme == ru.Scheme && lu.Host == ru.Host && lu.Path == ru.Path &&
+		reflect.DeepEqual(lu.Query(), ru.Query())
+}
+
+// MatchBody matches requests with the same body. JSON bodies are compared
+// semantically, ignoring key order and whitespace.
+func MatchBody(live, recorded Request) bool {
+	if live.Body == recorded.Body {
+		return true
+	}
+	var lv, rv any
+	if json.Unmarshal([]byte(live.Body), &lv) != nil || json.Unmarshal([]byte(recorded.Body), &rv) != nil {
+		return false
+	}
+	return reflect.DeepEqual(lv, rv)
+}
+
+// MatchAll combines matchers; all must match.
+func MatchAll(matchers ...Matcher) Matcher {
+	return func(live, recorded Request) bool {
+		for _, m := range matchers {
+			if !m(live, recorded) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Recorder is an http.RoundTripper that records and replays interactions.
+// It is safe for concurrent use.
+type Recorder struct {
+	cfg Config
+
+	mu       sync.Mutex
+	cassette *Cassette
+	used     map[*Interaction]bool
+}
+
+// Verify interface compliance at compile time.
+var _ http.RoundTripper = (*Recorder)(nil)
+
+// New creates a Recorder. In ModeReplay the cassette must exist; in
+// ModeReplayOrRecord it is loaded if present.
+func New(cfg Config) (*Recorder, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("vcr: cassette path is required")
+	}
+	if cfg.Transport == nil {
+		cfg.Transport = http.DefaultTransport
+	}
+	if cfg.Matcher == nil {
+		cfg.Matcher = DefaultMatcher
+	}
+	cfg.RedactHeaders = append(append([]string(nil), defaultRedactHeaders...), cfg.RedactHeaders...)
+
+	r := &Recorder{cfg: cfg, cassette: &Cassette{Version: 1}, used: make(map[*Interaction]bool)}
+	if cfg.Mode == ModeRecord {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(cfg.Path)
+	switch {
+	case errors.Is(err, os.ErrNotExist) && cfg.Mode == ModeReplayOrRecord:
+		return r, nil
+	case err != nil:
+		return nil, fmt.Errorf("vcr: load cassette: %w", err)
+	}
+	if err := json.Unmarshal(data, r.cassette); err != nil {
+		return nil, fmt.Errorf("vcr: parse cassette %s: %w", cfg.Path, err)
+	}
+	return r, nil
+}
+
+// Interactions returns the cassette's interactions.
+func (r *Recorder) Interactions() []*Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*Interaction(nil), r.cassette.Interactions...)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		_ = req.Body.Close()
+	}
+	live := r.redactRequest(req, body)
+
+	if r.cfg.Mode != ModeRecord {
+		if it := r.find(live, r.cfg.Mode == ModeReplay); it != nil {
+			return it.Response.httpResponse(req)
+		}
+		if r.cfg.Mode == ModeReplay {
+			return nil, fmt.Errorf("%w: %s %s", ErrInteractionNotFound, live.Method, live.URL)
+		}
+	}
+
+	out := req.Clone(req.Context())
+	if body != nil {
+		out.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	resp, err := r.cfg.Transport.RoundTrip(out)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	it := &Interaction{
+		Request:    live,
+		Response:   r.redactResponse(resp, respBody),
+		RecordedAt: time.Now().UTC(),
+	}
+	if r.cfg.BeforeSave != nil {
+		r.cfg.BeforeSave(it)
+	}
+	if err := r.record(it); err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	resp.ContentLength = int64(len(respBody))
+	return resp, nil
+}
+
+// find returns the first unused matching interaction, so repeated requests
+// replay recorded responses in order. With reuse set it falls back to the last
+// match once all have been used; otherwise a new interaction is recorded.
+func (r *Recorder) find(live Request, reuse bool) *Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var last *Interaction
+	for _, it := range r.cassette.Interactions {
+		if !r.cfg.Matcher(live, it.Request) {
+			continue
+		}
+		if !r.used[it] {
+			r.used[it] = true
+			return it
+		}
+		if reuse {
+			last = it
+		}
+	}
+	return last
+}
+
+// record appends it and writes the cassette.
+func (r *Recorder) record(it *Interaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, it)
+	r.used[it] = true
+
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return fmt.Errorf("vcr: encode cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.cfg.Path), 0o755); err != nil {
+		return fmt.Errorf("vcr: save cassette: %w", err)
+	}
+	// Write atomically so an interrupted test never leaves a corrupt cassette.
+	tmp := r.cfg.Path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("vcr: save cassette: %w", err)
+	}
+	if err := os.Rename(tmp, r.cfg.Path); err != nil {
+		return fmt.Errorf("vcr: save cassette: %w", err)
+	}
+	return nil
+}
+
+// --- Redaction ---
+
+func (r *Recorder) redactRequest(req *http.Request, body []byte) Request {
+	u := *req.URL
+	if len(r.cfg.RedactQuery) > 0 {
+		q := u.Query()
+		for _, name := range r.cfg.RedactQuery {
+			if q.Has(name) {
+				q.Set(name, Redacted)
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+	out := Request{
+		Method: req.Method,
+		URL:    u.String(),
+		Header: r.redactHeader(req.Header),
+	}
+	out.Body, out.BodyEncoding = encodeBody(r.redactJSON(body))
+	return out
+}
+
+func (r *Recorder) redactResponse(resp *http.Response, body []byte) Response {
+	out := Response{
+		StatusCode: resp.StatusCode,
+		Header:     r.redactHeader(resp.Header),
+	}
+	out.Body, out.BodyEncoding = encodeBody(r.redactJSON(body))
+	return out
+}
+
+func (r *Recorder) redactHeader(h http.Header) http.Header {
+	h = h.Clone()
+	for _, name := range r.cfg.RedactHeaders {
+		if vs := h.Values(name); len(vs) > 0 {
+			redacted := make([]string, len(vs))
+			for i := range redacted {
+				redacted[i] = Redacted
+			}
+			h[http.CanonicalHeaderKey(name)] = redacted
+		}
+	}
+	return h
+}
+
+// redactJSON redacts the configured fields in a JSON body. Non-JSON bodies
+// are returned unchanged.
+func (r *Recorder) redactJSON(body []byte) []byte {
+	if len(r.cfg.RedactJSONFields) == 0 || len(body) == 0 {
+		return body
+	}
+	var v any
+	if json.Unmarshal(body, &v) != nil {
+		return body
+	}
+	fields := make(map[string]bool, len(r.cfg.RedactJSONFields))
+	for _, f := range r.cfg.RedactJSONFields {
+		fields[f] = true
+	}
+	if !redactValue(v, fields) {
+		return body
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// redactValue redacts matching keys in place, reporting whether any were found.
+func redactValue(v any, fields map[string]bool) bool {
+	changed := false
+	switch x := v.(type) {
+	case map[string]any:
+		for k, child := range x {
+			if fields[k] {
+				x[k] = Redacted
+				changed = true
+				continue
+			}
+			changed = redactValue(child, fields) || changed
+		}
+	case []any:
+		for _, child := range x {
+			changed = redactValue(child, fields) || changed
+		}
+	}
+	return changed
+}
+
+// --- Bodies ---
+
+// encodeBody stores text bodies as-is and binary bodies as base64.
+func encodeBody(b []byte) (string, string) {
+	if utf8.Valid(b) {
+		return string(b), ""
+	}
+	return base64.StdEncoding.EncodeToString(b), "base64"
+}
+
+func decodeBody(s, encoding string) ([]byte, error) {
+	if encoding == "base64" {
+		return base64.StdEncoding.DecodeString(s)
+	}
+	return []byte(s), nil
+}
+
+// httpResponse rebuilds a response for req.
+func (resp Response) httpResponse(req *http.Request) (*http.Response, error) {
+	body, err := decodeBody(resp.Body, resp.BodyEncoding)
+	if err != nil {
+		return nil, fmt.Errorf("vcr: decode recorded body: %w", err)
+	}
+	return &http.Response{
+		StatusCode:    resp.StatusCode,
+		Status:        fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode)),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        resp.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,184 @@
+package vcr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/KARTIKrocks/apikit/httpclient"
+)
+
+// newClient returns an httpclient.Client that uses rec as its transport.
+func newClient(baseURL string, rec *Recorder) *httpclient.Client {
+	return httpclient.New(baseURL,
+		httpclient.WithTransport(rec),
+		httpclient.WithMaxRetries(0),
+		httpclient.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+}
+
+func TestRecorder_RecordThenReplay(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=abc")
+		fmt.Fprintf(w, `{"n":%d,"echo":%s,"token":"tok_live"}`, n, body)
+	}))
+	t.Cleanup(srv.Close)
+	path := filepath.Join(t.TempDir(), "cassettes", "charge.json")
+	ctx := context.Background()
+
+	rec, err := New(Config{
+		Path:             path,
+		Mode:             ModeRecord,
+		RedactQuery:      []string{"api_key"},
+		RedactJSONFields: []string{"token", "card"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(srv.URL, rec)
+	c.SetBearerToken("sk_live")
+	resp, err := c.Request().Path("/charges").Param("api_key", "k1").Param("amount", "10").
+		Body(map[string]string{"card": "4242"}).Post(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The caller still sees the real response.
+	if !strings.Contains(resp.String(), "tok_live") {
+		t.Fatalf("expected the unredacted live response, got %q", resp.String())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"sk_live", "k1", "4242", "tok_live", "session=abc"} {
+		if strings.Contains(string(data), secret) {
+			t.Fatalf("cassette leaks %q:\n%s", secret, data)
+		}
+	}
+
+	// Replay serves the cassette without touching the server.
+	srv.Close()
+	rec, err = New(Config{
+		Path:             path,
+		RedactQuery:      []string{"api_key"},
+		RedactJSONFields: []string{"token", "card"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = newClient(srv.URL, rec)
+	resp, err = c.Request().Path("/charges").Param("amount", "10").Param("api_key", "other").
+		Body(map[string]string{"card": "5555"}).Post(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(resp.Body, &got); err != nil || got["n"] != float64(1) || got["token"] != Redacted {
+		t.Fatalf("unexpected replayed body %q (%v)", resp.String(), err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected one real request, got %d", calls.Load())
+	}
+
+	_, err = c.Post(ctx, "/charges", map[string]string{"amount": "20"})
+	if !errors.Is(err, ErrInteractionNotFound) {
+		t.Fatalf("expected ErrInteractionNotFound, got %v", err)
+	}
+}
+
+func TestRecorder_ReplayOrRecordAndSequence(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "status-%d", calls.Add(1))
+	}))
+	t.Cleanup(srv.Close)
+	path := filepath.Join(t.TempDir(), "poll.json")
+	ctx := context.Background()
+
+	rec, err := New(Config{Path: path, Mode: ModeReplayOrRecord})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newClient(srv.URL, rec)
+	for range 2 {
+		if _, err := c.Get(ctx, "/job"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls.Load() != 2 || len(rec.Interactions()) != 2 {
+		t.Fatalf("expected two recorded interactions, got %d calls / %d", calls.Load(), len(rec.Interactions()))
+	}
+
+	// Replay serves repeated requests in recorded order, then keeps serving
+	// the last one.
+	rec, err = New(Config{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = newClient(srv.URL, rec)
+	for _, want := range []string{"status-1", "status-2", "status-2"} {
+		resp, err := c.Get(ctx, "/job")
+		if err != nil || resp.String() != want {
+			t.Fatalf("expected %q, got %q (%v)", want, resp.String(), err)
+		}
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("expected replay without network, got %d calls", calls.Load())
+	}
+}
+
+func TestRecorder_BinaryBodyAndMissingCassette(t *testing.T) {
+	payload := []byte{0xff, 0x00, 0xfe, 0x01}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		_, _ = w.Write(payload)
+	}))
+	t.Cleanup(srv.Close)
+	path := filepath.Join(t.TempDir(), "blob.json")
+
+	if _, err := New(Config{Path: path}); err == nil {
+		t.Fatal("expected replay mode to require an existing cassette")
+	}
+
+	rec, _ := New(Config{Path: path, Mode: ModeRecord})
+	if _, err := newClient(srv.URL, rec).Get(context.Background(), "/blob"); err != nil {
+		t.Fatal(err)
+	}
+	rec, err := New(Config{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := newClient(srv.URL, rec).Get(context.Background(), "/blob")
+	if err != nil || string(resp.Body) != string(payload) {
+		t.Fatalf("expected binary body to round-trip, got %v %v", resp.Body, err)
+	}
+}
+
+func TestMatchers(t *testing.T) {
+	a := Request{Method: "POST", URL: "https://x.test/a?b=2&a=1", Body: `{"x":1,"y":[1,2]}`}
+	b := Request{Method: "post", URL: "https://x.test/a?a=1&b=2", Body: "{\"y\": [1,2], \"x\": 1}"}
+	if !DefaultMatcher(a, b) {
+		t.Fatal("expected query order, method case, and JSON formatting to be ignored")
+	}
+	b.Body = `{"x":2,"y":[1,2]}`
+	if DefaultMatcher(a, b) {
+		t.Fatal("expected different bodies not to match")
+	}
+	if !MatchAll(MatchMethod, MatchURL)(a, b) {
+		t.Fatal("expected a custom matcher to ignore the body")
+	}
+}
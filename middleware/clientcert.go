@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/KARTIKrocks/apikit/errors"
+	"github.com/KARTIKrocks/apikit/response"
+)
+
+type clientIdentityKey struct{}
+
+// ClientIdentity describes a client certificate verified during the TLS
+// handshake (mutual TLS).
+type ClientIdentity struct {
+	// Subject is the certificate subject; Subject.CommonName is often the
+	// client or service name.
+	Subject pkix.Name
+
+	// Issuer is the subject of the issuing CA.
+	Issuer pkix.Name
+
+	// Subject alternative names.
+	DNSNames       []string
+	EmailAddresses []string
+	IPAddresses    []net.IP
+	URIs           []*url.URL // e.g. SPIFFE IDs
+
+	// Certificate is the verified leaf certificate.
+	Certificate *x509.Certificate
+}
+
+// ClientCertConfig configures the ClientCert middleware.
+type ClientCertConfig struct {
+	// Required rejects requests without a verified client certificate with
+	// 401 Unauthorized. When false, such requests pass through without an
+	// identity in the context.
+	Required bool
+
+	// Authorize, if set, decides whether an identity may proceed; denied
+	// requests get 403 Forbidden.
+	Authorize func(r *http.Request, id *ClientIdentity) bool
+}
+
+// ClientCert stores the identity of the verified TLS client certificate in
+// the request context, for retrieval with GetClientIdentity. Only
+// certificates that were verified against the server's client CAs are used,
+// so the server must request verification (e.g. server.WithClientCA with
+// tls.VerifyClientCertIfGiven or tls.RequireAndVerifyClientCert).
+//
+//	mw := middleware.ClientCert(middleware.ClientCertConfig{
+//	    Required: true,
+//	    Authorize: func(r *http.Request, id *middleware.ClientIdentity) bool {
+//	        return id.Subject.CommonName == "billing-service"
+//	    },
+//	})
+func ClientCert(cfg ClientCertConfig) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := verifiedIdentity(r)
+			if id == nil {
+				if cfg.Required {
+					response.Err(w, errors.Unauthorized("Client certificate required"))
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			if cfg.Authorize != nil && !cfg.Authorize(r, id) {
+				response.Err(w, errors.Forbidden("Client certificate not authorized"))
+				return
+			}
+			ctx := context.WithValue(r.Context(), clientIdentityKey{}, id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetClientIdentity retrieves the client identity stored by ClientCert.
+func GetClientIdentity(ctx context.Context) (*ClientIdentity, bool) {
+	id, ok := ctx.Value(clientIdentityKey{}).(*ClientIdentity)
+	return id, ok
+}
+
+// verifiedIdentity returns the identity of the verified leaf certificate,
+// or nil if the connection has none.
+func verifiedIdentity(r *http.Request) *ClientIdentity {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	cert := r.TLS.VerifiedChains[0][0]
+	return &ClientIdentity{
+		Subject:        cert.Subject,
+		Issuer:         cert.Issuer,
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		IPAddresses:    cert.IPAddresses,
+		URIs:           cert.URIs,
+		Certificate:    cert,
+	}
+}
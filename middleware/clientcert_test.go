@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientCert(t *testing.T) {
+	leaf := &x509.Certificate{Subject: pkix.Name{CommonName: "billing"}, DNSNames: []string{"billing.internal"}}
+	var seen *ClientIdentity
+	handler := func(cfg ClientCertConfig) http.Handler {
+		return ClientCert(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen, _ = GetClientIdentity(r.Context())
+		}))
+	}
+	request := func(verified bool) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}}
+		if verified {
+			r.TLS.VerifiedChains = [][]*x509.Certificate{{leaf}}
+		}
+		return r
+	}
+
+	rec := httptest.NewRecorder()
+	handler(ClientCertConfig{}).ServeHTTP(rec, request(true))
+	if rec.Code != http.StatusOK || seen == nil || seen.Subject.CommonName != "billing" || seen.DNSNames[0] != "billing.internal" {
+		t.Fatalf("expected the verified identity in context, got %d %+v", rec.Code, seen)
+	}
+
+	// Unverified peer certificates are never trusted.
+	seen = nil
+	rec = httptest.NewRecorder()
+	handler(ClientCertConfig{}).ServeHTTP(rec, request(false))
+	if rec.Code != http.StatusOK || seen != nil {
+		t.Fatalf("expected pass-through without identity, got %d %+v", rec.Code, seen)
+	}
+	rec = httptest.NewRecorder()
+	handler(ClientCertConfig{Required: true}).ServeHTTP(rec, request(false))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rec.Code)
+	}
+
+	deny := ClientCertConfig{Authorize: func(r *http.Request, id *ClientIdentity) bool {
+		return id.Subject.CommonName == "admin"
+	}}
+	rec = httptest.NewRecorder()
+	handler(deny).ServeHTTP(rec, request(true))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rec.Code)
+	}
+}
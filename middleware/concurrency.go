@@ -0,0 +1,347 @@
+package middleware
+
+import (
+	"context"
+	stderrors "errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/KARTIKrocks/apikit/errors"
+	"github.com/KARTIKrocks/apikit/response"
+)
+
+// ErrLimitExceeded is returned by a ConcurrencyLimiter that sheds a request:
+// the bulkhead queue is full, the queue wait timed out, or the adaptive
+// limit is reached.
+var ErrLimitExceeded = stderrors.New("concurrency limit exceeded")
+
+// ConcurrencyLimiter bounds the number of requests in flight. It is shared by
+// the ConcurrencyLimit middleware and httpclient.WithConcurrencyLimiter, so
+// the same limiter types protect inbound and outbound calls.
+type ConcurrencyLimiter interface {
+	// Acquire reserves a slot, blocking if the limiter queues. It returns
+	// ErrLimitExceeded (or the context error) when the request is shed. On
+	// success, release must be called exactly once when the request ends;
+	// dropped reports that it failed because the protected resource was
+	// overloaded (a timeout or 5xx), which adaptive limiters use as a signal.
+	Acquire(ctx context.Context) (release func(dropped bool), err error)
+}
+
+// --- Bulkhead ---
+
+// Bulkhead caps in-flight requests at a fixed number, with a bounded queue of
+// waiters. Requests beyond the queue, or waiting longer than the queue
+// timeout, are rejected with ErrLimitExceeded.
+type Bulkhead struct {
+	slots        chan struct{}
+	queueTimeout time.Duration
+
+	mu       sync.Mutex
+	queued   int
+	maxQueue int
+}
+
+// NewBulkhead creates a bulkhead allowing maxInFlight concurrent requests
+// and up to maxQueue waiting ones for at most queueTimeout (zero waits until
+// the request context is done).
+func NewBulkhead(maxInFlight, maxQueue int, queueTimeout time.Duration) *Bulkhead {
+	if maxInFlight < 1 {
+		maxInFlight = 1
+	}
+	if maxQueue < 0 {
+		maxQueue = 0
+	}
+	return &Bulkhead{
+		slots:        make(chan struct{}, maxInFlight),
+		maxQueue:     maxQueue,
+		queueTimeout: queueTimeout,
+	}
+}
+
+// Acquire implements ConcurrencyLimiter.
+func (b *Bulkhead) Acquire(ctx context.Context) (func(bool), error) {
+	select {
+	case b.slots <- struct{}{}:
+		return b.releaser(), nil
+	default:
+	}
+
+	b.mu.Lock()
+	if b.queued >= b.maxQueue {
+		b.mu.Unlock()
+		return nil, ErrLimitExceeded
+	}
+	b.queued++
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		b.queued--
+		b.mu.Unlock()
+	}()
+
+	var timeout <-chan time.Time
+	if b.queueTimeout > 0 {
+		t := time.NewTimer(b.queueTimeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	select {
+	case b.slots <- struct{}{}:
+		return b.releaser(), nil
+	case <-timeout:
+		return nil, ErrLimitExceeded
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// InFlight returns the number of requests currently holding a slot.
+func (b *Bulkhead) InFlight() int {
+	return len(b.slots)
+}
+
+// releaser returns the release func for an acquired slot. Extra calls are
+// no-ops, so a double release cannot free a slot held by another request.
+func (b *Bulkhead) releaser() func(bool) {
+	var once sync.Once
+	return func(bool) {
+		once.Do(func() { <-b.slots })
+	}
+}
+
+// --- Adaptive limiter ---
+
+// AdaptiveLimiterConfig configures an AdaptiveLimiter.
+type AdaptiveLimiterConfig struct {
+	// InitialLimit is the starting concurrency limit. Default: 20.
+	InitialLimit int
+
+	// MinLimit and MaxLimit bound the limit. Defaults: 1 and 1000.
+	MinLimit int
+	MaxLimit int
+
+	// LatencyTarget is the average latency over a sample window above which
+	// the window counts as congested. Default: 0, meaning Tolerance × the
+	// long-term average latency, so no tuning is needed for typical
+	// workloads.
+	LatencyTarget time.Duration
+
+	// Tolerance multiplies the long-term average latency when LatencyTarget
+	// is zero. Default: 2.
+	Tolerance float64
+
+	// BackoffRatio scales the limit down on congestion. Default: 0.9.
+	BackoffRatio float64
+}
+
+// AdaptiveLimiter adjusts its concurrency limit with AIMD (additive increase,
+// multiplicative decrease) from observed latency. Completed requests are
+// grouped into sample windows of roughly one limit's worth of requests: a
+// window whose average latency stays under the target grows the limit by
+// about one, while a congested window or a dropped request cuts it by
+// BackoffRatio. Requests admitted before a cut are ignored, so the limit
+// drops at most once per round trip rather than once per slow request.
+// Requests above the limit are shed immediately, before queueing drives
+// latency up.
+type AdaptiveLimiter struct {
+	cfg AdaptiveLimiterConfig
+
+	mu        sync.Mutex
+	limit     float64
+	inFlight  int
+	started   uint64        // requests admitted so far
+	recoverAt uint64        // requests admitted up to here predate the last cut
+	baseline  time.Duration // long-term average window latency
+
+	// Current sample window.
+	samples int
+	total   time.Duration
+	peak    int
+}
+
+const (
+	// minWindowSamples is the smallest sample window, so small limits still
+	// average out ordinary latency jitter.
+	minWindowSamples = 10
+
+	// baselineSmoothing is how many windows the long-term latency average
+	// spans, so the baseline follows lasting latency shifts but not bursts.
+	baselineSmoothing = 10
+)
+
+// NewAdaptiveLimiter creates an adaptive limiter from cfg.
+func NewAdaptiveLimiter(cfg AdaptiveLimiterConfig) *AdaptiveLimiter {
+	if cfg.MinLimit < 1 {
+		cfg.MinLimit = 1
+	}
+	if cfg.MaxLimit <= 0 {
+		cfg.MaxLimit = 1000
+	}
+	if cfg.MaxLimit < cfg.MinLimit {
+		cfg.MaxLimit = cfg.MinLimit
+	}
+	if cfg.InitialLimit <= 0 {
+		cfg.InitialLimit = 20
+	}
+	if cfg.Tolerance <= 1 {
+		cfg.Tolerance = 2
+	}
+	if cfg.BackoffRatio <= 0 || cfg.BackoffRatio >= 1 {
+		cfg.BackoffRatio = 0.9
+	}
+	l := &AdaptiveLimiter{cfg: cfg}
+	l.limit = clampLimit(float64(cfg.InitialLimit), cfg)
+	return l
+}
+
+// Acquire implements ConcurrencyLimiter. It never blocks.
+func (l *AdaptiveLimiter) Acquire(ctx context.Context) (func(bool), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	if l.inFlight >= int(l.limit) {
+		l.mu.Unlock()
+		return nil, ErrLimitExceeded
+	}
+	l.inFlight++
+	l.started++
+	seq := l.started
+	l.mu.Unlock()
+
+	start := time.Now()
+	var once sync.Once
+	return func(dropped bool) {
+		once.Do(func() { l.onRelease(seq, time.Since(start), dropped) })
+	}, nil
+}
+
+// Limit returns the current concurrency limit.
+func (l *AdaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// onRelease updates the limit from the completed request admitted as seq.
+func (l *AdaptiveLimiter) onRelease(seq uint64, latency time.Duration, dropped bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	inFlight := l.inFlight
+	l.inFlight--
+
+	if seq <= l.recoverAt {
+		// Admitted under the limit before the last cut; its outcome says
+		// nothing about the current limit.
+		return
+	}
+	if dropped {
+		l.decrease()
+		return
+	}
+
+	l.samples++
+	l.total += latency
+	l.peak = max(l.peak, inFlight)
+	if l.samples < max(int(l.limit), minWindowSamples) {
+		return
+	}
+
+	avg := l.total / time.Duration(l.samples)
+	target := l.cfg.LatencyTarget
+	if target <= 0 {
+		target = time.Duration(float64(l.baseline) * l.cfg.Tolerance)
+	}
+	if l.baseline == 0 {
+		l.baseline = avg
+	} else {
+		l.baseline += (avg - l.baseline) / baselineSmoothing
+	}
+
+	switch {
+	case target > 0 && avg > target:
+		l.decrease()
+		return
+	case float64(l.peak) >= l.limit/2:
+		// Only grow when the limit is actually being exercised; an idle
+		// service says nothing about how much more it can take.
+		l.limit = clampLimit(l.limit+float64(l.samples)/l.limit, l.cfg)
+	}
+	l.resetWindow()
+}
+
+// decrease cuts the limit and starts a new window that ignores requests
+// admitted before the cut.
+func (l *AdaptiveLimiter) decrease() {
+	l.limit = clampLimit(l.limit*l.cfg.BackoffRatio, l.cfg)
+	l.recoverAt = l.started
+	l.resetWindow()
+}
+
+func (l *AdaptiveLimiter) resetWindow() {
+	l.samples, l.total, l.peak = 0, 0, 0
+}
+
+func clampLimit(v float64, cfg AdaptiveLimiterConfig) float64 {
+	return math.Max(float64(cfg.MinLimit), math.Min(v, float64(cfg.MaxLimit)))
+}
+
+// --- Middleware ---
+
+// ConcurrencyLimitConfig configures the ConcurrencyLimit middleware.
+type ConcurrencyLimitConfig struct {
+	// Limiter decides whether a request may proceed.
+	// Default: NewAdaptiveLimiter(AdaptiveLimiterConfig{}).
+	Limiter ConcurrencyLimiter
+
+	// RetryAfter is sent in the Retry-After header on shed requests.
+	// Default: 1 second.
+	RetryAfter time.Duration
+
+	// Message is the error message sent when a request is shed.
+	// Default: "Server is overloaded. Please try again later."
+	Message string
+}
+
+// ConcurrencyLimit caps concurrent requests through the handler with a
+// Bulkhead or AdaptiveLimiter, shedding excess load with 503 Service
+// Unavailable and a Retry-After header. Responses with status 5xx are
+// reported to the limiter as dropped.
+//
+//	mux := middleware.ConcurrencyLimit(middleware.ConcurrencyLimitConfig{
+//	    Limiter: middleware.NewBulkhead(100, 50, 2*time.Second),
+//	})(handler)
+func ConcurrencyLimit(cfg ConcurrencyLimitConfig) Middleware {
+	if cfg.Limiter == nil {
+		cfg.Limiter = NewAdaptiveLimiter(AdaptiveLimiterConfig{})
+	}
+	if cfg.RetryAfter <= 0 {
+		cfg.RetryAfter = time.Second
+	}
+	if cfg.Message == "" {
+		cfg.Message = "Server is overloaded. Please try again later."
+	}
+	retryAfter := strconv.Itoa(int(math.Ceil(cfg.RetryAfter.Seconds())))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			release, err := cfg.Limiter.Acquire(r.Context())
+			if err != nil {
+				w.Header().Set("Retry-After", retryAfter)
+				response.Err(w, errors.ServiceUnavailable(cfg.Message))
+				return
+			}
+
+			rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			defer func() { release(rw.statusCode >= 500) }()
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
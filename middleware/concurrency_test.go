@@ -0,0 +1,253 @@
+package middleware
+
+import (
+	"context"
+	stderrors "errors"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBulkhead_QueuesThenRejects(t *testing.T) {
+	b := NewBulkhead(1, 1, 20*time.Millisecond)
+
+	release, err := b.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One waiter fits in the queue and gets the slot once it is released.
+	got := make(chan error, 1)
+	go func() {
+		r, err := b.Acquire(context.Background())
+		if err == nil {
+			r(false)
+		}
+		got <- err
+	}()
+	time.Sleep(5 * time.Millisecond)
+
+	// The queue is full: the next caller is rejected immediately.
+	if _, err := b.Acquire(context.Background()); !stderrors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("expected ErrLimitExceeded with full queue, got %v", err)
+	}
+
+	release(false)
+	if err := <-got; err != nil {
+		t.Fatalf("expected queued caller to acquire, got %v", err)
+	}
+	if b.InFlight() != 0 {
+		t.Fatalf("expected no requests in flight, got %d", b.InFlight())
+	}
+}
+
+func TestBulkhead_DoubleReleaseIsNoop(t *testing.T) {
+	b := NewBulkhead(2, 0, 0)
+
+	release, err := b.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := b.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other(false)
+
+	release(false)
+	done := make(chan struct{})
+	go func() {
+		release(false) // must neither free the other slot nor block
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("second release blocked")
+	}
+	if b.InFlight() != 1 {
+		t.Fatalf("expected the other request to keep its slot, got %d in flight", b.InFlight())
+	}
+}
+
+func TestBulkhead_QueueTimeout(t *testing.T) {
+	b := NewBulkhead(1, 5, 10*time.Millisecond)
+	release, _ := b.Acquire(context.Background())
+	defer release(false)
+
+	start := time.Now()
+	if _, err := b.Acquire(context.Background()); !stderrors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("expected ErrLimitExceeded after queue timeout, got %v", err)
+	}
+	if time.Since(start) < 10*time.Millisecond {
+		t.Fatal("expected caller to wait for the queue timeout")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := b.Acquire(ctx); !stderrors.Is(err, context.Canceled) {
+		t.Fatalf("expected context error, got %v", err)
+	}
+}
+
+func TestAdaptiveLimiter_ShedsAboveLimit(t *testing.T) {
+	l := NewAdaptiveLimiter(AdaptiveLimiterConfig{InitialLimit: 2})
+
+	r1, _ := l.Acquire(context.Background())
+	r2, _ := l.Acquire(context.Background())
+	if _, err := l.Acquire(context.Background()); !stderrors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("expected ErrLimitExceeded at the limit, got %v", err)
+	}
+	r1(false)
+	r2(false)
+	r2(false) // release is idempotent
+	if _, err := l.Acquire(context.Background()); err != nil {
+		t.Fatalf("expected a slot after release, got %v", err)
+	}
+}
+
+func TestAdaptiveLimiter_AIMD(t *testing.T) {
+	l := NewAdaptiveLimiter(AdaptiveLimiterConfig{
+		InitialLimit:  10,
+		MinLimit:      2,
+		LatencyTarget: time.Second,
+	})
+
+	// Drops cut the limit multiplicatively, down to MinLimit.
+	for range 50 {
+		release, err := l.Acquire(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		release(true)
+	}
+	if l.Limit() != 2 {
+		t.Fatalf("expected limit to fall to MinLimit, got %d", l.Limit())
+	}
+
+	// Fast requests with the limit in use grow it back additively.
+	for range 100 {
+		r1, _ := l.Acquire(context.Background())
+		r2, _ := l.Acquire(context.Background())
+		r1(false)
+		r2(false)
+	}
+	if got := l.Limit(); got <= 2 || got > 20 {
+		t.Fatalf("expected limit to grow gradually, got %d", got)
+	}
+}
+
+func TestAdaptiveLimiter_ToleratesLatencyJitter(t *testing.T) {
+	l := NewAdaptiveLimiter(AdaptiveLimiterConfig{})
+
+	// Healthy, load-independent latency: mostly 1-4ms with a 5% tail up to
+	// 12ms. None of it is congestion, so the limit must not collapse.
+	const workers, perWorker = 10, 150
+	var shed atomic.Int64
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range perWorker {
+				release, err := l.Acquire(context.Background())
+				if err != nil {
+					shed.Add(1)
+					time.Sleep(time.Millisecond)
+					continue
+				}
+				latency := time.Millisecond + rand.N(3*time.Millisecond)
+				if rand.IntN(20) == 0 {
+					latency = 8*time.Millisecond + rand.N(4*time.Millisecond)
+				}
+				time.Sleep(latency)
+				release(false)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := l.Limit(); got < workers {
+		t.Fatalf("expected the limit to stay at or above the offered concurrency %d, got %d", workers, got)
+	}
+	if n := shed.Load(); n > workers*perWorker/100 {
+		t.Fatalf("expected almost no healthy requests shed, got %d of %d", n, workers*perWorker)
+	}
+}
+
+func TestAdaptiveLimiter_BacksOffOncePerWindow(t *testing.T) {
+	l := NewAdaptiveLimiter(AdaptiveLimiterConfig{InitialLimit: 20})
+
+	run := func(n int, latency time.Duration) {
+		for range n {
+			release, err := l.Acquire(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(latency)
+			release(false)
+		}
+	}
+
+	// Establish a 1ms baseline over a few windows of 20 samples.
+	run(60, time.Millisecond)
+	if l.Limit() != 20 {
+		t.Fatalf("expected an unexercised limit to stay at 20, got %d", l.Limit())
+	}
+
+	// Two windows of sustained 10ms latency cut the limit twice, not once
+	// per slow request.
+	run(38, 10*time.Millisecond)
+	if got := l.Limit(); got != 16 {
+		t.Fatalf("expected two cuts to 16, got %d", got)
+	}
+}
+
+func TestConcurrencyLimit_Middleware(t *testing.T) {
+	block := make(chan struct{})
+	entered := make(chan struct{})
+	handler := ConcurrencyLimit(ConcurrencyLimitConfig{
+		Limiter:    NewBulkhead(1, 0, 0),
+		RetryAfter: 2 * time.Second,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-block
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}()
+	<-entered
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "2" {
+		t.Fatalf("expected Retry-After 2, got %q", w.Header().Get("Retry-After"))
+	}
+
+	close(block)
+	wg.Wait()
+}
+
+func TestConcurrencyLimit_ReportsServerErrorsAsDropped(t *testing.T) {
+	l := NewAdaptiveLimiter(AdaptiveLimiterConfig{InitialLimit: 10, LatencyTarget: time.Minute})
+	handler := ConcurrencyLimit(ConcurrencyLimitConfig{Limiter: l})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if l.Limit() != 9 {
+		t.Fatalf("expected the 500 to reduce the limit to 9, got %d", l.Limit())
+	}
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KARTIKrocks/apikit/errors"
+	"github.com/KARTIKrocks/apikit/response"
+)
+
+// RequestTimeoutHeader carries the caller's remaining deadline, in whole
+// milliseconds, so it can be honoured by the next service in a call chain.
+// httpclient.PropagateDeadline sets it; the Deadline middleware reads it.
+const RequestTimeoutHeader = "X-Request-Timeout"
+
+// DeadlineConfig configures the Deadline middleware.
+type DeadlineConfig struct {
+	// Header is the request header carrying the remaining time.
+	// Default: RequestTimeoutHeader.
+	Header string
+
+	// Max caps the timeout a caller can request. Zero means no cap.
+	Max time.Duration
+
+	// Default is applied when the header is absent or invalid.
+	// Zero means no deadline is added.
+	Default time.Duration
+}
+
+// Deadline tightens the request context to the deadline propagated by the
+// caller in the X-Request-Timeout header, so work on behalf of a caller that
+// has already given up is cancelled. The context deadline only ever moves
+// earlier; an existing tighter deadline is kept. A request whose propagated
+// deadline has already passed is rejected with 504 Gateway Timeout without
+// reaching the handler.
+//
+// Unlike Timeout, Deadline does not write a response when the deadline fires;
+// it relies on handlers and downstream calls observing ctx.Done().
+//
+//	handler = middleware.Deadline(middleware.DeadlineConfig{Max: 30 * time.Second})(handler)
+func Deadline(cfg DeadlineConfig) Middleware {
+	if cfg.Header == "" {
+		cfg.Header = RequestTimeoutHeader
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout, ok := ParseRequestTimeout(r.Header.Get(cfg.Header))
+			if !ok {
+				timeout = cfg.Default
+			} else if timeout <= 0 {
+				response.Err(w, errors.Timeout("Request deadline exceeded"))
+				return
+			}
+			if cfg.Max > 0 && (timeout <= 0 || timeout > cfg.Max) {
+				timeout = cfg.Max
+			}
+			if timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// FormatRequestTimeout formats d for the X-Request-Timeout header. Partial
+// milliseconds are dropped so the receiver never gets more time than the
+// caller has left; negative durations format as "0".
+func FormatRequestTimeout(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	return strconv.FormatInt(d.Milliseconds(), 10)
+}
+
+// ParseRequestTimeout parses an X-Request-Timeout value: whole milliseconds,
+// or a Go duration string such as "1.5s". It reports false for empty or
+// malformed values.
+func ParseRequestTimeout(v string) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
+	}
+	if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+		if ms < 0 || ms > int64(math.MaxInt64/time.Millisecond) {
+			return 0, false
+		}
+		return time.Duration(ms) * time.Millisecond, true
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, false
+	}
+	return d, true
+}
@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// deadlineProbe records the time remaining on the request context.
+func deadlineProbe(remaining *time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*remaining = -1
+		if d, ok := r.Context().Deadline(); ok {
+			*remaining = time.Until(d)
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func TestDeadline_TightensContext(t *testing.T) {
+	var remaining time.Duration
+	handler := Deadline(DeadlineConfig{})(deadlineProbe(&remaining))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(RequestTimeoutHeader, "1500")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if remaining <= time.Second || remaining > 1500*time.Millisecond {
+		t.Fatalf("expected ~1.5s deadline, got %v", remaining)
+	}
+
+	// No header and no default: the context is left alone.
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if remaining != -1 {
+		t.Fatalf("expected no deadline, got %v", remaining)
+	}
+}
+
+func TestDeadline_MaxAndDefault(t *testing.T) {
+	var remaining time.Duration
+	handler := Deadline(DeadlineConfig{Max: time.Second, Default: 200 * time.Millisecond})(deadlineProbe(&remaining))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(RequestTimeoutHeader, "1h")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if remaining <= 500*time.Millisecond || remaining > time.Second {
+		t.Fatalf("expected deadline capped at 1s, got %v", remaining)
+	}
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(RequestTimeoutHeader, "soon")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if remaining <= 0 || remaining > 200*time.Millisecond {
+		t.Fatalf("expected default deadline for an invalid header, got %v", remaining)
+	}
+}
+
+func TestDeadline_ExpiredRejected(t *testing.T) {
+	called := false
+	handler := Deadline(DeadlineConfig{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(RequestTimeoutHeader, "0")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if called || w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected 504 without calling the handler, got %d (called=%v)", w.Code, called)
+	}
+}
+
+func TestParseRequestTimeout(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"250", 250 * time.Millisecond, true},
+		{"1.5s", 1500 * time.Millisecond, true},
+		{" 0 ", 0, true},
+		{"", 0, false},
+		{"-5", 0, false},
+		{"abc", 0, false},
+		{"99999999999999999", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseRequestTimeout(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseRequestTimeout(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+	if FormatRequestTimeout(1999*time.Microsecond) != "1" || FormatRequestTimeout(-time.Second) != "0" {
+		t.Error("unexpected FormatRequestTimeout output")
+	}
+}
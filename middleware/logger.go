@@ -17,6 +17,10 @@ type LoggerConfig struct {
 	// Level is the default log level for successful requests. Default: slog.LevelInfo
 	Level slog.Level
 
+	// MinLevel, if set, drops request logs below its current level. Pass the
+	// *slog.LevelVar given to server.AdminConfig to change it at runtime.
+	MinLevel slog.Leveler
+
 	// SkipPaths is a set of paths to skip logging for (e.g., health checks).
 	SkipPaths map[string]bool
 
@@ -69,6 +73,18 @@ func LoggerWithConfig(cfg LoggerConfig) Middleware {
 
 			duration := time.Since(start)
 
+			// Log at appropriate level based on status code
+			level := cfg.Level
+			switch {
+			case rw.statusCode >= 500:
+				level = slog.LevelError
+			case rw.statusCode >= 400:
+				level = slog.LevelWarn
+			}
+			if cfg.MinLevel != nil && level < cfg.MinLevel.Level() {
+				return
+			}
+
 			attrs := []any{
 				slog.String("method", r.Method),
 				slog.String("path", r.URL.Path),
@@ -92,15 +108,7 @@ func LoggerWithConfig(cfg LoggerConfig) Middleware {
 				slog.String("user_agent", r.UserAgent()),
 			)
 
-			// Log at appropriate level based on status code
-			switch {
-			case rw.statusCode >= 500:
-				logger.Error("HTTP request", attrs...)
-			case rw.statusCode >= 400:
-				logger.Warn("HTTP request", attrs...)
-			default:
-				logger.Log(r.Context(), cfg.Level, "HTTP request", attrs...)
-			}
+			logger.Log(r.Context(), level, "HTTP request", attrs...)
 		})
 	}
 }
@@ -140,6 +140,40 @@ func TestLogger_ErrorLevel(t *testing.T) {
 	}
 }
 
+func TestLogger_MinLevel(t *testing.T) {
+	var buf strings.Builder
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	var level slog.LevelVar
+	level.Set(slog.LevelWarn)
+
+	cfg := DefaultLoggerConfig()
+	cfg.Logger = logger
+	cfg.MinLevel = &level
+	status := http.StatusOK
+	handler := LoggerWithConfig(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if buf.Len() > 0 {
+		t.Errorf("2xx should be dropped below MinLevel, got %q", buf.String())
+	}
+
+	status = http.StatusNotFound
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if !strings.Contains(buf.String(), "WARN") {
+		t.Error("4xx should be logged at MinLevel")
+	}
+
+	buf.Reset()
+	level.Set(slog.LevelInfo)
+	status = http.StatusOK
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if !strings.Contains(buf.String(), "HTTP request") {
+		t.Error("lowering MinLevel should log 2xx again")
+	}
+}
+
 func TestResponseWriter_WriteDefaultsStatus(t *testing.T) {
 	rw := &responseWriter{ResponseWriter: httptest.NewRecorder(), statusCode: http.StatusOK}
 
@@ -0,0 +1,245 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/KARTIKrocks/apikit/errors"
+	"github.com/KARTIKrocks/apikit/health"
+	"github.com/KARTIKrocks/apikit/middleware"
+	"github.com/KARTIKrocks/apikit/request"
+	"github.com/KARTIKrocks/apikit/response"
+	"github.com/KARTIKrocks/apikit/router"
+)
+
+// EndpointAdmin is the endpoint name of the admin listener in Addrs.
+const EndpointAdmin = "admin"
+
+// AdminConfig configures the admin listener added by WithAdmin.
+type AdminConfig struct {
+	// Auth protects every admin endpoint, e.g. middleware.Auth with an API
+	// key. Without it the endpoints are open, so bind the listener to a
+	// loopback or private address.
+	Auth middleware.Middleware
+
+	// LogLevel enables GET and PUT /loglevel. Use the same LevelVar for the
+	// logger's handler (slog.HandlerOptions.Level) and
+	// middleware.LoggerConfig.MinLevel so changes apply everywhere.
+	LogLevel *slog.LevelVar
+
+	// Router, if set, is listed by GET /debug/routes.
+	Router *router.Router
+
+	// Health serves GET /health and /health/live. Default: the checker set
+	// by WithHealthChecker, if any.
+	Health *health.Checker
+}
+
+// WithAdmin adds a separate listener on addr for operating the service
+// without redeploying it:
+//
+//	GET  /debug/pprof/   net/http/pprof profiles
+//	GET  /debug/runtime  goroutines, memory, GC, and connection stats
+//	GET  /debug/build    module versions and VCS settings from the binary
+//	GET  /debug/routes   the route table (AdminConfig.Router)
+//	GET  /health         health checks (AdminConfig.Health)
+//	GET  /loglevel       the current log level (AdminConfig.LogLevel)
+//	PUT  /loglevel       change it: {"level": "debug"}
+//
+// The admin listener shares the server's lifecycle but not its handler or
+// write timeout, so long CPU profiles and traces complete.
+func WithAdmin(addr string, cfg AdminConfig) Option {
+	return func(s *Server) {
+		s.endpoints = append(s.endpoints, endpointSpec{kind: kindAdmin, addr: addr, admin: &cfg})
+	}
+}
+
+// processStart approximates the process start time for uptime reporting.
+var processStart = time.Now()
+
+// adminHandler returns the handler for the admin listener.
+func (s *Server) adminHandler(cfg AdminConfig) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /debug/pprof/", pprof.Index)
+	mux.HandleFunc("GET /debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("GET /debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("GET /debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("POST /debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("GET /debug/pprof/trace", pprof.Trace)
+
+	mux.HandleFunc("GET /debug/runtime", func(w http.ResponseWriter, _ *http.Request) {
+		response.OK(w, "Runtime stats", s.runtimeStats())
+	})
+	mux.HandleFunc("GET /debug/build", response.Handle(func(w http.ResponseWriter, _ *http.Request) error {
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return errors.NotFound("Build info")
+		}
+		response.OK(w, "Build info", newBuildInfo(info))
+		return nil
+	}))
+
+	if cfg.Router != nil {
+		mux.HandleFunc("GET /debug/routes", func(w http.ResponseWriter, _ *http.Request) {
+			response.OK(w, "Routes", cfg.Router.Routes())
+		})
+	}
+
+	checker := cfg.Health
+	if checker == nil {
+		checker = s.health
+	}
+	if checker != nil {
+		mux.HandleFunc("GET /health", response.Handle(checker.Handler()))
+		mux.HandleFunc("GET /health/live", response.Handle(checker.LiveHandler()))
+	}
+
+	if lv := cfg.LogLevel; lv != nil {
+		mux.HandleFunc("GET /loglevel", func(w http.ResponseWriter, _ *http.Request) {
+			response.OK(w, "Log level", logLevel{Level: lv.Level().String()})
+		})
+		mux.HandleFunc("PUT /loglevel", response.Handle(func(w http.ResponseWriter, r *http.Request) error {
+			var body logLevel
+			if err := request.DecodeJSON(r, &body); err != nil {
+				return err
+			}
+			var level slog.Level
+			if err := level.UnmarshalText([]byte(body.Level)); err != nil {
+				return errors.Validation("Invalid log level", map[string]string{
+					"level": "must be debug, info, warn, or error, optionally with an offset such as warn+2",
+				})
+			}
+			prev := lv.Level()
+			lv.Set(level)
+			s.logger.Info("log level changed", "from", prev.String(), "to", level.String())
+			response.OK(w, "Log level updated", logLevel{Level: level.String()})
+			return nil
+		}))
+	}
+
+	if cfg.Auth != nil {
+		return cfg.Auth(mux)
+	}
+	return mux
+}
+
+// logLevel is the body of the /loglevel endpoints.
+type logLevel struct {
+	Level string `json:"level"`
+}
+
+// runtimeStats is the body of GET /debug/runtime.
+type runtimeStats struct {
+	GoVersion     string    `json:"go_version"`
+	UptimeSeconds int64     `json:"uptime_seconds"`
+	Goroutines    int       `json:"goroutines"`
+	GOMAXPROCS    int       `json:"gomaxprocs"`
+	NumCPU        int       `json:"num_cpu"`
+	Memory        memStats  `json:"memory"`
+	GC            gcStats   `json:"gc"`
+	Connections   ConnStats `json:"connections"`
+}
+
+type memStats struct {
+	HeapAlloc   uint64 `json:"heap_alloc"`
+	HeapInuse   uint64 `json:"heap_inuse"`
+	HeapObjects uint64 `json:"heap_objects"`
+	StackInuse  uint64 `json:"stack_inuse"`
+	Sys         uint64 `json:"sys"`
+	TotalAlloc  uint64 `json:"total_alloc"`
+	Mallocs     uint64 `json:"mallocs"`
+	Frees       uint64 `json:"frees"`
+}
+
+type gcStats struct {
+	NumGC        uint32     `json:"num_gc"`
+	NextGC       uint64     `json:"next_gc"`
+	LastGC       *time.Time `json:"last_gc,omitempty"`
+	PauseTotalNs uint64     `json:"pause_total_ns"`
+	LastPauseNs  uint64     `json:"last_pause_ns"`
+	CPUFraction  float64    `json:"cpu_fraction"`
+	MemoryLimit  int64      `json:"memory_limit"`
+}
+
+func (s *Server) runtimeStats() runtimeStats {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	gc := gcStats{
+		NumGC:        m.NumGC,
+		NextGC:       m.NextGC,
+		PauseTotalNs: m.PauseTotalNs,
+		LastPauseNs:  m.PauseNs[(m.NumGC+255)%256],
+		CPUFraction:  m.GCCPUFraction,
+		MemoryLimit:  debug.SetMemoryLimit(-1), // a negative limit only reads it
+	}
+	if m.LastGC > 0 {
+		last := time.Unix(0, int64(m.LastGC)).UTC()
+		gc.LastGC = &last
+	}
+
+	return runtimeStats{
+		GoVersion:     runtime.Version(),
+		UptimeSeconds: int64(time.Since(processStart).Seconds()),
+		Goroutines:    runtime.NumGoroutine(),
+		GOMAXPROCS:    runtime.GOMAXPROCS(0),
+		NumCPU:        runtime.NumCPU(),
+		Memory: memStats{
+			HeapAlloc:   m.HeapAlloc,
+			HeapInuse:   m.HeapInuse,
+			HeapObjects: m.HeapObjects,
+			StackInuse:  m.StackInuse,
+			Sys:         m.Sys,
+			TotalAlloc:  m.TotalAlloc,
+			Mallocs:     m.Mallocs,
+			Frees:       m.Frees,
+		},
+		GC:          gc,
+		Connections: s.Connections(),
+	}
+}
+
+// buildInfo is the body of GET /debug/build.
+type buildInfo struct {
+	GoVersion string            `json:"go_version"`
+	Path      string            `json:"path"`
+	Main      buildModule       `json:"main"`
+	Settings  map[string]string `json:"settings"`
+	Deps      []buildModule     `json:"deps"`
+}
+
+type buildModule struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	Sum     string `json:"sum,omitempty"`
+}
+
+func newBuildInfo(info *debug.BuildInfo) buildInfo {
+	b := buildInfo{
+		GoVersion: info.GoVersion,
+		Path:      info.Path,
+		Main:      buildModule{Path: info.Main.Path, Version: info.Main.Version, Sum: info.Main.Sum},
+		Settings:  make(map[string]string, len(info.Settings)),
+		Deps:      make([]buildModule, 0, len(info.Deps)),
+	}
+	for _, st := range info.Settings {
+		// Linker and compiler flags can embed secrets; keep only build metadata.
+		if strings.HasPrefix(st.Key, "-") {
+			continue
+		}
+		b.Settings[st.Key] = st.Value
+	}
+	for _, d := range info.Deps {
+		if d.Replace != nil {
+			d = d.Replace
+		}
+		b.Deps = append(b.Deps, buildModule{Path: d.Path, Version: d.Version, Sum: d.Sum})
+	}
+	return b
+}
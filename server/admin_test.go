@@ -0,0 +1,136 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/KARTIKrocks/apikit/middleware"
+	"github.com/KARTIKrocks/apikit/router"
+	"github.com/KARTIKrocks/apikit/server"
+)
+
+func TestServer_Admin(t *testing.T) {
+	t.Parallel()
+	var level slog.LevelVar
+	r := router.New()
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) error { return nil })
+
+	srv := server.New(http.NotFoundHandler(),
+		server.WithAddr("127.0.0.1:0"),
+		server.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		server.WithAdmin("127.0.0.1:0", server.AdminConfig{
+			Auth: middleware.Auth(middleware.AuthConfig{
+				Scheme: "api-key",
+				Authenticate: func(_ context.Context, key string) (any, error) {
+					if key != "secret" {
+						return nil, errors.New("invalid key")
+					}
+					return "admin", nil
+				},
+			}),
+			LogLevel: &level,
+			Router:   r,
+		}),
+	)
+	startServer(t, srv)
+	base := "http://" + srv.Addrs()[server.EndpointAdmin].String()
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	do := func(method, path, body string, out any) int {
+		t.Helper()
+		req, _ := http.NewRequest(method, base+path, strings.NewReader(body))
+		req.Header.Set("X-API-Key", "secret")
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		if out != nil {
+			env := struct{ Data any }{Data: out}
+			if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+				t.Fatalf("%s %s: decode: %v", method, path, err)
+			}
+		}
+		return resp.StatusCode
+	}
+
+	t.Run("auth", func(t *testing.T) {
+		resp, err := client.Get(base + "/debug/runtime")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("status = %d, want 401", resp.StatusCode)
+		}
+	})
+
+	t.Run("loglevel", func(t *testing.T) {
+		var got struct{ Level string }
+		if code := do("PUT", "/loglevel", `{"level":"debug"}`, &got); code != http.StatusOK {
+			t.Fatalf("status = %d", code)
+		}
+		if got.Level != "DEBUG" || level.Level() != slog.LevelDebug {
+			t.Errorf("level = %q (%v), want DEBUG", got.Level, level.Level())
+		}
+		if code := do("PUT", "/loglevel", `{"level":"loud"}`, nil); code != http.StatusUnprocessableEntity && code != http.StatusBadRequest {
+			t.Errorf("invalid level status = %d", code)
+		}
+		got.Level = ""
+		do("GET", "/loglevel", "", &got)
+		if got.Level != "DEBUG" {
+			t.Errorf("GET level = %q", got.Level)
+		}
+	})
+
+	t.Run("runtime", func(t *testing.T) {
+		var got struct {
+			Goroutines int `json:"goroutines"`
+			Memory     struct {
+				HeapAlloc uint64 `json:"heap_alloc"`
+			} `json:"memory"`
+			Connections server.ConnStats `json:"connections"`
+		}
+		if code := do("GET", "/debug/runtime", "", &got); code != http.StatusOK {
+			t.Fatalf("status = %d", code)
+		}
+		if got.Goroutines == 0 || got.Memory.HeapAlloc == 0 || got.Connections.Active == 0 {
+			t.Errorf("unexpected stats: %+v", got)
+		}
+	})
+
+	t.Run("build", func(t *testing.T) {
+		var got struct {
+			GoVersion string `json:"go_version"`
+		}
+		if code := do("GET", "/debug/build", "", &got); code != http.StatusOK {
+			t.Fatalf("status = %d", code)
+		}
+		if !strings.HasPrefix(got.GoVersion, "go") {
+			t.Errorf("go_version = %q", got.GoVersion)
+		}
+	})
+
+	t.Run("routes", func(t *testing.T) {
+		var got []router.RouteInfo
+		do("GET", "/debug/routes", "", &got)
+		if len(got) != 1 || got[0].Pattern != "/users/{id}" {
+			t.Errorf("routes = %+v", got)
+		}
+	})
+
+	t.Run("pprof", func(t *testing.T) {
+		if code := do("GET", "/debug/pprof/goroutine?debug=1", "", nil); code != http.StatusOK {
+			t.Errorf("status = %d", code)
+		}
+	})
+}
@@ -0,0 +1,254 @@
+package server
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/KARTIKrocks/apikit/health"
+)
+
+// WithHealthChecker marks c as draining when graceful shutdown begins, so
+// its readiness handler starts failing before the server stops accepting
+// connections. Combine with WithPreStopDelay.
+func WithHealthChecker(c *health.Checker) Option {
+	return func(s *Server) {
+		s.health = c
+	}
+}
+
+// WithPreStopDelay keeps serving for d after shutdown begins and readiness
+// starts failing, giving load balancers (e.g. Kubernetes endpoints) time to
+// stop routing new traffic before listeners close. A second shutdown signal
+// skips the rest of the delay. The delay does not count against the
+// shutdown timeout.
+func WithPreStopDelay(d time.Duration) Option {
+	return func(s *Server) {
+		s.preStopDelay = d
+	}
+}
+
+type stoppingKey struct{}
+
+// Stopping returns a channel that is closed when the server that received
+// the request stops accepting connections. Long-lived handlers such as SSE
+// streams and WebSockets should select on it and finish, since graceful
+// shutdown waits for them. It returns nil, which blocks forever, for
+// contexts not created by Server.
+//
+//	for {
+//	    select {
+//	    case <-server.Stopping(r.Context()):
+//	        return
+//	    case ev := <-events:
+//	        // ...
+//	    }
+//	}
+func Stopping(ctx context.Context) <-chan struct{} {
+	ch, _ := ctx.Value(stoppingKey{}).(chan struct{})
+	return ch
+}
+
+// ConnStats reports the server's connections.
+type ConnStats struct {
+	// Open is the number of accepted connections not yet closed.
+	Open int64 `json:"open"`
+	// Active connections are serving a request other than a stream.
+	Active int64 `json:"active"`
+	// Streaming connections are serving a request that accepts
+	// text/event-stream, such as an SSE subscription.
+	Streaming int64 `json:"streaming"`
+	// Idle connections are kept alive between requests.
+	Idle int64 `json:"idle"`
+	// Hijacked connections were taken over by a handler, e.g. WebSockets.
+	// Graceful shutdown waits for them to close.
+	Hijacked int64 `json:"hijacked"`
+}
+
+// Connections returns current connection counts across all listeners.
+func (s *Server) Connections() ConnStats {
+	return s.conns.stats()
+}
+
+// connTracker follows connections through http.Server.ConnState, keyed by
+// the connection the server reports. Accepted connections are not wrapped,
+// so type assertions and optimizations such as sendfile keep working.
+type connTracker struct {
+	mu       sync.Mutex
+	conns    map[net.Conn]*connInfo
+	hijacked int // tracked connections in StateHijacked
+	pruneAt  int // hijacked count at which closed ones are pruned
+}
+
+// minHijackedPrune is the smallest number of hijacked connections that
+// triggers pruning the closed ones.
+const minHijackedPrune = 64
+
+// connInfo is a tracked connection's state.
+type connInfo struct {
+	state   http.ConnState
+	streams int // in-flight event-stream requests
+}
+
+// connState is the http.Server.ConnState hook.
+func (t *connTracker) connState(c net.Conn, state http.ConnState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch state {
+	case http.StateNew:
+		if t.conns == nil {
+			t.conns = make(map[net.Conn]*connInfo)
+		}
+		t.conns[c] = &connInfo{state: state}
+	case http.StateClosed:
+		delete(t.conns, c)
+	default:
+		info, ok := t.conns[c]
+		if !ok {
+			return
+		}
+		if state == http.StateHijacked && info.state != http.StateHijacked {
+			t.hijacked++
+			// http.Server never reports hijacked connections closed, so
+			// drop closed ones whenever their number doubles. This keeps
+			// the map bounded for WebSocket servers without a scan per
+			// connection.
+			if t.hijacked >= t.pruneAt {
+				t.pruneHijacked()
+				t.pruneAt = max(2*t.hijacked, minHijackedPrune)
+			}
+		}
+		info.state = state
+	}
+}
+
+// pruneHijacked drops hijacked connections that have been closed. t.mu
+// must be held.
+func (t *connTracker) pruneHijacked() {
+	for c, info := range t.conns {
+		if info.state == http.StateHijacked && connClosed(c) {
+			delete(t.conns, c)
+			t.hijacked--
+		}
+	}
+}
+
+// connContext is the http.Server.ConnContext hook. It records the
+// connection so the handler can find it.
+func (t *connTracker) connContext(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, connKey{}, c)
+}
+
+type connKey struct{}
+
+// handler counts requests for event streams against their connection.
+func (t *connTracker) handler(next http.Handler) http.Handler {
+	if next == nil {
+		next = http.DefaultServeMux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, ok := r.Context().Value(connKey{}).(net.Conn)
+		if !ok || !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		t.addStream(c, 1)
+		defer t.addStream(c, -1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (t *connTracker) addStream(c net.Conn, n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if info, ok := t.conns[c]; ok {
+		info.streams += n
+	}
+}
+
+// stats counts connections by state. http.Server forgets hijacked
+// connections, so they are checked for closure here, as well as when more
+// are hijacked, and dropped once closed.
+func (t *connTracker) stats() ConnStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var st ConnStats
+	for c, info := range t.conns {
+		switch info.state {
+		case http.StateActive:
+			if info.streams > 0 {
+				st.Streaming++
+			} else {
+				st.Active++
+			}
+		case http.StateIdle:
+			st.Idle++
+		case http.StateHijacked:
+			if connClosed(c) {
+				delete(t.conns, c)
+				t.hijacked--
+				continue
+			}
+			st.Hijacked++
+		}
+		st.Open++
+	}
+	return st
+}
+
+// connClosed reports whether a hijacked connection has been closed.
+// Connections that don't expose their file descriptor through syscall.Conn
+// can't be checked and are reported closed.
+func connClosed(c net.Conn) bool {
+	// TLS connections wrap the accepted connection.
+	if nc, ok := c.(interface{ NetConn() net.Conn }); ok {
+		c = nc.NetConn()
+	}
+	sc, ok := c.(syscall.Conn)
+	if !ok {
+		return true
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return true
+	}
+	return rc.Control(func(uintptr) {}) != nil
+}
+
+// preStop fails readiness and waits out the pre-stop delay, returning early
+// if another shutdown signal arrives.
+func (s *Server) preStop(signals <-chan os.Signal) {
+	if s.health != nil {
+		s.health.SetDraining(true)
+	}
+	if s.preStopDelay <= 0 {
+		return
+	}
+	s.logger.Info("draining before shutdown", "delay", s.preStopDelay.String())
+	timer := time.NewTimer(s.preStopDelay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-signals:
+	case <-s.shutdownCh:
+	}
+}
+
+// waitHijacked waits until handlers close hijacked connections or ctx ends.
+func (s *Server) waitHijacked(ctx context.Context) {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for s.conns.stats().Hijacked > 0 {
+		select {
+		case <-ctx.Done():
+			s.logger.Warn("hijacked connections still open", "count", s.conns.stats().Hijacked)
+			return
+		case <-ticker.C:
+		}
+	}
+}
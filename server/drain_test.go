@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"testing"
+)
+
+func TestConnTracker_PrunesClosedHijackedConns(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	dial := func() net.Conn {
+		client, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { client.Close() })
+		c, err := ln.Accept()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	var tr connTracker
+	live := dial()
+	defer live.Close()
+	tr.connState(live, http.StateNew)
+	tr.connState(live, http.StateHijacked)
+
+	// Handlers take over and close many connections, as a WebSocket
+	// server does. http.Server never reports them closed.
+	for range 1000 {
+		c := dial()
+		tr.connState(c, http.StateNew)
+		tr.connState(c, http.StateActive)
+		tr.connState(c, http.StateHijacked)
+		c.Close()
+	}
+
+	tr.mu.Lock()
+	size := len(tr.conns)
+	_, kept := tr.conns[live]
+	tr.mu.Unlock()
+	if size > 2*minHijackedPrune {
+		t.Fatalf("expected closed hijacked connections to be pruned, tracking %d", size)
+	}
+	if !kept {
+		t.Fatal("expected the open hijacked connection to stay tracked")
+	}
+	if st := tr.stats(); st.Hijacked != 1 || st.Open != 1 {
+		t.Fatalf("expected one open hijacked connection, got %+v", st)
+	}
+	if tr.hijacked != 1 {
+		t.Fatalf("expected the hijacked count to match, got %d", tr.hijacked)
+	}
+}
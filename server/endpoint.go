@@ -0,0 +1,250 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Endpoint names reported by Addrs.
+const (
+	EndpointHTTP     = "http"     // primary listener without TLS
+	EndpointHTTPS    = "https"    // primary listener with WithTLS
+	EndpointRedirect = "redirect" // WithHTTPRedirect
+	EndpointUnix     = "unix"     // WithUnixSocket
+	EndpointH2C      = "h2c"      // WithH2C
+)
+
+// endpointKind selects how an additional endpoint serves requests.
+type endpointKind int
+
+const (
+	kindRedirect endpointKind = iota
+	kindUnix
+	kindH2C
+	kindAdmin
+)
+
+// endpointSpec is an additional listener configured by an option.
+type endpointSpec struct {
+	kind  endpointKind
+	addr  string
+	admin *AdminConfig // kindAdmin
+}
+
+// endpoint is a listener and the http.Server that serves it.
+type endpoint struct {
+	name    string
+	network string
+	addr    string
+	certs   *certStore // set when serving TLS
+	srv     *http.Server
+	ln      net.Listener
+}
+
+// WithHTTPRedirect adds a plain HTTP listener on addr that permanently
+// redirects every request to the HTTPS listener. It requires WithTLS.
+//
+// Redirects keep the request's host and use the HTTPS listener's port
+// (omitted when it is 443). Behind NAT or container port mapping, where
+// that port is not the one clients reach, set the public address with
+// WithRedirectTarget.
+func WithHTTPRedirect(addr string) Option {
+	return func(s *Server) {
+		s.endpoints = append(s.endpoints, endpointSpec{kind: kindRedirect, addr: addr})
+	}
+}
+
+// WithRedirectTarget sets the public HTTPS address WithHTTPRedirect sends
+// clients to, as "host", "host:port", or ":port". An empty host keeps the
+// request's host, and port 443 is omitted, so a listener on :8443 published
+// as 443 uses WithRedirectTarget(":443").
+func WithRedirectTarget(hostport string) Option {
+	return func(s *Server) {
+		s.redirectTarget = hostport
+	}
+}
+
+// WithUnixSocket adds a listener on the Unix domain socket at path, serving
+// the same handler over plain HTTP/1.1, e.g. for sidecars on the same host.
+// A stale socket file left by a previous process is removed; if another
+// process is still serving on path, Start fails instead.
+func WithUnixSocket(path string) Option {
+	return func(s *Server) {
+		s.endpoints = append(s.endpoints, endpointSpec{kind: kindUnix, addr: path})
+	}
+}
+
+// WithH2C adds a listener on addr that serves the same handler over
+// cleartext HTTP/2 (h2c) as well as HTTP/1.1, for internal gRPC-style
+// traffic that terminates TLS elsewhere. It requires Go 1.24 or later: when
+// built with Go 1.22 or 1.23, Start returns an error instead of serving.
+func WithH2C(addr string) Option {
+	return func(s *Server) {
+		s.endpoints = append(s.endpoints, endpointSpec{kind: kindH2C, addr: addr})
+	}
+}
+
+// Addrs returns the address of every listener by endpoint name (EndpointHTTP
+// or EndpointHTTPS for the primary listener, EndpointRedirect, EndpointUnix,
+// EndpointH2C, EndpointAdmin). Returns nil if the server has not started yet.
+func (s *Server) Addrs() map[string]net.Addr {
+	v := s.addrs.Load()
+	if v == nil {
+		return nil
+	}
+	addrs := v.(map[string]net.Addr)
+	out := make(map[string]net.Addr, len(addrs))
+	for name, addr := range addrs {
+		out[name] = addr
+	}
+	return out
+}
+
+// buildEndpoints returns the primary endpoint followed by the additional ones
+// in the order their options were given. Additional endpoints copy the
+// primary server's timeouts and handler.
+func (s *Server) buildEndpoints() ([]*endpoint, error) {
+	primary := &endpoint{name: EndpointHTTP, network: "tcp", addr: s.httpServer.Addr, srv: s.httpServer}
+	if len(s.tlsCerts) > 0 {
+		certs, err := s.newCertStore()
+		if err != nil {
+			return nil, err
+		}
+		primary.name, primary.certs = EndpointHTTPS, certs
+		primary.srv.TLSConfig = certs.tlsConfig()
+	}
+	eps := []*endpoint{primary}
+
+	for _, spec := range s.endpoints {
+		ep := &endpoint{network: "tcp", addr: spec.addr, srv: s.cloneHTTPServer()}
+		switch spec.kind {
+		case kindRedirect:
+			if primary.certs == nil {
+				return nil, errors.New("server: WithHTTPRedirect requires WithTLS")
+			}
+			ep.name = EndpointRedirect
+			ep.srv.Handler = redirectHandler(primary, s.redirectTarget)
+		case kindUnix:
+			ep.name, ep.network = EndpointUnix, "unix"
+		case kindH2C:
+			ep.name = EndpointH2C
+			if err := enableH2C(ep.srv); err != nil {
+				return nil, err
+			}
+		case kindAdmin:
+			ep.name = EndpointAdmin
+			ep.srv.Handler = s.adminHandler(*spec.admin)
+			ep.srv.WriteTimeout = 0
+		}
+		eps = append(eps, ep)
+	}
+	return eps, nil
+}
+
+// cloneHTTPServer returns a server with the primary server's settings.
+func (s *Server) cloneHTTPServer() *http.Server {
+	p := s.httpServer
+	return &http.Server{
+		Handler:           p.Handler,
+		ReadTimeout:       p.ReadTimeout,
+		ReadHeaderTimeout: p.ReadHeaderTimeout,
+		WriteTimeout:      p.WriteTimeout,
+		IdleTimeout:       p.IdleTimeout,
+		MaxHeaderBytes:    p.MaxHeaderBytes,
+		ErrorLog:          p.ErrorLog,
+	}
+}
+
+// listen opens the endpoint's listener.
+func (ep *endpoint) listen() (net.Listener, error) {
+	if ep.network == "unix" {
+		if err := removeStaleSocket(ep.addr); err != nil {
+			return nil, err
+		}
+	}
+	return net.Listen(ep.network, ep.addr)
+}
+
+// removeStaleSocket removes the socket file at path if no process is
+// listening on it. A live socket is left alone and reported as an error.
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if err != nil || fi.Mode()&os.ModeSocket == 0 {
+		return nil // missing, or not a socket: let Listen report it
+	}
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("server: unix socket %s is in use by another process", path)
+	}
+	_ = os.Remove(path)
+	return nil
+}
+
+// serve serves the endpoint until it is shut down, tracking its connections
+// and giving requests a context that carries the Stopping channel.
+func (s *Server) serve(ep *endpoint) error {
+	ep.srv.ConnState = s.conns.connState
+	ep.srv.ConnContext = s.conns.connContext
+	ep.srv.BaseContext = func(net.Listener) context.Context {
+		return context.WithValue(context.Background(), stoppingKey{}, s.stopping)
+	}
+	ep.srv.Handler = s.conns.handler(ep.srv.Handler)
+	if ep.certs != nil {
+		return ep.srv.ServeTLS(ep.ln, "", "")
+	}
+	return ep.srv.Serve(ep.ln)
+}
+
+// shutdownAll gracefully shuts down every endpoint concurrently and returns
+// the first error.
+func shutdownAll(ctx context.Context, eps []*endpoint) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(eps))
+	for i, ep := range eps {
+		wg.Add(1)
+		go func(i int, ep *endpoint) {
+			defer wg.Done()
+			errs[i] = ep.srv.Shutdown(ctx)
+		}(i, ep)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// redirectHandler redirects to the same host and URI on the HTTPS endpoint,
+// or on target when set (see WithRedirectTarget).
+func redirectHandler(https *endpoint, target string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, port := target, ""
+		if h, p, err := net.SplitHostPort(target); err == nil {
+			host, port = h, p
+		} else if target == "" {
+			_, port, _ = net.SplitHostPort(https.ln.Addr().String())
+		}
+		if host == "" {
+			host = r.Host
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		} else if strings.Contains(host, ":") && !strings.HasPrefix(host, "[") {
+			host = "[" + host + "]" // bare IPv6 literal
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
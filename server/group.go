@@ -0,0 +1,450 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Component is a long-running part of a service managed by a Group, such as
+// a Server, a queue consumer, or a ticker. Run should block until ctx is
+// cancelled and then return nil; returning earlier means the component
+// stopped or failed.
+type Component interface {
+	Run(ctx context.Context) error
+}
+
+// ComponentFunc adapts a function to Component.
+type ComponentFunc func(ctx context.Context) error
+
+// Run calls f(ctx).
+func (f ComponentFunc) Run(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Readier is implemented by components that take time to start, such as
+// Server. A Group starts a component's dependents only after its Ready
+// channel is closed; other components are ready as soon as they are started.
+type Readier interface {
+	Ready() <-chan struct{}
+}
+
+// Every returns a component that calls fn every interval until its context
+// is cancelled. An error from fn stops the component, so combine it with
+// WithRestart to keep a periodic job running after failures.
+func Every(interval time.Duration, fn func(ctx context.Context) error) Component {
+	return ComponentFunc(func(ctx context.Context) error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+				if err := fn(ctx); err != nil {
+					return err
+				}
+			}
+		}
+	})
+}
+
+// RestartPolicy controls how a Group restarts a failed component.
+type RestartPolicy struct {
+	// MaxRestarts is the number of consecutive failures to restart after;
+	// negative means unlimited. A run that lasts at least MaxBackoff resets
+	// the count.
+	MaxRestarts int
+
+	// Backoff is the delay before the first restart, doubling after each
+	// consecutive failure. Default: 1s.
+	Backoff time.Duration
+
+	// MaxBackoff caps the delay. Default: 1m.
+	MaxBackoff time.Duration
+}
+
+// ComponentOption configures a component added to a Group.
+type ComponentOption func(*component)
+
+// DependsOn starts the component after the named components are ready and
+// stops it before them. If a dependency fails before the component starts,
+// the component is skipped when Optional and stops the group otherwise.
+func DependsOn(names ...string) ComponentOption {
+	return func(c *component) {
+		c.deps = append(c.deps, names...)
+	}
+}
+
+// Optional marks the component as non-critical: when it stops or fails for
+// good, the failure is logged and the rest of the group keeps running.
+// By default any component stopping shuts the whole group down.
+func Optional() ComponentOption {
+	return func(c *component) {
+		c.optional = true
+	}
+}
+
+// WithRestart restarts the component with backoff when it returns an error
+// or panics. Restarted components must support being run again; a Server
+// does not.
+func WithRestart(p RestartPolicy) ComponentOption {
+	return func(c *component) {
+		if p.Backoff <= 0 {
+			p.Backoff = time.Second
+		}
+		if p.MaxBackoff <= 0 {
+			p.MaxBackoff = time.Minute
+		}
+		c.restart = &p
+	}
+}
+
+// GroupConfig configures a Group.
+type GroupConfig struct {
+	// ShutdownTimeout bounds the whole coordinated shutdown. Default: 30s.
+	ShutdownTimeout time.Duration
+
+	// Logger logs component lifecycle events. Default: slog.Default().
+	Logger *slog.Logger
+}
+
+// Group runs several components under one lifecycle: it starts them in
+// dependency order, with independent components starting concurrently,
+// restarts failed ones according to their policy, and on SIGINT/SIGTERM,
+// context cancellation, or the exit of a critical component stops them all
+// in reverse order.
+//
+// The Group handles shutdown signals for the process: add a Server with Add,
+// which runs it through Server.Run, rather than calling Start from a
+// component.
+//
+//	g := server.NewGroup(server.GroupConfig{})
+//	g.Add("db", dbPool)
+//	g.Add("http", srv, server.DependsOn("db"))
+//	g.Add("consumer", consumer, server.DependsOn("db"),
+//	    server.WithRestart(server.RestartPolicy{MaxRestarts: 5}))
+//	g.Add("cleanup", server.Every(time.Hour, cleanup), server.Optional())
+//	if err := g.Run(context.Background()); err != nil {
+//	    log.Fatal(err)
+//	}
+type Group struct {
+	cfg        GroupConfig
+	components []*component
+}
+
+// component is a Component with its group settings and run state.
+type component struct {
+	name     string
+	c        Component
+	deps     []string
+	optional bool
+	restart  *RestartPolicy
+
+	cancel    context.CancelFunc
+	ready     chan struct{}
+	readyOnce sync.Once
+	done      chan struct{}
+	err       error // final error, valid after done is closed
+}
+
+// NewGroup creates an empty Group.
+func NewGroup(cfg GroupConfig) *Group {
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = 30 * time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	return &Group{cfg: cfg}
+}
+
+// Add registers a component under a unique name. Components without
+// dependencies start in the order they were added.
+func (g *Group) Add(name string, c Component, opts ...ComponentOption) {
+	comp := &component{name: name, c: c}
+	for _, opt := range opts {
+		opt(comp)
+	}
+	g.components = append(g.components, comp)
+}
+
+// Run starts all components and blocks until the group stops. It returns the
+// error of the critical component that caused the shutdown, or nil when the
+// group was stopped by a signal or ctx.
+func (g *Group) Run(ctx context.Context) error {
+	order, err := g.startOrder()
+	if err != nil {
+		return err
+	}
+	log := g.cfg.Logger
+
+	sigCh, stopSignals := notifyShutdown()
+	defer stopSignals()
+
+	exited := make(chan *component, len(order))
+	readyCh := make(chan *component, len(order))
+	var started []*component
+	var runErr error
+
+	stop := func(reason string) { log.Info("shutdown signal received", "signal", reason) }
+	fail := func(c *component) bool {
+		if c.optional {
+			log.Warn("optional component stopped", "component", c.name, "error", c.err)
+			return false
+		}
+		if c.err != nil {
+			runErr = fmt.Errorf("server: component %s: %w", c.name, c.err)
+		}
+		log.Error("critical component stopped", "component", c.name, "error", c.err)
+		return true
+	}
+	// skip handles a component whose dependency dep failed before it could
+	// start: optional components are skipped, critical ones stop the group.
+	skip := func(c *component, dep string) bool {
+		if c.optional {
+			log.Warn("optional component skipped", "component", c.name, "dependency", dep)
+			return false
+		}
+		runErr = fmt.Errorf("server: component %s: dependency %s failed", c.name, dep)
+		log.Error("critical component cannot start", "component", c.name, "dependency", dep)
+		return true
+	}
+
+	// Start every component whose dependencies are ready, so independent
+	// components start, and become ready, concurrently.
+	const (
+		starting = iota + 1
+		ready
+		failed
+	)
+	state := make(map[string]int, len(order))
+	pending, waiting := order, 0
+	stopped := false
+startup:
+	for len(pending) > 0 || waiting > 0 {
+		var blocked []*component
+	next:
+		for _, c := range pending {
+			for _, dep := range c.deps {
+				switch state[dep] {
+				case failed:
+					state[c.name] = failed
+					if skip(c, dep) {
+						stopped = true
+						break startup
+					}
+					continue next
+				case ready:
+				default:
+					blocked = append(blocked, c)
+					continue next
+				}
+			}
+			log.Info("component starting", "component", c.name)
+			g.start(c, exited)
+			started = append(started, c)
+			state[c.name] = starting
+			waiting++
+			go func(c *component) {
+				select {
+				case <-c.ready:
+					readyCh <- c
+				case <-c.done:
+				}
+			}(c)
+		}
+		pending = blocked
+		if waiting == 0 {
+			break // every component started or was skipped
+		}
+
+		select {
+		case c := <-readyCh:
+			if state[c.name] == starting {
+				state[c.name] = ready
+				waiting--
+			}
+		case c := <-exited:
+			if state[c.name] == starting {
+				waiting--
+			}
+			state[c.name] = failed
+			if fail(c) {
+				stopped = true
+				break startup
+			}
+		case sig := <-sigCh:
+			stop(sig.String())
+			stopped = true
+			break startup
+		case <-ctx.Done():
+			stop("context")
+			stopped = true
+			break startup
+		}
+	}
+
+	if !stopped {
+	wait:
+		for {
+			select {
+			case c := <-exited:
+				if fail(c) {
+					break wait
+				}
+			case sig := <-sigCh:
+				stop(sig.String())
+				break wait
+			case <-ctx.Done():
+				stop("context")
+				break wait
+			}
+		}
+	}
+
+	// Stop in reverse start order, so dependents stop before their dependencies.
+	deadline := time.NewTimer(g.cfg.ShutdownTimeout)
+	defer deadline.Stop()
+	for i := len(started) - 1; i >= 0; i-- {
+		c := started[i]
+		c.cancel()
+		select {
+		case <-c.done:
+			log.Info("component stopped", "component", c.name)
+		case <-deadline.C:
+			return errors.Join(runErr, fmt.Errorf("server: shutdown timed out waiting for component %s", c.name))
+		}
+	}
+	return runErr
+}
+
+// start supervises c in a new goroutine, reporting on exited when it stops
+// for good.
+func (g *Group) start(c *component, exited chan<- *component) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.ready = make(chan struct{})
+	c.done = make(chan struct{})
+	markReady := func() { c.readyOnce.Do(func() { close(c.ready) }) }
+
+	go func() {
+		defer func() {
+			close(c.done)
+			if ctx.Err() == nil {
+				exited <- c
+			}
+		}()
+
+		var backoff time.Duration
+		failures := 0
+		for {
+			if r, ok := c.c.(Readier); ok {
+				go func() {
+					select {
+					case <-r.Ready():
+						markReady()
+					case <-ctx.Done():
+					}
+				}()
+			} else {
+				markReady()
+			}
+
+			began := time.Now()
+			err := runComponent(ctx, c.c)
+			if ctx.Err() != nil {
+				return
+			}
+			if err == nil || c.restart == nil {
+				c.err = err
+				return
+			}
+
+			if time.Since(began) >= c.restart.MaxBackoff {
+				failures, backoff = 0, 0
+			}
+			failures++
+			if c.restart.MaxRestarts >= 0 && failures > c.restart.MaxRestarts {
+				c.err = err
+				return
+			}
+			if backoff == 0 {
+				backoff = c.restart.Backoff
+			} else {
+				backoff = min(2*backoff, c.restart.MaxBackoff)
+			}
+			g.cfg.Logger.Warn("component failed, restarting",
+				"component", c.name, "error", err, "attempt", failures, "backoff", backoff.String())
+
+			timer := time.NewTimer(backoff)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	}()
+}
+
+// runComponent runs c, converting a panic into an error.
+func runComponent(ctx context.Context, c Component) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return c.Run(ctx)
+}
+
+// startOrder sorts components so each starts after its dependencies,
+// keeping insertion order otherwise.
+func (g *Group) startOrder() ([]*component, error) {
+	byName := make(map[string]*component, len(g.components))
+	for _, c := range g.components {
+		if _, dup := byName[c.name]; dup {
+			return nil, fmt.Errorf("server: duplicate component %q", c.name)
+		}
+		byName[c.name] = c
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[*component]int, len(g.components))
+	order := make([]*component, 0, len(g.components))
+	var visit func(c *component) error
+	visit = func(c *component) error {
+		switch state[c] {
+		case visiting:
+			return fmt.Errorf("server: dependency cycle through component %q", c.name)
+		case visited:
+			return nil
+		}
+		state[c] = visiting
+		for _, name := range c.deps {
+			dep, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("server: component %q depends on unknown component %q", c.name, name)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[c] = visited
+		order = append(order, c)
+		return nil
+	}
+	for _, c := range g.components {
+		if err := visit(c); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
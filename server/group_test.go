@@ -0,0 +1,329 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KARTIKrocks/apikit/server"
+)
+
+func quietGroup() *server.Group {
+	return server.NewGroup(server.GroupConfig{
+		ShutdownTimeout: 5 * time.Second,
+		Logger:          slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+}
+
+// recorder logs component start and stop events in order.
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(ev string) {
+	r.mu.Lock()
+	r.events = append(r.events, ev)
+	r.mu.Unlock()
+}
+
+func (r *recorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+func (r *recorder) component(name string) server.Component {
+	return server.ComponentFunc(func(ctx context.Context) error {
+		r.add("start " + name)
+		<-ctx.Done()
+		r.add("stop " + name)
+		return nil
+	})
+}
+
+func TestGroup_DependencyOrder(t *testing.T) {
+	t.Parallel()
+	rec := &recorder{}
+	g := quietGroup()
+	g.Add("api", rec.component("api"), server.DependsOn("db", "cache"))
+	g.Add("db", rec.component("db"))
+	g.Add("cache", rec.component("cache"), server.DependsOn("db"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- g.Run(ctx) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(rec.get()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Fatalf("run returned error: %v", err)
+	}
+
+	want := []string{"start db", "start cache", "start api", "stop api", "stop cache", "stop db"}
+	if got := rec.get(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+func TestGroup_InvalidDependencies(t *testing.T) {
+	t.Parallel()
+	noop := server.ComponentFunc(func(ctx context.Context) error { <-ctx.Done(); return nil })
+
+	g := quietGroup()
+	g.Add("a", noop, server.DependsOn("missing"))
+	if err := g.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "unknown component") {
+		t.Errorf("expected unknown component error, got %v", err)
+	}
+
+	g = quietGroup()
+	g.Add("a", noop, server.DependsOn("b"))
+	g.Add("b", noop, server.DependsOn("a"))
+	if err := g.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("expected cycle error, got %v", err)
+	}
+}
+
+func TestGroup_CriticalFailureStopsGroup(t *testing.T) {
+	t.Parallel()
+	rec := &recorder{}
+	boom := errors.New("boom")
+
+	g := quietGroup()
+	g.Add("worker", rec.component("worker"))
+	g.Add("failing", server.ComponentFunc(func(ctx context.Context) error {
+		time.Sleep(20 * time.Millisecond)
+		return boom
+	}))
+
+	done := make(chan error, 1)
+	go func() { done <- g.Run(context.Background()) }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, boom) {
+			t.Fatalf("expected boom, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("group did not stop")
+	}
+	if got := rec.get(); len(got) != 2 || got[1] != "stop worker" {
+		t.Errorf("events = %v, want worker stopped", got)
+	}
+}
+
+func TestGroup_OptionalFailureKeepsRunning(t *testing.T) {
+	t.Parallel()
+	g := quietGroup()
+	g.Add("worker", server.ComponentFunc(func(ctx context.Context) error { <-ctx.Done(); return nil }))
+	g.Add("flaky", server.ComponentFunc(func(ctx context.Context) error {
+		panic("flaky")
+	}), server.Optional())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := g.Run(ctx); err != nil {
+		t.Fatalf("run returned error: %v", err)
+	}
+}
+
+func TestGroup_RestartWithBackoff(t *testing.T) {
+	t.Parallel()
+	var runs atomic.Int32
+	g := quietGroup()
+	g.Add("consumer", server.ComponentFunc(func(ctx context.Context) error {
+		if runs.Add(1) < 3 {
+			return errors.New("connection lost")
+		}
+		<-ctx.Done()
+		return nil
+	}), server.WithRestart(server.RestartPolicy{MaxRestarts: 5, Backoff: time.Millisecond, MaxBackoff: time.Second}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- g.Run(ctx) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runs.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Fatalf("run returned error: %v", err)
+	}
+	if n := runs.Load(); n != 3 {
+		t.Errorf("runs = %d, want 3", n)
+	}
+}
+
+func TestGroup_RestartLimit(t *testing.T) {
+	t.Parallel()
+	var runs atomic.Int32
+	g := quietGroup()
+	g.Add("consumer", server.ComponentFunc(func(ctx context.Context) error {
+		runs.Add(1)
+		return errors.New("connection lost")
+	}), server.WithRestart(server.RestartPolicy{MaxRestarts: 2, Backoff: time.Millisecond}))
+
+	if err := g.Run(context.Background()); err == nil {
+		t.Fatal("expected error after restarts are exhausted")
+	}
+	if n := runs.Load(); n != 3 {
+		t.Errorf("runs = %d, want 3", n)
+	}
+}
+
+func TestGroup_Every(t *testing.T) {
+	t.Parallel()
+	var ticks atomic.Int32
+	g := quietGroup()
+	g.Add("ticker", server.Every(5*time.Millisecond, func(ctx context.Context) error {
+		ticks.Add(1)
+		return nil
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := g.Run(ctx); err != nil {
+		t.Fatalf("run returned error: %v", err)
+	}
+	if ticks.Load() == 0 {
+		t.Error("expected ticks")
+	}
+}
+
+func TestGroup_ServerComponent(t *testing.T) {
+	t.Parallel()
+	srv := server.New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}), server.WithAddr("127.0.0.1:0"), server.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+
+	var addr string
+	g := quietGroup()
+	g.Add("http", srv)
+	g.Add("client", server.ComponentFunc(func(ctx context.Context) error {
+		// Dependencies are ready, so the server is already listening.
+		addr = srv.Addrs()[server.EndpointHTTP].String()
+		<-ctx.Done()
+		return nil
+	}), server.DependsOn("http"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() { errCh <- g.Run(ctx) }()
+
+	select {
+	case <-srv.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not become ready")
+	}
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	resp, err := client.Get("http://" + srv.Addrs()[server.EndpointHTTP].String())
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("status = %d, want 204", resp.StatusCode)
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Fatalf("run returned error: %v", err)
+	}
+	if addr == "" {
+		t.Error("dependent started before the server was listening")
+	}
+}
+
+// slowReady is a component that becomes ready after a delay.
+type slowReady struct {
+	delay time.Duration
+	ready chan struct{}
+}
+
+func (s *slowReady) Run(ctx context.Context) error {
+	timer := time.AfterFunc(s.delay, func() { close(s.ready) })
+	defer timer.Stop()
+	<-ctx.Done()
+	return nil
+}
+
+func (s *slowReady) Ready() <-chan struct{} { return s.ready }
+
+func TestGroup_IndependentComponentsStartConcurrently(t *testing.T) {
+	t.Parallel()
+	g := quietGroup()
+	for _, name := range []string{"a", "b", "c"} {
+		g.Add(name, &slowReady{delay: 200 * time.Millisecond, ready: make(chan struct{})})
+	}
+	began := make(chan time.Time, 1)
+	g.Add("api", server.ComponentFunc(func(ctx context.Context) error {
+		began <- time.Now()
+		<-ctx.Done()
+		return nil
+	}), server.DependsOn("a", "b", "c"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	start := time.Now()
+	go func() { errCh <- g.Run(ctx) }()
+
+	select {
+	case at := <-began:
+		if elapsed := at.Sub(start); elapsed > 500*time.Millisecond {
+			t.Errorf("dependent started after %v; independent components were started one by one", elapsed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("dependent did not start")
+	}
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Fatalf("run returned error: %v", err)
+	}
+}
+
+func TestGroup_DependentsOfFailedOptionalComponent(t *testing.T) {
+	t.Parallel()
+	broken := server.ComponentFunc(func(ctx context.Context) error {
+		return errors.New("unavailable")
+	})
+
+	// An optional dependent is skipped and the group keeps running.
+	rec := &recorder{}
+	g := quietGroup()
+	g.Add("cache", broken, server.Optional())
+	g.Add("warmer", rec.component("warmer"), server.DependsOn("cache"), server.Optional())
+	g.Add("worker", rec.component("worker"))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := g.Run(ctx); err != nil {
+		t.Fatalf("run returned error: %v", err)
+	}
+	if got := rec.get(); !reflect.DeepEqual(got, []string{"start worker", "stop worker"}) {
+		t.Errorf("events = %v, want the warmer skipped", got)
+	}
+
+	// A critical dependent can't run, so the group stops.
+	rec = &recorder{}
+	g = quietGroup()
+	g.Add("cache", broken, server.Optional())
+	g.Add("api", rec.component("api"), server.DependsOn("cache"))
+	err := g.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "dependency cache failed") {
+		t.Fatalf("expected dependency error, got %v", err)
+	}
+	if got := rec.get(); len(got) != 0 {
+		t.Errorf("events = %v, want api never started", got)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build go1.24
+
+package server
+
+import "net/http"
+
+// enableH2C makes srv accept cleartext HTTP/2 alongside HTTP/1.1.
+func enableH2C(srv *http.Server) error {
+	var protocols http.Protocols
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+	srv.Protocols = &protocols
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !go1.24
+
+package server
+
+import (
+	"errors"
+	"net/http"
+)
+
+// enableH2C is unavailable: net/http supports h2c from Go 1.24.
+func enableH2C(*http.Server) error {
+	return errors.New("server: WithH2C requires Go 1.24 or later")
+}
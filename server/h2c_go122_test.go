@@ -0,0 +1,18 @@
+//go:build !go1.24
+
+package server_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/KARTIKrocks/apikit/server"
+)
+
+func TestServer_H2CUnsupported(t *testing.T) {
+	t.Parallel()
+	srv := server.New(http.NotFoundHandler(), server.WithAddr("127.0.0.1:0"), server.WithH2C("127.0.0.1:0"))
+	if err := srv.Start(); err == nil {
+		t.Fatal("expected Start to fail: WithH2C requires Go 1.24")
+	}
+}
@@ -0,0 +1,33 @@
+//go:build go1.24
+
+package server_test
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/KARTIKrocks/apikit/server"
+)
+
+func TestServer_H2C(t *testing.T) {
+	t.Parallel()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	})
+	srv := server.New(handler, server.WithAddr("127.0.0.1:0"), server.WithH2C("127.0.0.1:0"))
+	startServer(t, srv)
+
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: &protocols}}
+	resp, err := client.Get("http://" + srv.Addrs()[server.EndpointH2C].String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "HTTP/2.0" {
+		t.Fatalf("expected HTTP/2.0, got %q", body)
+	}
+}
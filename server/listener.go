@@ -0,0 +1,282 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables of the systemd socket activation protocol
+// (sd_listen_fds(3)). Passed descriptors start at fd 3.
+const (
+	listenPIDEnv     = "LISTEN_PID"
+	listenFDsEnv     = "LISTEN_FDS"
+	listenFDNamesEnv = "LISTEN_FDNAMES"
+	listenFDsStart   = 3
+)
+
+// upgradeEnv tells a child started by a graceful upgrade how many listening
+// sockets it inherited. They occupy fds 3..3+n-1; fd 3+n is a pipe the child
+// writes to once it is serving.
+const upgradeEnv = "APIKIT_UPGRADE_FDS"
+
+// ErrUpgradeUnsupported is returned by Upgrade on platforms that cannot pass
+// sockets to a child process.
+var ErrUpgradeUnsupported = errors.New("server: graceful upgrade is not supported on this platform")
+
+// WithListener serves the primary endpoint on ln instead of listening on the
+// configured address. The server takes ownership of ln and closes it on
+// shutdown.
+func WithListener(ln net.Listener) Option {
+	return func(s *Server) {
+		s.listener = ln
+	}
+}
+
+// WithSocketActivation serves on the sockets passed by systemd socket
+// activation (LISTEN_FDS), assigned in order to the primary endpoint and then
+// to those added by WithHTTPRedirect, WithUnixSocket, and WithH2C. Endpoints
+// without a passed socket, or all of them when the process was not
+// socket-activated, listen on their configured addresses.
+func WithSocketActivation() Option {
+	return func(s *Server) {
+		s.socketActivation = true
+	}
+}
+
+// WithGracefulUpgrade enables zero-downtime binary upgrades. When one of the
+// given signals arrives (default SIGHUP and SIGUSR2) or Upgrade is called,
+// the server re-executes its binary with the same arguments, passes the
+// listening sockets to the child, waits until the child is serving, and then
+// shuts down gracefully. If the child fails to start, the parent keeps
+// serving.
+func WithGracefulUpgrade(signals ...os.Signal) Option {
+	return func(s *Server) {
+		s.upgradeEnabled = true
+		if len(signals) == 0 {
+			signals = defaultUpgradeSignals
+		}
+		s.upgradeSignals = signals
+	}
+}
+
+// WithUpgradeTimeout sets how long a graceful upgrade waits for the child to
+// start serving before killing it (default 30s). Zero or negative keeps the
+// default.
+func WithUpgradeTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		if d <= 0 {
+			d = 30 * time.Second
+		}
+		s.upgradeTimeout = d
+	}
+}
+
+// ActivatedListeners returns the sockets passed to this process by systemd
+// socket activation, in the order of the unit's ListenStream= directives,
+// with the names from FileDescriptorName= (empty when unnamed). It returns
+// nil if the process was not socket-activated. The activation variables are
+// removed from the environment so they are not inherited by child processes.
+func ActivatedListeners() ([]net.Listener, []string, error) {
+	pid, fds := os.Getenv(listenPIDEnv), os.Getenv(listenFDsEnv)
+	names := os.Getenv(listenFDNamesEnv)
+	if pid == "" || fds == "" {
+		return nil, nil, nil
+	}
+	if pid != strconv.Itoa(os.Getpid()) {
+		return nil, nil, nil
+	}
+	_ = os.Unsetenv(listenPIDEnv)
+	_ = os.Unsetenv(listenFDsEnv)
+	_ = os.Unsetenv(listenFDNamesEnv)
+
+	n, err := strconv.Atoi(fds)
+	if err != nil || n < 0 {
+		return nil, nil, fmt.Errorf("server: invalid %s %q", listenFDsEnv, fds)
+	}
+	lns, err := fileListeners(n)
+	if err != nil {
+		return nil, nil, err
+	}
+	fdNames := make([]string, n)
+	if names != "" {
+		copy(fdNames, strings.Split(names, ":"))
+	}
+	return lns, fdNames, nil
+}
+
+// inheritedListeners returns the sockets passed by a parent during a graceful
+// upgrade and the pipe used to report readiness, or nil if this process was
+// not started by an upgrade.
+func inheritedListeners() ([]net.Listener, *os.File, error) {
+	v := os.Getenv(upgradeEnv)
+	if v == "" {
+		return nil, nil, nil
+	}
+	_ = os.Unsetenv(upgradeEnv)
+
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return nil, nil, fmt.Errorf("server: invalid %s %q", upgradeEnv, v)
+	}
+	lns, err := fileListeners(n)
+	if err != nil {
+		return nil, nil, err
+	}
+	return lns, os.NewFile(uintptr(listenFDsStart+n), "upgrade-ready"), nil
+}
+
+// fileListeners converts the n descriptors starting at fd 3 into listeners.
+// net.FileListener duplicates each descriptor, so the originals are closed.
+func fileListeners(n int) ([]net.Listener, error) {
+	lns := make([]net.Listener, 0, n)
+	for i := 0; i < n; i++ {
+		fd := listenFDsStart + i
+		f := os.NewFile(uintptr(fd), "listener-"+strconv.Itoa(fd))
+		ln, err := net.FileListener(f)
+		_ = f.Close()
+		if err != nil {
+			for _, l := range lns {
+				_ = l.Close()
+			}
+			return nil, fmt.Errorf("server: inherit listener fd %d: %w", fd, err)
+		}
+		lns = append(lns, ln)
+	}
+	return lns, nil
+}
+
+// listen opens a listener for each endpoint. Sockets inherited from an
+// upgrading parent, or passed by systemd with WithSocketActivation, are
+// assigned to endpoints in order; WithListener supplies the primary
+// endpoint's socket otherwise; the remaining endpoints listen on their
+// addresses.
+func (s *Server) listen(eps []*endpoint) error {
+	inherited, ready, err := inheritedListeners()
+	if err != nil {
+		return err
+	}
+	if len(inherited) > 0 {
+		s.readyFile = ready
+	} else if s.socketActivation {
+		if inherited, _, err = ActivatedListeners(); err != nil {
+			return err
+		}
+	}
+	if len(inherited) > len(eps) {
+		closeExtra(inherited[len(eps):])
+	}
+	if s.listener != nil && len(inherited) > 0 {
+		_ = s.listener.Close()
+	}
+
+	for i, ep := range eps {
+		switch {
+		case i < len(inherited):
+			ep.ln = inherited[i]
+		case i == 0 && s.listener != nil:
+			ep.ln = s.listener
+		default:
+			if ep.ln, err = ep.listen(); err != nil {
+				for _, opened := range eps[:i] {
+					_ = opened.ln.Close()
+				}
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// closeExtra closes listeners the server does not serve on.
+func closeExtra(lns []net.Listener) {
+	for _, ln := range lns {
+		_ = ln.Close()
+	}
+}
+
+// notifyReady tells an upgrading parent that this process is serving.
+func (s *Server) notifyReady() {
+	if s.readyFile == nil {
+		return
+	}
+	_, _ = s.readyFile.Write([]byte{1})
+	_ = s.readyFile.Close()
+	s.readyFile = nil
+}
+
+// Upgrade performs a graceful binary upgrade as described in
+// WithGracefulUpgrade. It returns once the child is serving and the parent
+// has begun shutting down, or with an error if the upgrade failed, in which
+// case the server keeps running. Upgrade requires WithGracefulUpgrade and a
+// running server.
+func (s *Server) Upgrade(ctx context.Context) error {
+	if !s.upgradeEnabled {
+		return errors.New("server: graceful upgrade not enabled")
+	}
+	reply := make(chan error, 1)
+	select {
+	case s.upgradeCh <- reply:
+	case <-s.doneCh:
+		return errors.New("server: not running")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-reply:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// upgrade starts a child with the endpoints' listeners and waits until it is
+// serving.
+func (s *Server) upgrade(eps []*endpoint) error {
+	files := make([]*os.File, 0, len(eps))
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+	for _, ep := range eps {
+		f, err := listenerFile(ep.ln)
+		if err != nil {
+			return fmt.Errorf("server: upgrade: %w", err)
+		}
+		files = append(files, f)
+	}
+
+	pid, err := startChild(files, s.upgradeTimeout)
+	if err != nil {
+		return fmt.Errorf("server: upgrade: %w", err)
+	}
+	// The child now serves the Unix socket; closing ours must not remove it.
+	for _, ep := range eps {
+		if ul, ok := ep.ln.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+	}
+	s.logger.Info("upgrade child ready", "pid", pid)
+	return nil
+}
+
+// childEnv returns the environment for an upgrade child, without socket
+// activation variables that refer to this process.
+func childEnv(nfds int) []string {
+	env := make([]string, 0, len(os.Environ())+1)
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		switch name {
+		case listenPIDEnv, listenFDsEnv, listenFDNamesEnv, upgradeEnv:
+			continue
+		}
+		env = append(env, kv)
+	}
+	return append(env, upgradeEnv+"="+strconv.Itoa(nfds))
+}
@@ -4,6 +4,7 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
 	"log/slog"
 	"net"
 	"net/http"
@@ -13,20 +14,42 @@ import (
 	"sync/atomic"
 	"syscall"
 	"time"
+
+	"github.com/KARTIKrocks/apikit/health"
 )
 
 // Server wraps http.Server with graceful shutdown and lifecycle hooks.
 type Server struct {
-	httpServer      *http.Server
-	shutdownTimeout time.Duration
-	logger          *slog.Logger
-	onStart         []func() error
-	onShutdown      []func(ctx context.Context) error
-	shutdownCh      chan struct{} // signals Shutdown was called programmatically
-	doneCh          chan struct{} // closed when Start() returns
-	tlsCertFile     string
-	tlsKeyFile      string
-	listenAddr      atomic.Value // stores net.Addr after listening
+	httpServer        *http.Server
+	shutdownTimeout   time.Duration
+	logger            *slog.Logger
+	onStart           []func() error
+	onShutdown        []func(ctx context.Context) error
+	shutdownCh        chan struct{} // signals Shutdown was called programmatically
+	doneCh            chan struct{} // closed when Start() returns
+	tlsCerts          []certPair
+	tlsBase           *tls.Config
+	tlsReloadInterval time.Duration
+	clientCAFile      string
+	clientAuth        tls.ClientAuthType
+	listenAddr        atomic.Value // stores net.Addr after listening
+	addrs             atomic.Value // stores map[string]net.Addr after listening
+	endpoints         []endpointSpec
+	redirectTarget    string // public HTTPS host[:port] for WithHTTPRedirect
+
+	health       *health.Checker
+	preStopDelay time.Duration
+	conns        connTracker
+	stopping     chan struct{} // closed when listeners stop accepting
+	ready        chan struct{} // closed once listening
+
+	listener         net.Listener // set by WithListener
+	socketActivation bool
+	upgradeEnabled   bool
+	upgradeSignals   []os.Signal
+	upgradeTimeout   time.Duration
+	upgradeCh        chan chan error // Upgrade requests from other goroutines
+	readyFile        *os.File        // readiness pipe when started by an upgrade
 }
 
 // Option configures a Server.
@@ -46,6 +69,10 @@ func New(handler http.Handler, opts ...Option) *Server {
 		logger:          slog.Default(),
 		shutdownCh:      make(chan struct{}, 1),
 		doneCh:          make(chan struct{}),
+		upgradeTimeout:  30 * time.Second,
+		upgradeCh:       make(chan chan error),
+		stopping:        make(chan struct{}),
+		ready:           make(chan struct{}),
 	}
 
 	for _, opt := range opts {
@@ -101,14 +128,6 @@ func WithLogger(logger *slog.Logger) Option {
 	}
 }
 
-// WithTLS enables HTTPS with the given certificate and key files.
-func WithTLS(certFile, keyFile string) Option {
-	return func(s *Server) {
-		s.tlsCertFile = certFile
-		s.tlsKeyFile = keyFile
-	}
-}
-
 // Addr returns the listener address after the server has started.
 // Returns nil if the server has not started yet.
 func (s *Server) Addr() net.Addr {
@@ -131,9 +150,44 @@ func (s *Server) OnShutdown(fn func(ctx context.Context) error) {
 	s.onShutdown = append(s.onShutdown, fn)
 }
 
+// RegisterOnShutdown registers a function to call as soon as graceful
+// shutdown begins, before connections are drained. Use it to stop long-lived
+// handlers such as SSE streams or WebSockets, which would otherwise hold the
+// drain open until the shutdown timeout. Each function runs in its own goroutine.
+func (s *Server) RegisterOnShutdown(fn func()) {
+	s.httpServer.RegisterOnShutdown(fn)
+}
+
 // Start runs the server and blocks until a shutdown signal (SIGINT/SIGTERM)
-// is received or Shutdown is called programmatically.
+// is received, Shutdown is called programmatically, or a graceful upgrade
+// hands the listeners to a new process. All listeners are shut down together.
 func (s *Server) Start() error {
+	return s.run(context.Background(), true)
+}
+
+// Run runs the server like Start, but shuts down when ctx is cancelled
+// instead of handling SIGINT/SIGTERM itself. Server implements the Component
+// interface through Run, for use in a Group.
+func (s *Server) Run(ctx context.Context) error {
+	return s.run(ctx, false)
+}
+
+// Ready returns a channel that is closed once the server is listening.
+func (s *Server) Ready() <-chan struct{} {
+	return s.ready
+}
+
+// notifyShutdown relays SIGINT and SIGTERM on the returned channel until
+// stop is called. Server.Start and Group.Run share it, so a process has one
+// shutdown signal handler.
+func notifyShutdown() (sigCh <-chan os.Signal, stop func()) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM)
+	return ch, func() { signal.Stop(ch) }
+}
+
+// run serves until a stop signal, ctx, Shutdown, or an upgrade ends it.
+func (s *Server) run(ctx context.Context, handleSignals bool) error {
 	var closeOnce sync.Once
 	closeDone := func() {
 		closeOnce.Do(func() { close(s.doneCh) })
@@ -147,59 +201,117 @@ func (s *Server) Start() error {
 		}
 	}
 
-	// Start listening
-	ln, err := net.Listen("tcp", s.httpServer.Addr)
+	eps, err := s.buildEndpoints()
 	if err != nil {
 		return err
 	}
 
-	s.listenAddr.Store(ln.Addr())
+	// Register for signals before serving, so a signal sent once the server
+	// answers requests is never missed.
+	var sigCh <-chan os.Signal
+	if handleSignals {
+		var stopSignals func()
+		sigCh, stopSignals = notifyShutdown()
+		defer stopSignals()
+	}
 
-	if s.tlsCertFile != "" {
-		s.logger.Info("server starting (tls)", "addr", ln.Addr().String())
-	} else {
-		s.logger.Info("server starting", "addr", ln.Addr().String())
+	var upgradeSigCh chan os.Signal
+	if s.upgradeEnabled && len(s.upgradeSignals) > 0 {
+		upgradeSigCh = make(chan os.Signal, 1)
+		signal.Notify(upgradeSigCh, s.upgradeSignals...)
+		defer signal.Stop(upgradeSigCh)
 	}
 
+	// Start listening
+	if err := s.listen(eps); err != nil {
+		return err
+	}
+
+	addrs := make(map[string]net.Addr, len(eps))
+	for _, ep := range eps {
+		addrs[ep.name] = ep.ln.Addr()
+	}
+	s.listenAddr.Store(eps[0].ln.Addr())
+	s.addrs.Store(addrs)
+
 	// Serve in background
-	errCh := make(chan error, 1)
-	go func() {
-		var serveErr error
-		if s.tlsCertFile != "" {
-			serveErr = s.httpServer.ServeTLS(ln, s.tlsCertFile, s.tlsKeyFile)
-		} else {
-			serveErr = s.httpServer.Serve(ln)
-		}
-		if serveErr != nil && serveErr != http.ErrServerClosed {
-			errCh <- serveErr
+	errCh := make(chan error, len(eps))
+	for _, ep := range eps {
+		s.logger.Info("server starting", "endpoint", ep.name, "addr", ep.ln.Addr().String())
+		go func(ep *endpoint) {
+			if err := s.serve(ep); err != nil && err != http.ErrServerClosed {
+				errCh <- err
+			}
+		}(ep)
+	}
+	s.notifyReady()
+	close(s.ready)
+
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	if s.tlsReloadInterval > 0 {
+		for _, ep := range eps {
+			if ep.certs != nil {
+				go ep.certs.watch(watchCtx, s.tlsReloadInterval, s)
+			}
 		}
-		close(errCh)
-	}()
+	}
 
 	// Wait for signal or programmatic shutdown
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-	defer signal.Stop(sigCh)
-
-	select {
-	case sig := <-sigCh:
-		s.logger.Info("shutdown signal received", "signal", sig.String())
-	case err := <-errCh:
-		if err != nil {
+	upgraded := false
+wait:
+	for {
+		select {
+		case sig := <-sigCh:
+			s.logger.Info("shutdown signal received", "signal", sig.String())
+			break wait
+		case sig := <-upgradeSigCh:
+			s.logger.Info("upgrade signal received", "signal", sig.String())
+			if err := s.upgrade(eps); err != nil {
+				s.logger.Error("upgrade failed", "error", err)
+				continue
+			}
+			upgraded = true
+			break wait
+		case reply := <-s.upgradeCh:
+			err := s.upgrade(eps)
+			reply <- err
+			if err != nil {
+				s.logger.Error("upgrade failed", "error", err)
+				continue
+			}
+			upgraded = true
+			break wait
+		case err := <-errCh:
+			_ = shutdownAll(context.Background(), eps)
 			return err
+		case <-s.shutdownCh:
+			s.logger.Info("shutdown signal received", "signal", "programmatic")
+			break wait
+		case <-ctx.Done():
+			s.logger.Info("shutdown signal received", "signal", "context")
+			break wait
 		}
-	case <-s.shutdownCh:
-		s.logger.Info("shutdown signal received", "signal", "programmatic")
+	}
+
+	// Fail readiness and let load balancers notice. After an upgrade the
+	// child already serves the same sockets, so there is nothing to drain.
+	if !upgraded {
+		s.preStop(sigCh)
 	}
 
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
 	defer cancel()
 
-	if err := s.httpServer.Shutdown(ctx); err != nil {
+	conns := s.Connections()
+	s.logger.Info("shutting down", "active", conns.Active, "streaming", conns.Streaming, "idle", conns.Idle, "hijacked", conns.Hijacked)
+	close(s.stopping)
+	if err := shutdownAll(ctx, eps); err != nil {
 		s.logger.Error("server shutdown error", "error", err)
 		return err
 	}
+	s.waitHijacked(ctx)
 
 	// Run OnShutdown hooks
 	for _, fn := range s.onShutdown {
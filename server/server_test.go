@@ -20,6 +20,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/KARTIKrocks/apikit/health"
 	"github.com/KARTIKrocks/apikit/server"
 )
 
@@ -164,6 +165,43 @@ func TestServer_OnShutdownHook(t *testing.T) {
 	}
 }
 
+func TestServer_RegisterOnShutdown_StopsLongLivedHandler(t *testing.T) {
+	t.Parallel()
+	stop := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+		w.(http.Flusher).Flush()
+		<-stop // a stream that only ends when told to
+	})
+	srv := server.New(handler, server.WithAddr(":0"), server.WithShutdownTimeout(5*time.Second))
+	srv.RegisterOnShutdown(func() { close(stop) })
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Start()
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	resp, err := http.Get("http://" + srv.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown error: %v", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("start returned error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("shutdown waited %v for the stream to drain", elapsed)
+	}
+}
+
 func TestServer_MultipleHooks(t *testing.T) {
 	t.Parallel()
 	handler := http.NewServeMux()
@@ -303,3 +341,332 @@ func TestServer_TLS(t *testing.T) {
 		t.Fatal("server did not stop in time")
 	}
 }
+
+// startServer starts srv and registers a cleanup that shuts it down.
+func startServer(t *testing.T, srv *server.Server) {
+	t.Helper()
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Start() }()
+	deadline := time.Now().Add(5 * time.Second)
+	for srv.Addrs() == nil {
+		select {
+		case err := <-errCh:
+			t.Fatalf("start returned error: %v", err)
+		default:
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("server did not start in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			t.Errorf("shutdown error: %v", err)
+		}
+		if err := <-errCh; err != nil {
+			t.Errorf("start returned error: %v", err)
+		}
+	})
+}
+
+func TestServer_HTTPRedirect(t *testing.T) {
+	t.Parallel()
+	certFile, keyFile := generateTestCert(t)
+	srv := server.New(http.NewServeMux(),
+		server.WithAddr("127.0.0.1:0"),
+		server.WithTLS(certFile, keyFile),
+		server.WithHTTPRedirect("127.0.0.1:0"),
+	)
+	startServer(t, srv)
+
+	addrs := srv.Addrs()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Post("http://"+addrs[server.EndpointRedirect].String()+"/orders?page=2", "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	_, port, _ := net.SplitHostPort(addrs[server.EndpointHTTPS].String())
+	want := "https://127.0.0.1:" + port + "/orders?page=2"
+	if resp.StatusCode != http.StatusPermanentRedirect || resp.Header.Get("Location") != want {
+		t.Fatalf("expected 308 to %s, got %d %s", want, resp.StatusCode, resp.Header.Get("Location"))
+	}
+}
+
+func TestServer_HTTPRedirectTarget(t *testing.T) {
+	t.Parallel()
+	certFile, keyFile := generateTestCert(t)
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{":443", "https://example.com/a?b=1"},
+		{":8443", "https://example.com:8443/a?b=1"},
+		{"public.example.com", "https://public.example.com/a?b=1"},
+		{"public.example.com:9443", "https://public.example.com:9443/a?b=1"},
+	}
+	for _, tt := range tests {
+		srv := server.New(http.NewServeMux(),
+			server.WithAddr("127.0.0.1:0"),
+			server.WithTLS(certFile, keyFile),
+			server.WithHTTPRedirect("127.0.0.1:0"),
+			server.WithRedirectTarget(tt.target),
+		)
+		startServer(t, srv)
+
+		req, _ := http.NewRequest(http.MethodGet, "http://"+srv.Addrs()[server.EndpointRedirect].String()+"/a?b=1", nil)
+		req.Host = "example.com:8080"
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if got := resp.Header.Get("Location"); got != tt.want {
+			t.Errorf("target %q: expected redirect to %s, got %s", tt.target, tt.want, got)
+		}
+	}
+}
+
+func TestServer_HTTPRedirectRequiresTLS(t *testing.T) {
+	t.Parallel()
+	srv := server.New(http.NewServeMux(), server.WithAddr("127.0.0.1:0"), server.WithHTTPRedirect("127.0.0.1:0"))
+	if err := srv.Start(); err == nil {
+		t.Fatal("expected an error without WithTLS")
+	}
+}
+
+func TestServer_UnixSocket(t *testing.T) {
+	t.Parallel()
+	// Socket paths are limited to ~100 bytes, which t.TempDir can exceed.
+	dir, err := os.MkdirTemp("", "srv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "api.sock")
+	// A stale socket file from a crashed process must not prevent startup.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	srv := server.New(handler, server.WithAddr("127.0.0.1:0"), server.WithUnixSocket(path))
+	startServer(t, srv)
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://unix/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "ok" {
+		t.Fatalf("expected ok over the unix socket, got %q", body)
+	}
+}
+
+func TestServer_UnixSocketInUse(t *testing.T) {
+	t.Parallel()
+	dir, err := os.MkdirTemp("", "srv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "api.sock")
+	// A socket another process is still serving on must be left alone.
+	live, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	defer live.Close()
+
+	srv := server.New(http.NotFoundHandler(), server.WithAddr("127.0.0.1:0"), server.WithUnixSocket(path))
+	if err := srv.Start(); err == nil {
+		t.Fatal("expected Start to fail on a socket in use")
+	}
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("live socket was removed: %v", err)
+	}
+	conn.Close()
+}
+
+func TestServer_ReadinessDrain(t *testing.T) {
+	t.Parallel()
+	checker := health.NewChecker()
+	ready := checker.Handler()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) { _ = ready(w, r) })
+	mux.HandleFunc("/work", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("ok")) })
+
+	srv := server.New(mux,
+		server.WithAddr("127.0.0.1:0"),
+		server.WithHealthChecker(checker),
+		server.WithPreStopDelay(300*time.Millisecond),
+	)
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Start() }()
+	time.Sleep(50 * time.Millisecond)
+	base := "http://" + srv.Addr().String()
+
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	status := func(path string) int {
+		resp, err := client.Get(base + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := status("/ready"); code != http.StatusOK {
+		t.Fatalf("expected ready before shutdown, got %d", code)
+	}
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		done <- srv.Shutdown(ctx)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// During the pre-stop delay readiness fails but traffic is still served.
+	if code := status("/ready"); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected readiness to fail while draining, got %d", code)
+	}
+	if code := status("/work"); code != http.StatusOK {
+		t.Fatalf("expected requests to be served while draining, got %d", code)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Fatalf("expected the pre-stop delay to be honoured, stopped after %v", elapsed)
+	}
+}
+
+func TestServer_StoppingNotifiesStreams(t *testing.T) {
+	t.Parallel()
+	hijackClosed := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stream", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-server.Stopping(r.Context())
+	})
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		go func() {
+			<-server.Stopping(r.Context())
+			conn.Close()
+			close(hijackClosed)
+		}()
+	})
+	srv := server.New(mux, server.WithAddr("127.0.0.1:0"))
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Start() }()
+	time.Sleep(50 * time.Millisecond)
+	addr := srv.Addr().String()
+
+	req, _ := http.NewRequest(http.MethodGet, "http://"+addr+"/stream", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	ws, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	fmt.Fprintf(ws, "GET /ws HTTP/1.1\r\nHost: %s\r\n\r\n", addr)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		c := srv.Connections()
+		if c.Streaming == 1 && c.Active == 0 && c.Hijacked == 1 && c.Open == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected connection stats %+v", c)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-hijackClosed:
+	default:
+		t.Fatal("expected shutdown to wait for the hijacked connection")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("shutdown took %v; streams were not notified", elapsed)
+	}
+	if c := srv.Connections(); c.Open != 0 || c.Hijacked != 0 {
+		t.Fatalf("expected all connections closed, got %+v", c)
+	}
+}
+
+func TestServer_ConnectionsNotWrapped(t *testing.T) {
+	t.Parallel()
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if _, ok := conn.(*net.TCPConn); ok {
+			_, _ = conn.Write([]byte("tcp"))
+		} else {
+			fmt.Fprintf(conn, "%T", conn)
+		}
+	})
+	srv := server.New(handler, server.WithAddr("127.0.0.1:0"))
+	startServer(t, srv)
+
+	conn, err := net.Dial("tcp", srv.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: test\r\n\r\n")
+	got, _ := io.ReadAll(conn)
+	if string(got) != "tcp" {
+		t.Fatalf("hijacked connection is %s, want *net.TCPConn", got)
+	}
+}
@@ -0,0 +1,205 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// certPair is a certificate and key file configured by WithTLS.
+type certPair struct {
+	certFile string
+	keyFile  string
+}
+
+// WithTLS enables HTTPS with the given certificate and key files. Call it
+// again to add certificates for other host names: the certificate is chosen
+// by the client's SNI server name, with the first one as the default.
+func WithTLS(certFile, keyFile string) Option {
+	return func(s *Server) {
+		s.tlsCerts = append(s.tlsCerts, certPair{certFile: certFile, keyFile: keyFile})
+	}
+}
+
+// WithTLSConfig sets the base TLS configuration, e.g. MinVersion or
+// CipherSuites. Certificates and client authentication are filled in from
+// WithTLS and WithClientCA.
+func WithTLSConfig(cfg *tls.Config) Option {
+	return func(s *Server) {
+		s.tlsBase = cfg
+	}
+}
+
+// WithTLSReload polls the certificate, key, and client CA files every
+// interval and reloads them when they change, so renewed certificates are
+// served without a restart. A reload that fails is logged and the previous
+// certificates stay in use.
+func WithTLSReload(interval time.Duration) Option {
+	return func(s *Server) {
+		s.tlsReloadInterval = interval
+	}
+}
+
+// WithClientCA enables mutual TLS: client certificates are requested and
+// verified against the PEM-encoded CAs in caFile according to mode, e.g.
+// tls.RequireAndVerifyClientCert, or tls.VerifyClientCertIfGiven to let
+// handlers decide. Use middleware.ClientCert to access the verified identity.
+func WithClientCA(caFile string, mode tls.ClientAuthType) Option {
+	return func(s *Server) {
+		s.clientCAFile = caFile
+		s.clientAuth = mode
+	}
+}
+
+// fileStamp identifies a version of a file for change detection.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// certStore holds the current certificates and client CA pool and reloads
+// them from disk.
+type certStore struct {
+	pairs      []certPair
+	caFile     string
+	clientAuth tls.ClientAuthType
+	base       *tls.Config
+
+	mu     sync.RWMutex
+	certs  []*tls.Certificate
+	config *tls.Config // per-connection config carrying the current CA pool
+	stamps map[string]fileStamp
+}
+
+// newCertStore loads the configured files.
+func (s *Server) newCertStore() (*certStore, error) {
+	base := s.tlsBase
+	if base == nil {
+		base = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	cs := &certStore{pairs: s.tlsCerts, caFile: s.clientCAFile, clientAuth: s.clientAuth, base: base}
+	if err := cs.load(); err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+// load reads all files and swaps them in atomically.
+func (cs *certStore) load() error {
+	stamps := make(map[string]fileStamp)
+	certs := make([]*tls.Certificate, 0, len(cs.pairs))
+	for _, p := range cs.pairs {
+		cert, err := tls.LoadX509KeyPair(p.certFile, p.keyFile)
+		if err != nil {
+			return fmt.Errorf("server: load certificate %s: %w", p.certFile, err)
+		}
+		certs = append(certs, &cert)
+		stamps[p.certFile], stamps[p.keyFile] = stat(p.certFile), stat(p.keyFile)
+	}
+
+	var config *tls.Config
+	if cs.caFile != "" {
+		pem, err := os.ReadFile(cs.caFile)
+		if err != nil {
+			return fmt.Errorf("server: load client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("server: load client CA %s: no certificates found", cs.caFile)
+		}
+		stamps[cs.caFile] = stat(cs.caFile)
+
+		config = cs.base.Clone()
+		config.GetCertificate = cs.getCertificate
+		config.ClientAuth = cs.clientAuth
+		config.ClientCAs = pool
+		if len(config.NextProtos) == 0 {
+			config.NextProtos = []string{"h2", "http/1.1"}
+		}
+	}
+
+	cs.mu.Lock()
+	cs.certs, cs.config, cs.stamps = certs, config, stamps
+	cs.mu.Unlock()
+	return nil
+}
+
+// changed reports whether any file differs from when it was loaded.
+func (cs *certStore) changed() bool {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	for path, st := range cs.stamps {
+		if stat(path) != st {
+			return true
+		}
+	}
+	return false
+}
+
+// watch reloads changed files every interval until ctx is done.
+func (cs *certStore) watch(ctx context.Context, interval time.Duration, s *Server) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !cs.changed() {
+				continue
+			}
+			if err := cs.load(); err != nil {
+				s.logger.Error("tls reload failed", "error", err)
+				continue
+			}
+			s.logger.Info("tls certificates reloaded")
+		}
+	}
+}
+
+// tlsConfig returns the server's TLS configuration.
+func (cs *certStore) tlsConfig() *tls.Config {
+	cfg := cs.base.Clone()
+	cfg.GetCertificate = cs.getCertificate
+	if cs.caFile != "" {
+		cfg.ClientAuth = cs.clientAuth
+		cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cs.mu.RLock()
+			defer cs.mu.RUnlock()
+			return cs.config, nil
+		}
+	}
+	return cfg
+}
+
+// getCertificate selects the first certificate valid for the client's SNI
+// name and signature schemes, falling back to the first certificate.
+func (cs *certStore) getCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	if len(cs.certs) == 0 {
+		return nil, errors.New("server: no certificates")
+	}
+	if len(cs.certs) > 1 {
+		for _, cert := range cs.certs {
+			if hello.SupportsCertificate(cert) == nil {
+				return cert, nil
+			}
+		}
+	}
+	return cs.certs[0], nil
+}
+
+// stat returns the file's stamp, or the zero stamp if it cannot be read.
+func stat(path string) fileStamp {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: fi.ModTime(), size: fi.Size()}
+}
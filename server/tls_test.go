@@ -0,0 +1,219 @@
+package server_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/KARTIKrocks/apikit/middleware"
+	"github.com/KARTIKrocks/apikit/server"
+)
+
+// testCA issues certificates for tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	file string
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	file := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{cert: cert, key: key, file: file}
+}
+
+// issue writes a certificate for cn to dir and returns the file paths.
+func (ca *testCA) issue(t *testing.T, dir, cn string, usage x509.ExtKeyUsage, modify func(*x509.Certificate)) (certFile, keyFile string) {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{cn},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	if modify != nil {
+		modify(tmpl)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	certFile, keyFile = filepath.Join(dir, cn+".pem"), filepath.Join(dir, cn+"-key.pem")
+	// Write the key first so a reload never pairs a new cert with an old key.
+	writeFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	writeFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	return certFile, keyFile
+}
+
+// writeFile replaces path atomically.
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// servedCert returns the leaf certificate presented for serverName.
+func servedCert(t *testing.T, addr net.Addr, serverName string) *x509.Certificate {
+	t.Helper()
+	conn, err := tls.Dial("tcp", addr.String(), &tls.Config{ServerName: serverName, InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0]
+}
+
+func TestServer_TLSReload(t *testing.T) {
+	t.Parallel()
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	certFile, keyFile := ca.issue(t, dir, "api.test", x509.ExtKeyUsageServerAuth, nil)
+
+	srv := server.New(http.NewServeMux(),
+		server.WithAddr("127.0.0.1:0"),
+		server.WithTLS(certFile, keyFile),
+		server.WithTLSReload(10*time.Millisecond),
+	)
+	startServer(t, srv)
+	before := servedCert(t, srv.Addr(), "api.test")
+
+	// Renew: same paths, new certificate. Bump the mtime in case the
+	// filesystem's timestamp resolution hides the change.
+	ca.issue(t, dir, "api.test", x509.ExtKeyUsageServerAuth, nil)
+	future := time.Now().Add(time.Second)
+	_ = os.Chtimes(certFile, future, future)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		after := servedCert(t, srv.Addr(), "api.test")
+		if after.SerialNumber.Cmp(before.SerialNumber) != 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the renewed certificate to be served")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServer_SNI(t *testing.T) {
+	t.Parallel()
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	aCert, aKey := ca.issue(t, dir, "a.test", x509.ExtKeyUsageServerAuth, nil)
+	bCert, bKey := ca.issue(t, dir, "b.test", x509.ExtKeyUsageServerAuth, nil)
+
+	srv := server.New(http.NewServeMux(),
+		server.WithAddr("127.0.0.1:0"),
+		server.WithTLS(aCert, aKey),
+		server.WithTLS(bCert, bKey),
+	)
+	startServer(t, srv)
+
+	for name, want := range map[string]string{"a.test": "a.test", "b.test": "b.test", "other.test": "a.test"} {
+		if got := servedCert(t, srv.Addr(), name).Subject.CommonName; got != want {
+			t.Errorf("SNI %s: expected %s, got %s", name, want, got)
+		}
+	}
+}
+
+func TestServer_MutualTLS(t *testing.T) {
+	t.Parallel()
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	serverCert, serverKey := ca.issue(t, dir, "api.test", x509.ExtKeyUsageServerAuth, nil)
+	clientCert, clientKey := ca.issue(t, dir, "billing", x509.ExtKeyUsageClientAuth, func(c *x509.Certificate) {
+		c.URIs = []*url.URL{{Scheme: "spiffe", Host: "example.org", Path: "/billing"}}
+	})
+
+	handler := middleware.ClientCert(middleware.ClientCertConfig{Required: true})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, _ := middleware.GetClientIdentity(r.Context())
+			_, _ = w.Write([]byte(id.Subject.CommonName + " " + id.URIs[0].String()))
+		}))
+	srv := server.New(handler,
+		server.WithAddr("127.0.0.1:0"),
+		server.WithTLS(serverCert, serverKey),
+		server.WithClientCA(ca.file, tls.RequireAndVerifyClientCert),
+	)
+	startServer(t, srv)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: &http.Transport{ForceAttemptHTTP2: true, TLSClientConfig: &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{cert},
+	}}}
+	url := "https://" + srv.Addr().String()
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "billing spiffe://example.org/billing" {
+		t.Fatalf("unexpected identity %q", body)
+	}
+	if resp.ProtoMajor != 2 {
+		t.Errorf("expected HTTP/2 to be negotiated, got %s", resp.Proto)
+	}
+
+	anon := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if resp, err := anon.Do(req); err == nil {
+		resp.Body.Close()
+		t.Fatal("expected the handshake to fail without a client certificate")
+	} else if errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("expected a handshake error, not a timeout")
+	}
+}
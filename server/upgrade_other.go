@@ -0,0 +1,22 @@
+//go:build !unix
+
+package server
+
+import (
+	"net"
+	"os"
+	"time"
+)
+
+// defaultUpgradeSignals is empty: this platform has no upgrade signals.
+var defaultUpgradeSignals []os.Signal
+
+// listenerFile is unsupported on this platform.
+func listenerFile(net.Listener) (*os.File, error) {
+	return nil, ErrUpgradeUnsupported
+}
+
+// startChild is unsupported on this platform.
+func startChild([]*os.File, time.Duration) (int, error) {
+	return 0, ErrUpgradeUnsupported
+}
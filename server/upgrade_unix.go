@@ -0,0 +1,100 @@
+//go:build unix
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// defaultUpgradeSignals trigger a graceful upgrade when WithGracefulUpgrade
+// is given no signals.
+var defaultUpgradeSignals = []os.Signal{syscall.SIGHUP, syscall.SIGUSR2}
+
+// listenerFile duplicates ln's descriptor for passing to a child. Unlike
+// (*net.TCPListener).File it leaves the socket in non-blocking mode, which
+// the parent still needs to stop accepting during shutdown.
+func listenerFile(ln net.Listener) (*os.File, error) {
+	sc, ok := ln.(syscall.Conn)
+	if !ok {
+		return nil, fmt.Errorf("listener %T cannot be passed to a child", ln)
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	var dup int
+	var dupErr error
+	err = raw.Control(func(fd uintptr) {
+		syscall.ForkLock.RLock()
+		defer syscall.ForkLock.RUnlock()
+		dup, dupErr = syscall.Dup(int(fd))
+		if dupErr == nil {
+			syscall.CloseOnExec(dup)
+		}
+	})
+	if err == nil {
+		err = dupErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return os.NewFile(uintptr(dup), "listener"), nil
+}
+
+// startChild re-executes the current binary with files as inherited
+// listeners and returns the child's PID once it reports that it is serving.
+// A child that exits or misses the timeout is killed and reaped.
+func startChild(files []*os.File, timeout time.Duration) (int, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	procFiles := append([]*os.File{os.Stdin, os.Stdout, os.Stderr}, files...)
+	procFiles = append(procFiles, w)
+	p, err := os.StartProcess(exe, os.Args, &os.ProcAttr{
+		Env:   childEnv(len(files)),
+		Files: procFiles,
+	})
+	_ = w.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	readyCh := make(chan error, 1)
+	go func() {
+		var b [1]byte
+		_, err := r.Read(b[:])
+		if errors.Is(err, io.EOF) {
+			err = errors.New("child exited before it was ready")
+		}
+		readyCh <- err
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err = <-readyCh:
+	case <-timer.C:
+		err = fmt.Errorf("child not ready after %v", timeout)
+	}
+	if err != nil {
+		_ = p.Kill()
+		_, _ = p.Wait()
+		return 0, err
+	}
+	pid := p.Pid
+	_ = p.Release()
+	return pid, nil
+}
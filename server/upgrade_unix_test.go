@@ -0,0 +1,179 @@
+//go:build unix
+
+package server_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/KARTIKrocks/apikit/server"
+)
+
+// helperEnv makes the test binary act as a server process for the tests
+// below instead of running tests.
+const helperEnv = "SERVER_TEST_HELPER"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(helperEnv); mode != "" {
+		os.Exit(runHelper(mode))
+	}
+	os.Exit(m.Run())
+}
+
+func runHelper(mode string) int {
+	opts := []server.Option{server.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))}
+	switch mode {
+	case "upgrade":
+		opts = append(opts, server.WithAddr(":0"), server.WithGracefulUpgrade())
+	case "activation":
+		// systemd sets LISTEN_PID to the PID of the activated process.
+		_ = os.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+		opts = append(opts, server.WithAddr("invalid address"), server.WithSocketActivation())
+	default:
+		return 1
+	}
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %d", mode, os.Getpid())
+	})
+	if err := server.New(handler, opts...).Start(); err != nil {
+		return 1
+	}
+	return 0
+}
+
+// getBody polls url until it answers or the deadline passes.
+func getBody(t *testing.T, url string) string {
+	t.Helper()
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		resp, err := client.Get(url)
+		if err == nil {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return string(body)
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("GET %s: %v", url, err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// stopHelper terminates the helper process whose PID is in body.
+func stopHelper(t *testing.T, body string) {
+	t.Helper()
+	_, pidStr, _ := strings.Cut(body, " ")
+	pid, err := strconv.Atoi(pidStr)
+	if err != nil {
+		t.Fatalf("unexpected helper response %q", body)
+	}
+	_ = syscall.Kill(pid, syscall.SIGTERM)
+}
+
+func TestServer_SocketActivation(t *testing.T) {
+	t.Parallel()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	f, err := ln.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), helperEnv+"=activation", "LISTEN_FDS=1", "LISTEN_FDNAMES=http")
+	cmd.ExtraFiles = []*os.File{f}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	body := getBody(t, "http://"+ln.Addr().String())
+	if !strings.HasPrefix(body, "activation ") {
+		t.Fatalf("expected the activated child to serve, got %q", body)
+	}
+	stopHelper(t, body)
+	if err := cmd.Wait(); err != nil {
+		t.Fatalf("helper exited with %v", err)
+	}
+}
+
+func TestServer_GracefulUpgrade(t *testing.T) {
+	t.Setenv(helperEnv, "upgrade")
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("parent"))
+	})
+	srv := server.New(handler, server.WithAddr("127.0.0.1:0"), server.WithGracefulUpgrade(),
+		server.WithUpgradeTimeout(0), // keeps the 30s default instead of timing out at once
+		server.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Start() }()
+	time.Sleep(50 * time.Millisecond)
+	url := "http://" + srv.Addr().String()
+	if body := getBody(t, url); body != "parent" {
+		t.Fatalf("expected the parent to serve, got %q", body)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := srv.Upgrade(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("parent returned error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("parent did not stop after the upgrade")
+	}
+
+	// The same address is now served by the child.
+	body := getBody(t, url)
+	if !strings.HasPrefix(body, "upgrade ") {
+		t.Fatalf("expected the child to serve, got %q", body)
+	}
+	stopHelper(t, body)
+}
+
+func TestServer_GracefulUpgrade_ChildFails(t *testing.T) {
+	t.Setenv(helperEnv, "fail")
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("parent"))
+	})
+	srv := server.New(handler, server.WithAddr("127.0.0.1:0"), server.WithGracefulUpgrade(),
+		server.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.Start() }()
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := srv.Upgrade(ctx); err == nil {
+		t.Fatal("expected the upgrade to fail")
+	}
+	if body := getBody(t, "http://"+srv.Addr().String()); body != "parent" {
+		t.Fatalf("expected the parent to keep serving, got %q", body)
+	}
+
+	_ = srv.Shutdown(ctx)
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,443 @@
+// Package sse provides a Server-Sent Events broker with per-topic fan-out,
+// monotonically increasing event IDs, a bounded replay buffer for
+// Last-Event-ID reconnects, and periodic heartbeats.
+//
+// For a single one-off stream, response.Stream is simpler. Use a Broker when
+// many clients subscribe to the same events and must survive reconnects.
+//
+// Usage:
+//
+//	b := sse.NewBroker(
+//	    sse.WithReplaySize(256),
+//	    sse.WithHeartbeat(15 * time.Second),
+//	)
+//	defer b.Close()
+//
+//	r.Get("/events/{room}", b.Handler(func(r *http.Request) string {
+//	    return r.PathValue("room")
+//	}))
+//
+//	b.PublishJSON("lobby", "message", msg)
+//
+//	// Detach subscribers as soon as shutdown begins.
+//	srv.RegisterOnShutdown(b.Close)
+package sse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KARTIKrocks/apikit/response"
+)
+
+// ErrClosed is returned when publishing to or subscribing on a closed Broker.
+var ErrClosed = errors.New("sse: broker closed")
+
+// ErrInvalidEvent is returned by Publish when the event type contains a line
+// break, which would let it inject fields into the stream.
+var ErrInvalidEvent = errors.New("sse: event type contains a line break")
+
+// lineBreaks matches the SSE line terminators: CRLF, CR, and LF.
+var lineBreaks = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// Event is a single Server-Sent Event.
+type Event struct {
+	// ID is assigned by the Broker on publish.
+	ID uint64
+	// Event is the event type. Empty means the default "message" type.
+	// Line breaks are removed when writing.
+	Event string
+	// Data is the payload. Multi-line data is split into several data fields
+	// at CRLF, CR, or LF, the line terminators of the SSE format.
+	Data string
+}
+
+// WriteTo writes the event in text/event-stream wire format.
+func (e Event) WriteTo(w io.Writer) (int64, error) {
+	var sb strings.Builder
+	if e.ID != 0 {
+		sb.WriteString("id: ")
+		sb.WriteString(strconv.FormatUint(e.ID, 10))
+		sb.WriteByte('\n')
+	}
+	if e.Event != "" {
+		sb.WriteString("event: ")
+		sb.WriteString(strings.NewReplacer("\r", "", "\n", "").Replace(e.Event))
+		sb.WriteByte('\n')
+	}
+	for _, line := range strings.Split(lineBreaks.Replace(e.Data), "\n") {
+		sb.WriteString("data: ")
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+	}
+	sb.WriteByte('\n')
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+// topic holds the subscribers and replay buffer of one topic.
+type topic struct {
+	subs   map[*subscriber]struct{}
+	replay []Event // bounded to replaySize, ordered by ID, oldest first
+
+	idleSince time.Time   // when the last subscriber left
+	idle      *time.Timer // evicts the topic after idleTTL without subscribers
+}
+
+// subscriber is a single connected client.
+type subscriber struct {
+	ch      chan Event
+	dropped chan struct{} // closed when the broker detaches a slow client
+}
+
+// Broker fans published events out to subscribers grouped by topic.
+type Broker struct {
+	mu     sync.Mutex
+	topics map[string]*topic
+	lastID uint64
+	closed bool
+	done   chan struct{}
+
+	replaySize int
+	idleTTL    time.Duration
+	heartbeat  time.Duration
+	retry      time.Duration
+	bufferSize int
+	logger     *slog.Logger
+}
+
+// Option configures a Broker.
+type Option func(*Broker)
+
+// WithReplaySize sets how many recent events are kept per topic for
+// Last-Event-ID replay (default 100). Zero disables replay.
+func WithReplaySize(n int) Option {
+	return func(b *Broker) {
+		if n < 0 {
+			n = 0
+		}
+		b.replaySize = n
+	}
+}
+
+// WithIdleTopicTTL sets how long a topic's replay buffer is kept after its
+// last subscriber leaves (default 5 minutes), so clients reconnecting within
+// that window still catch up. Idle topics are then removed, which bounds
+// memory when many short-lived topics are used. Zero or negative removes a
+// topic as soon as it has no subscribers; events published to it meanwhile
+// are not kept.
+func WithIdleTopicTTL(d time.Duration) Option {
+	return func(b *Broker) { b.idleTTL = d }
+}
+
+// WithHeartbeat sets the interval between comment heartbeats that keep idle
+// connections open through proxies (default 15s). Zero disables heartbeats.
+func WithHeartbeat(d time.Duration) Option {
+	return func(b *Broker) { b.heartbeat = d }
+}
+
+// WithRetry sets the reconnection delay sent to clients in the "retry:" field
+// when they connect. Zero (the default) leaves the browser default in place.
+func WithRetry(d time.Duration) Option {
+	return func(b *Broker) { b.retry = d }
+}
+
+// WithBufferSize sets the per-subscriber event buffer (default 64). A client
+// that falls this far behind is disconnected and can catch up on reconnect
+// via Last-Event-ID, so one slow reader never blocks publishers.
+func WithBufferSize(n int) Option {
+	return func(b *Broker) {
+		if n < 1 {
+			n = 1
+		}
+		b.bufferSize = n
+	}
+}
+
+// WithLogger sets the structured logger (default slog.Default()).
+func WithLogger(logger *slog.Logger) Option {
+	return func(b *Broker) {
+		if logger != nil {
+			b.logger = logger
+		} else {
+			b.logger = slog.Default()
+		}
+	}
+}
+
+// NewBroker creates a new Broker with the given options.
+func NewBroker(opts ...Option) *Broker {
+	b := &Broker{
+		topics:     make(map[string]*topic),
+		done:       make(chan struct{}),
+		replaySize: 100,
+		idleTTL:    5 * time.Minute,
+		heartbeat:  15 * time.Second,
+		bufferSize: 64,
+		logger:     slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Publish sends an event to every subscriber of the topic and records it in
+// the replay buffer. It returns the assigned event ID. An event type
+// containing CR or LF is rejected with ErrInvalidEvent.
+func (b *Broker) Publish(topicName, event, data string) (uint64, error) {
+	if strings.ContainsAny(event, "\r\n") {
+		return 0, ErrInvalidEvent
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return 0, ErrClosed
+	}
+
+	b.lastID++
+	ev := Event{ID: b.lastID, Event: event, Data: data}
+
+	t := b.topicLocked(topicName)
+	if b.replaySize > 0 {
+		if len(t.replay) >= b.replaySize {
+			copy(t.replay, t.replay[1:])
+			t.replay = t.replay[:len(t.replay)-1]
+		}
+		t.replay = append(t.replay, ev)
+	}
+
+	for s := range t.subs {
+		select {
+		case s.ch <- ev:
+		default:
+			// Slow consumer: detach it rather than block every publisher.
+			delete(t.subs, s)
+			close(s.dropped)
+			b.logger.Warn("sse subscriber dropped: buffer full", "topic", topicName)
+		}
+	}
+	if len(t.subs) == 0 {
+		b.idleLocked(topicName, t)
+	}
+
+	return ev.ID, nil
+}
+
+// PublishJSON is like Publish but JSON-encodes data.
+func (b *Broker) PublishJSON(topicName, event string, data any) (uint64, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return 0, err
+	}
+	return b.Publish(topicName, event, string(raw))
+}
+
+// Subscribers returns the number of clients connected to the topic.
+func (b *Broker) Subscribers(topicName string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t, ok := b.topics[topicName]; ok {
+		return len(t.subs)
+	}
+	return 0
+}
+
+// Close detaches all subscribers and rejects further publishes. Handlers
+// return promptly, so Close is suitable for server.Server.RegisterOnShutdown.
+// It is safe to call more than once.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	close(b.done)
+	for _, t := range b.topics {
+		if t.idle != nil {
+			t.idle.Stop()
+		}
+	}
+	b.topics = make(map[string]*topic)
+}
+
+// Handler returns an HTTP handler that subscribes the client to the topic
+// chosen by topicFn. Compatible with router.HandlerFunc (returns error).
+func (b *Broker) Handler(topicFn func(r *http.Request) string) func(http.ResponseWriter, *http.Request) error {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		return b.Serve(w, r, topicFn(r))
+	}
+}
+
+// Serve streams the topic to the client until it disconnects, the Broker is
+// closed, or the client falls too far behind. If the request carries a
+// Last-Event-ID header, buffered events newer than that ID are replayed first.
+func (b *Broker) Serve(w http.ResponseWriter, r *http.Request, topicName string) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		response.InternalServerError(w, "Streaming not supported")
+		return nil
+	}
+
+	var lastID uint64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		// An unparsable ID is treated as a fresh connection.
+		lastID, _ = strconv.ParseUint(strings.TrimSpace(v), 10, 64)
+	}
+
+	sub, backlog, err := b.subscribe(topicName, lastID)
+	if err != nil {
+		response.ServiceUnavailable(w, "Event stream is shutting down")
+		return nil
+	}
+	defer b.unsubscribe(topicName, sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx buffering
+	w.WriteHeader(http.StatusOK)
+
+	if b.retry > 0 {
+		if _, err := fmt.Fprintf(w, "retry: %d\n\n", b.retry.Milliseconds()); err != nil {
+			return nil
+		}
+	}
+	for _, ev := range backlog {
+		if _, err := ev.WriteTo(w); err != nil {
+			return nil
+		}
+	}
+	flusher.Flush()
+
+	var heartbeat <-chan time.Time
+	if b.heartbeat > 0 {
+		ticker := time.NewTicker(b.heartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	for {
+		select {
+		case ev := <-sub.ch:
+			if _, err := ev.WriteTo(w); err != nil {
+				return nil
+			}
+			flusher.Flush()
+		case <-heartbeat:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+			flusher.Flush()
+		case <-sub.dropped:
+			return nil
+		case <-b.done:
+			return nil
+		case <-r.Context().Done():
+			return nil
+		}
+	}
+}
+
+// subscribe registers a subscriber and snapshots the replay backlog under the
+// same lock as Publish, so no event is either missed or delivered twice.
+func (b *Broker) subscribe(topicName string, lastID uint64) (*subscriber, []Event, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return nil, nil, ErrClosed
+	}
+
+	t := b.topicLocked(topicName)
+	if t.idle != nil {
+		t.idle.Stop()
+	}
+	sub := &subscriber{
+		ch:      make(chan Event, b.bufferSize),
+		dropped: make(chan struct{}),
+	}
+	t.subs[sub] = struct{}{}
+
+	var backlog []Event
+	if lastID > 0 {
+		for _, ev := range t.replay {
+			if ev.ID > lastID {
+				backlog = append(backlog, ev)
+			}
+		}
+	}
+	return sub, backlog, nil
+}
+
+// unsubscribe removes the subscriber and starts evicting the topic once it
+// has none left.
+func (b *Broker) unsubscribe(topicName string, sub *subscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.topics[topicName]
+	if !ok {
+		return
+	}
+	delete(t.subs, sub)
+	if len(t.subs) == 0 {
+		b.idleLocked(topicName, t)
+	}
+}
+
+// idleLocked handles a topic without subscribers: it is removed at once if
+// it has no history to replay, otherwise after idleTTL. b.mu must be held.
+func (b *Broker) idleLocked(name string, t *topic) {
+	if len(t.replay) == 0 || b.idleTTL <= 0 {
+		if t.idle != nil {
+			t.idle.Stop()
+		}
+		delete(b.topics, name)
+		return
+	}
+	t.idleSince = time.Now()
+	if t.idle == nil {
+		t.idle = time.AfterFunc(b.idleTTL, func() { b.evictIdle(name, t) })
+	} else {
+		t.idle.Reset(b.idleTTL)
+	}
+}
+
+// evictIdle removes the topic if it is still idle. A timer that fired just
+// before being re-armed finds the topic idle for less than idleTTL and
+// re-arms for the remainder instead.
+func (b *Broker) evictIdle(name string, t *topic) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.topics[name] != t || len(t.subs) > 0 {
+		return
+	}
+	if left := b.idleTTL - time.Since(t.idleSince); left > 0 {
+		t.idle.Reset(left)
+		return
+	}
+	delete(b.topics, name)
+}
+
+// topicLocked returns the named topic, creating it if needed. b.mu must be held.
+func (b *Broker) topicLocked(name string) *topic {
+	t, ok := b.topics[name]
+	if !ok {
+		t = &topic{subs: make(map[*subscriber]struct{})}
+		b.topics[name] = t
+	}
+	return t
+}
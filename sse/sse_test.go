@@ -0,0 +1,299 @@
+package sse
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestBroker(opts ...Option) *Broker {
+	opts = append([]Option{WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))}, opts...)
+	return NewBroker(opts...)
+}
+
+// connect opens an SSE stream against ts and returns a line reader over it.
+func connect(t *testing.T, ctx context.Context, url, lastID string) (*http.Response, *bufio.Reader) {
+	t.Helper()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp, bufio.NewReader(resp.Body)
+}
+
+// readEvent reads lines until a blank line and returns the non-empty ones.
+func readEvent(t *testing.T, br *bufio.Reader) []string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read: %v (got %q)", err, lines)
+		}
+		line = strings.TrimRight(line, "\n")
+		if line == "" {
+			if len(lines) == 0 {
+				continue
+			}
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+func waitSubscribers(t *testing.T, b *Broker, topic string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for b.Subscribers(topic) != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d subscribers, got %d", n, b.Subscribers(topic))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func handler(b *Broker) http.Handler {
+	h := b.Handler(func(r *http.Request) string { return r.URL.Query().Get("topic") })
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { _ = h(w, r) })
+}
+
+func TestEventWriteTo(t *testing.T) {
+	var sb strings.Builder
+	_, _ = Event{ID: 7, Event: "update", Data: "a\nb"}.WriteTo(&sb)
+	want := "id: 7\nevent: update\ndata: a\ndata: b\n\n"
+	if sb.String() != want {
+		t.Fatalf("got %q, want %q", sb.String(), want)
+	}
+}
+
+func TestEventWriteTo_LineBreaks(t *testing.T) {
+	var sb strings.Builder
+	_, _ = Event{ID: 1, Event: "x\rid: 99\ndata: y", Data: "a\rid: 42\r\nb\nretry: 1"}.WriteTo(&sb)
+	want := "id: 1\nevent: xid: 99data: y\ndata: a\ndata: id: 42\ndata: b\ndata: retry: 1\n\n"
+	if sb.String() != want {
+		t.Fatalf("got %q, want %q", sb.String(), want)
+	}
+}
+
+func TestBroker_PublishRejectsLineBreakInEvent(t *testing.T) {
+	b := newTestBroker()
+	defer b.Close()
+
+	for _, event := range []string{"a\nid: 9", "a\rb"} {
+		if _, err := b.Publish("t", event, "x"); err != ErrInvalidEvent {
+			t.Errorf("Publish(%q) err = %v, want ErrInvalidEvent", event, err)
+		}
+	}
+}
+
+func TestBroker_IdleTopicEvicted(t *testing.T) {
+	b := newTestBroker(WithIdleTopicTTL(20 * time.Millisecond))
+	defer b.Close()
+
+	sub, _, _ := b.subscribe("history", 0)
+	_, _ = b.Publish("history", "", "a")
+	b.unsubscribe("history", sub)
+	_, _ = b.Publish("other", "", "b") // no subscribers, kept for replay
+
+	topics := func() int {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		return len(b.topics)
+	}
+	if n := topics(); n != 2 {
+		t.Fatalf("expected 2 topics before TTL, got %d", n)
+	}
+
+	// A subscriber within the TTL keeps the history.
+	sub, backlog, _ := b.subscribe("history", 0)
+	if len(backlog) != 0 {
+		t.Fatalf("unexpected backlog for lastID 0: %v", backlog)
+	}
+	_, backlog2, _ := b.subscribe("other", 1)
+	if len(backlog2) != 1 {
+		t.Fatalf("expected replay within TTL, got %v", backlog2)
+	}
+
+	b.unsubscribe("history", sub)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		b.mu.Lock()
+		_, ok := b.topics["history"]
+		b.mu.Unlock()
+		if !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("idle topic was not evicted")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if b.Subscribers("other") != 1 {
+		t.Fatal("topic with a subscriber must not be evicted")
+	}
+}
+
+func TestBroker_NoReplayTopicRemoved(t *testing.T) {
+	b := newTestBroker(WithReplaySize(0))
+	defer b.Close()
+
+	_, _ = b.Publish("t", "", "a")
+	b.mu.Lock()
+	n := len(b.topics)
+	b.mu.Unlock()
+	if n != 0 {
+		t.Fatalf("expected topic without subscribers or history to be removed, got %d topics", n)
+	}
+}
+
+func TestBroker_FanOut(t *testing.T) {
+	b := newTestBroker(WithHeartbeat(0))
+	defer b.Close()
+	ts := httptest.NewServer(handler(b))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resp, br1 := connect(t, ctx, ts.URL+"?topic=news", "")
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	_, br2 := connect(t, ctx, ts.URL+"?topic=news", "")
+	waitSubscribers(t, b, "news", 2)
+
+	if _, err := b.Publish("other", "x", "ignored"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.PublishJSON("news", "update", map[string]int{"n": 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, br := range []*bufio.Reader{br1, br2} {
+		got := readEvent(t, br)
+		want := []string{"id: 2", "event: update", `data: {"n":1}`}
+		if strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+}
+
+func TestBroker_LastEventIDReplay(t *testing.T) {
+	b := newTestBroker(WithHeartbeat(0), WithReplaySize(2), WithRetry(3*time.Second))
+	defer b.Close()
+	ts := httptest.NewServer(handler(b))
+	defer ts.Close()
+
+	for _, d := range []string{"one", "two", "three"} {
+		if _, err := b.Publish("t", "", d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, br := connect(t, ctx, ts.URL+"?topic=t", "1")
+
+	if got := readEvent(t, br); got[0] != "retry: 3000" {
+		t.Fatalf("expected retry hint, got %q", got)
+	}
+	if got := readEvent(t, br); got[0] != "id: 2" || got[1] != "data: two" {
+		t.Fatalf("unexpected replay %q", got)
+	}
+	if got := readEvent(t, br); got[0] != "id: 3" {
+		t.Fatalf("unexpected replay %q", got)
+	}
+
+	waitSubscribers(t, b, "t", 1)
+	_, _ = b.Publish("t", "", "four")
+	if got := readEvent(t, br); got[0] != "id: 4" {
+		t.Fatalf("expected live event after replay, got %q", got)
+	}
+}
+
+func TestBroker_Heartbeat(t *testing.T) {
+	b := newTestBroker(WithHeartbeat(10 * time.Millisecond))
+	defer b.Close()
+	ts := httptest.NewServer(handler(b))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, br := connect(t, ctx, ts.URL+"?topic=t", "")
+
+	if got := readEvent(t, br); got[0] != ": heartbeat" {
+		t.Fatalf("expected heartbeat comment, got %q", got)
+	}
+}
+
+func TestBroker_ClientDisconnectDetaches(t *testing.T) {
+	b := newTestBroker(WithHeartbeat(0))
+	defer b.Close()
+	ts := httptest.NewServer(handler(b))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	connect(t, ctx, ts.URL+"?topic=t", "")
+	waitSubscribers(t, b, "t", 1)
+
+	cancel()
+	waitSubscribers(t, b, "t", 0)
+}
+
+func TestBroker_SlowSubscriberDropped(t *testing.T) {
+	b := newTestBroker(WithBufferSize(1))
+	defer b.Close()
+
+	sub, _, err := b.subscribe("t", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = b.Publish("t", "", "a")
+	_, _ = b.Publish("t", "", "b")
+
+	select {
+	case <-sub.dropped:
+	default:
+		t.Fatal("expected slow subscriber to be dropped")
+	}
+	if b.Subscribers("t") != 0 {
+		t.Fatalf("expected 0 subscribers, got %d", b.Subscribers("t"))
+	}
+}
+
+func TestBroker_Close(t *testing.T) {
+	b := newTestBroker(WithHeartbeat(0))
+	ts := httptest.NewServer(handler(b))
+	defer ts.Close()
+
+	_, br := connect(t, context.Background(), ts.URL+"?topic=t", "")
+	waitSubscribers(t, b, "t", 1)
+
+	b.Close()
+	b.Close() // idempotent
+
+	if _, err := br.ReadString('\n'); err != io.EOF {
+		t.Fatalf("expected stream to end, got %v", err)
+	}
+	if _, err := b.Publish("t", "", "x"); err != ErrClosed {
+		t.Fatalf("expected ErrClosed, got %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/?topic=t", nil)
+	handler(b).ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 after close, got %d", rec.Code)
+	}
+}